@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// alertCooldown is the minimum time between two firings of an alert for the
+// same group, so a burst hovering around the threshold fires once.
+const alertCooldown = time.Minute
+
+// Alert fires when the count of a matching group within the trend window
+// exceeds a threshold.
+type Alert struct {
+	spec      string
+	match     []alertMatch
+	threshold int
+}
+
+// alertMatch requires the field key of the group's latest entry to be value.
+type alertMatch struct {
+	key, value string
+}
+
+// alertState is the state of an Alert for a row.
+type alertState struct {
+	// above is set while the row exceeds the threshold.
+	above bool
+
+	// fired is when the alert last fired for the row.
+	fired time.Time
+}
+
+// Fired is an alert fired by a group, passed to -alert-cmd as JSON.
+type Fired struct {
+	Alert string `json:"alert"`
+	Group
+}
+
+// parseAlert parses a spec like "level=ERROR count>100". Every key=value must
+// match the group's latest entry, levels case-insensitively, and count>N or
+// count>=N is the threshold of its count within the trend window.
+func parseAlert(spec string) (Alert, error) {
+	a := Alert{spec: spec, threshold: -1}
+	for _, field := range strings.Fields(spec) {
+		if n, ok := strings.CutPrefix(field, "count>"); ok {
+			orEqual := strings.HasPrefix(n, "=")
+			threshold, err := strconv.Atoi(strings.TrimPrefix(n, "="))
+			if err != nil || threshold < 0 || orEqual && threshold == 0 {
+				return Alert{}, fmt.Errorf("invalid alert %q: bad threshold %q", spec, field)
+			}
+			if orEqual {
+				threshold--
+			}
+			a.threshold = threshold
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || key == "" {
+			return Alert{}, fmt.Errorf("invalid alert %q: want key=value or count>N, got %q", spec, field)
+		}
+		a.match = append(a.match, alertMatch{key, value})
+	}
+	if a.threshold < 0 {
+		return Alert{}, fmt.Errorf("invalid alert %q: missing count>N", spec)
+	}
+	return a, nil
+}
+
+func parseAlerts(specs []string) ([]Alert, error) {
+	alerts := make([]Alert, 0, len(specs))
+	for _, spec := range specs {
+		a, err := parseAlert(spec)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+func (a Alert) matches(data map[string]interface{}) bool {
+	for _, m := range a.match {
+		v, ok := lookup(data, m.key)
+		if !ok {
+			return false
+		}
+		s := fmt.Sprintf("%v", v)
+		if m.key == "level" && !strings.EqualFold(s, m.value) || m.key != "level" && s != m.value {
+			return false
+		}
+	}
+	return true
+}
+
+// SetAlerts sets the alerts checked whenever a row changes.
+func (s *Store) SetAlerts(alerts []Alert) {
+	s.alerts = alerts
+}
+
+// checkAlerts updates the alert state of row at now, queueing the alerts it
+// fires for TakeAlerts. An alert fires when the row crosses its threshold,
+// and again only after falling back to it and alertCooldown.
+func (s *Store) checkAlerts(row *RowData, now time.Time) {
+	if len(s.alerts) == 0 {
+		return
+	}
+	if len(row.alerts) != len(s.alerts) {
+		row.alerts = make([]alertState, len(s.alerts))
+	}
+	n := s.windowCount(row)
+	alerting := false
+	for i, a := range s.alerts {
+		st := &row.alerts[i]
+		switch {
+		case n <= float64(a.threshold) || !a.matches(row.data):
+			st.above = false
+		case !st.above:
+			st.above = true
+			if st.fired.IsZero() || now.Sub(st.fired) >= alertCooldown {
+				st.fired = now
+				s.fired = append(s.fired, Fired{Alert: a.spec, Group: row.group()})
+			}
+		}
+		alerting = alerting || st.above
+	}
+	row.alerting = alerting
+}
+
+// TakeAlerts returns the alerts fired since the last call.
+func (s *Store) TakeAlerts() []Fired {
+	fired := s.fired
+	s.fired = nil
+	return fired
+}
+
+// notifyAlerts rings the terminal bell and shows fired alerts in the status
+// line, and runs -alert-cmd for each of them.
+func notifyAlerts(fired []Fired) {
+	for _, f := range fired {
+		log.Printf("alert: %s, group %v with %d entries", f.Alert, f.Data["message"], f.Count)
+		if app != nil {
+			alert := f.Alert
+			go app.QueueUpdate(func() {
+				ringBell()
+				showMessage("alert: %s", alert)
+			})
+		}
+		if alertCmd != "" {
+			go runAlertCmd(alertCmd, f)
+		}
+	}
+}
+
+// ringBell rings the terminal bell. It runs in the event loop, so the bell
+// doesn't end up in the middle of a screen update.
+func ringBell() {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return
+	}
+	defer tty.Close()
+	tty.Write([]byte("\a"))
+}
+
+// runAlertCmd runs command with the shell, passing f as JSON on stdin.
+func runAlertCmd(command string, f Fired) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		log.Println("alert command:", err)
+		return
+	}
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(data)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		log.Printf("alert command: %v %s", err, strings.TrimSpace(string(out)))
+	}
+}
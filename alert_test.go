@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAlert(t *testing.T) {
+	tests := []struct {
+		spec      string
+		match     []alertMatch
+		threshold int
+		err       bool
+	}{
+		{"level=ERROR count>100", []alertMatch{{"level", "ERROR"}}, 100, false},
+		{"count>=5", nil, 4, false},
+		{" position=db.go:12  level=WARN count>0 ", []alertMatch{{"position", "db.go:12"}, {"level", "WARN"}}, 0, false},
+		{"level=ERROR", nil, 0, true},
+		{"level=ERROR count>many", nil, 0, true},
+		{"count>=0", nil, 0, true},
+		{"ERROR count>1", nil, 0, true},
+	}
+	for i, tt := range tests {
+		a, err := parseAlert(tt.spec)
+		if (err != nil) != tt.err {
+			t.Errorf("Test[%d]: parseAlert(%q) error = %v, want error %v", i, tt.spec, err, tt.err)
+			continue
+		}
+		if err == nil && (!reflect.DeepEqual(a.match, tt.match) || a.threshold != tt.threshold) {
+			t.Errorf("Test[%d]: parseAlert(%q) = %v count>%d, want %v count>%d", i, tt.spec, a.match, a.threshold, tt.match, tt.threshold)
+		}
+	}
+}
+
+func TestStoreAlerts(t *testing.T) {
+	alerts, err := parseAlerts([]string{"level=error count>2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(time.Second, 2, 1, []string{"message"}, nil)
+	s.SetAlerts(alerts)
+	push := func(level string, n int) []Fired {
+		for i := 0; i < n; i++ {
+			s.Push(map[string]interface{}{"level": level, "message": "failed " + level})
+		}
+		return s.TakeAlerts()
+	}
+
+	if fired := push("ERROR", 2); len(fired) != 0 {
+		t.Errorf("fired %v at the threshold", fired)
+	}
+	fired := push("ERROR", 1)
+	if len(fired) != 1 || fired[0].Alert != "level=error count>2" || fired[0].Count != 3 {
+		t.Fatalf("fired %v crossing the threshold, want the group with 3 entries", fired)
+	}
+	if !s.Get(0).GetAlerting() {
+		t.Error("the row isn't alerting above the threshold")
+	}
+	if fired := push("ERROR", 5); len(fired) != 0 {
+		t.Errorf("fired %v again during the burst", fired)
+	}
+	if fired := push("WARN", 5); len(fired) != 0 || s.Get(1).GetAlerting() {
+		t.Errorf("fired %v for a group not matching the alert", fired)
+	}
+
+	s.Shift()
+	s.Shift()
+	if s.Get(0).GetAlerting() {
+		t.Error("the row still alerts after its entries left the trend window")
+	}
+	if fired := push("ERROR", 3); len(fired) != 0 || !s.Get(0).GetAlerting() {
+		t.Errorf("fired %v within the cooldown, want none but the row alerting", fired)
+	}
+
+	s.Shift()
+	s.Shift()
+	s.rows[0].alerts[0].fired = time.Now().Add(-alertCooldown)
+	if fired := push("ERROR", 3); len(fired) != 1 {
+		t.Errorf("fired %v after the cooldown, want one alert", fired)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// maxBatch bounds the entries pending in a batcher, a reader reaching it
+// applies the batch itself rather than growing the queue without limit.
+const maxBatch = 4096
+
+// updates batches entries for the store, nil to push every entry at once.
+var updates *batcher
+
+// batcher queues entries and applies them to the store in batches, so
+// readers take the store lock once per batch instead of once per entry and
+// don't contend with rendering on every line. Entries are applied in the
+// order they were added.
+type batcher struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending []map[string]interface{}
+
+	// flushing serializes flushes, so batches taken from pending are
+	// applied in order.
+	flushing sync.Mutex
+	spare    []map[string]interface{}
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newBatcher(interval time.Duration) *batcher {
+	return &batcher{
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// add queues an entry for the next batch.
+func (b *batcher) add(value map[string]interface{}) {
+	b.mu.Lock()
+	b.pending = append(b.pending, value)
+	full := len(b.pending) >= maxBatch
+	b.mu.Unlock()
+
+	if full {
+		b.flush()
+	}
+}
+
+// flush applies the pending entries to the store.
+func (b *batcher) flush() {
+	b.flushing.Lock()
+	defer b.flushing.Unlock()
+
+	b.mu.Lock()
+	batch := b.pending
+	b.pending = b.spare[:0]
+	b.mu.Unlock()
+
+	if len(batch) > 0 {
+		apply(batch)
+	}
+	clear(batch)
+	b.spare = batch
+}
+
+// run flushes every interval until close is called.
+func (b *batcher) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.flush()
+		case <-b.stop:
+			b.flush()
+			return
+		}
+	}
+}
+
+// close stops run and applies what is still pending, so no entry added
+// before it is lost.
+func (b *batcher) close() {
+	close(b.stop)
+	<-b.done
+}
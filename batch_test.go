@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatcher(t *testing.T) {
+	keys = []string{"message"}
+	store = NewStore(time.Second, defaultTrendBuckets, 0, keys, nil)
+	defer func() { keys, store = nil, nil }()
+
+	b := newBatcher(time.Hour)
+	go b.run()
+	for i := 0; i < maxBatch+10; i++ {
+		b.add(map[string]interface{}{"message": fmt.Sprint(i)})
+	}
+	// a full batch is applied without waiting for the interval
+	store.RLock()
+	n := store.Len()
+	store.RUnlock()
+	if n != maxBatch {
+		t.Errorf("%d groups before close, want %d", n, maxBatch)
+	}
+
+	// close applies the rest, in order
+	b.close()
+	if n := store.Len(); n != maxBatch+10 {
+		t.Fatalf("%d groups after close, want %d", n, maxBatch+10)
+	}
+	for i := 0; i < store.Len(); i++ {
+		if got, want := store.Get(i).data["message"], fmt.Sprint(i); got != want {
+			t.Fatalf("group %d is %v, want %s", i, got, want)
+		}
+	}
+}
+
+// The batched benchmarks push from 4 readers like -follow with several files
+// or -listen connections, while rendering takes the read lock.
+func BenchmarkUpdate(b *testing.B) {
+	for _, interval := range []time.Duration{0, 20 * time.Millisecond} {
+		b.Run(fmt.Sprintf("batch=%v", interval), func(b *testing.B) {
+			keys = []string{"message"}
+			store = NewStore(time.Second, defaultTrendBuckets, 3, keys, nil)
+			defer func() { keys, store, updates = nil, nil, nil }()
+			if interval > 0 {
+				updates = newBatcher(interval)
+				go updates.run()
+			}
+
+			s, stop := store, make(chan struct{})
+			go func() {
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					s.RLock()
+					s.Len()
+					s.RUnlock()
+				}
+			}()
+
+			value := map[string]interface{}{"message": "empty counter list"}
+			var wg sync.WaitGroup
+			b.ResetTimer()
+			for r := 0; r < 4; r++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < b.N/4; i++ {
+						update(value)
+					}
+				}()
+			}
+			wg.Wait()
+			if updates != nil {
+				updates.close()
+			}
+			b.StopTimer()
+			close(stop)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboard copies text to the system clipboard.
+type clipboard interface {
+	Copy(text string) error
+}
+
+// commandClipboard pipes the text into a clipboard utility like pbcopy.
+type commandClipboard struct {
+	name string
+	args []string
+}
+
+// Copy waits for the utility only, not for its output to close: xclip and
+// xsel fork a child which keeps the selection and inherits stdout and stderr.
+// Stderr therefore goes to a file instead of a pipe.
+func (c commandClipboard) Copy(text string) error {
+	stderr, err := os.CreateTemp("", "red-clipboard")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(stderr.Name())
+	defer stderr.Close()
+
+	cmd := exec.Command(c.name, c.args...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		out, _ := os.ReadFile(stderr.Name())
+		return fmt.Errorf("%s: %v %s", c.name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// osc52Clipboard asks the terminal to set the clipboard with the OSC 52
+// escape sequence, which also works over ssh if the terminal supports it.
+type osc52Clipboard struct{}
+
+func (osc52Clipboard) Copy(text string) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer tty.Close()
+	_, err = fmt.Fprintf(tty, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+var errNoClipboard = errors.New("no clipboard backend available, install xclip or wl-clipboard")
+
+// findClipboard returns the first available clipboard backend. In ssh
+// sessions OSC 52 is preferred, local utilities would copy on the remote host.
+func findClipboard() (clipboard, error) {
+	if os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "" {
+		return osc52Clipboard{}, nil
+	}
+
+	var candidates []commandClipboard
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = append(candidates, commandClipboard{name: "pbcopy"})
+	case "windows":
+		candidates = append(candidates, commandClipboard{name: "clip"})
+	default:
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			candidates = append(candidates, commandClipboard{name: "wl-copy"})
+		}
+		candidates = append(candidates,
+			commandClipboard{name: "xclip", args: []string{"-selection", "clipboard"}},
+			commandClipboard{name: "xsel", args: []string{"--clipboard", "--input"}},
+		)
+	}
+	for _, c := range candidates {
+		if _, err := exec.LookPath(c.name); err == nil {
+			return c, nil
+		}
+	}
+	return nil, errNoClipboard
+}
+
+// clipboardBackend finds the backend copyToClipboard uses, tests replace it.
+var clipboardBackend = findClipboard
+
+// copyToClipboard copies text with the first available backend.
+func copyToClipboard(text string) error {
+	c, err := clipboardBackend()
+	if err != nil {
+		return err
+	}
+	return c.Copy(text)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+func TestFindClipboard(t *testing.T) {
+	t.Setenv("SSH_CONNECTION", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+
+	t.Setenv("SSH_TTY", "/dev/pts/1")
+	if c, err := findClipboard(); err != nil || c != (osc52Clipboard{}) {
+		t.Errorf("findClipboard() over ssh = %v, %v, want OSC 52", c, err)
+	}
+
+	t.Setenv("SSH_TTY", "")
+	t.Setenv("PATH", t.TempDir())
+	if c, err := findClipboard(); !errors.Is(err, errNoClipboard) {
+		t.Errorf("findClipboard() without utilities = %v, %v, want %v", c, err, errNoClipboard)
+	}
+}
+
+func TestCommandClipboard(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "clipboard")
+	if err := (commandClipboard{name: "sh", args: []string{"-c", "cat > " + name}}).Copy("copied"); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(name); err != nil || string(b) != "copied" {
+		t.Errorf("clipboard holds %q, %v, want copied", b, err)
+	}
+
+	// a child keeping stdout and stderr open doesn't block the copy
+	start := time.Now()
+	if err := (commandClipboard{name: "sh", args: []string{"-c", "cat > /dev/null; sleep 5 &"}}).Copy("copied"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Copy() waited %v for the forked child", elapsed)
+	}
+
+	err := commandClipboard{name: "sh", args: []string{"-c", "echo no display >&2; exit 1"}}.Copy("copied")
+	if err == nil || !strings.Contains(err.Error(), "no display") {
+		t.Errorf("Copy() error = %v, want the output of the failed command", err)
+	}
+}
+
+// fakeClipboard keeps the copied text.
+type fakeClipboard struct {
+	text *string
+}
+
+func (c fakeClipboard) Copy(text string) error {
+	*c.text = text
+	return nil
+}
+
+func TestCopyRowData(t *testing.T) {
+	var copied string
+	clipboardBackend = func() (clipboard, error) { return fakeClipboard{&copied}, nil }
+	table = tview.NewTable()
+	store = NewStore(time.Second, defaultTrendBuckets, 3, []string{"message"}, nil)
+	defer func() {
+		clipboardBackend, table, store, rows, message = findClipboard, nil, nil, nil, ""
+	}()
+
+	copyRowData()
+	if message != "nothing to copy" || copied != "" {
+		t.Errorf("copied %q without rows, message %q", copied, message)
+	}
+
+	store.Push(map[string]interface{}{"message": "disk full", rawKey: "disk full"})
+	rows = []int{0}
+	table.SetSelectable(true, false)
+	table.Select(1, 0)
+	copyRowData()
+	if want := "{\n  \"message\": \"disk full\"\n}"; copied != want {
+		t.Errorf("copied %q, want %q", copied, want)
+	}
+	if message != "copied row to clipboard" {
+		t.Errorf("message %q after copying", message)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"github.com/rivo/tview"
+)
+
+var (
+	columnPicker *tview.List
+	columnsOpen  bool
+	columnFields []string
+)
+
+// openColumns shows a picker of every key seen so far, Enter toggles whether
+// the key is displayed as a column.
+func openColumns() {
+	columnsOpen = true
+
+	store.RLock()
+	columnFields = store.Fields()
+	store.RUnlock()
+
+	columnPicker = tview.NewList().
+		ShowSecondaryText(false).
+		SetSelectedFunc(func(i int, _, _ string, _ rune) {
+			toggleColumn(columnFields[i])
+			columnPicker.SetItemText(i, columnItem(columnFields[i]), "")
+		})
+	columnPicker.SetBorder(true).SetTitle(" columns, Enter toggles, c or Esc to close ")
+	for _, field := range columnFields {
+		columnPicker.AddItem(columnItem(field), "", 0, nil)
+	}
+
+	overlay := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(columnPicker, 0, 4, true).
+			AddItem(nil, 0, 1, false), 0, 1, true).
+		AddItem(nil, 0, 1, false)
+	pages.AddPage("columns", overlay, true, true)
+	app.SetFocus(columnPicker)
+}
+
+func closeColumns() {
+	columnsOpen = false
+	pages.RemovePage("columns")
+	app.SetFocus(table)
+}
+
+func columnItem(field string) string {
+	mark := "[ []"
+	if indexOf(keys, field) >= 0 {
+		mark = "[x[]"
+	}
+	return mark + " " + escape(field)
+}
+
+// toggleColumn adds field as the last column, or removes it unless it is the
+// only one. The store keeps grouping by the keys it was created with.
+func toggleColumn(field string) {
+	store.Lock()
+	if i := indexOf(keys, field); i >= 0 {
+		if len(keys) > 1 {
+			keys = append(keys[:i:i], keys[i+1:]...)
+		}
+	} else {
+		keys = append(keys[:len(keys):len(keys)], field)
+	}
+	store.Unlock()
+
+	renderColumns()
+	renderRows()
+}
+
+func indexOf(list []string, s string) int {
+	for i := range list {
+		if list[i] == s {
+			return i
+		}
+	}
+	return -1
+}
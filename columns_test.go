@@ -0,0 +1,122 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+func TestToggleColumn(t *testing.T) {
+	keys = []string{"level", "message"}
+	table = tview.NewTable().SetFixed(1, 2)
+	store = NewStore(time.Second, defaultTrendBuckets, 3, keys, nil)
+	defer func() { keys, table, store, rows = nil, nil, nil, nil }()
+
+	store.Push(map[string]interface{}{"level": "INFO", "message": "a", "caller": "main.go:1"})
+	store.Push(map[string]interface{}{"level": "INFO", "message": "a", "traceID": "1"})
+	if got, want := store.Fields(), []string{"caller", "level", "message", "traceID"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Fields() = %v, want %v", got, want)
+	}
+
+	steps := []struct {
+		field string
+		want  []string
+	}{
+		{"caller", []string{"level", "message", "caller"}},
+		{"level", []string{"message", "caller"}},
+		{"caller", []string{"message"}},
+		{"message", []string{"message"}}, // the last column stays
+	}
+	for i, tt := range steps {
+		toggleColumn(tt.field)
+		if !reflect.DeepEqual(keys, tt.want) {
+			t.Errorf("Test[%d]: toggle %s, keys %v, want %v", i, tt.field, keys, tt.want)
+		}
+		if n := table.GetColumnCount(); n != firstDataColumn+len(tt.want) {
+			t.Errorf("Test[%d]: %d table columns, want %d", i, n, firstDataColumn+len(tt.want))
+		}
+	}
+
+	// grouping still compares the keys the store was created with
+	if !reflect.DeepEqual(store.keys, []string{"level", "message"}) {
+		t.Errorf("store keys %v changed", store.keys)
+	}
+}
+
+func TestRenderRows(t *testing.T) {
+	keys = []string{"level", "message"}
+	table = tview.NewTable().SetFixed(1, 2)
+	store = NewStore(time.Second, defaultTrendBuckets, 3, keys, nil)
+	defer func() { keys, table, store, rows = nil, nil, nil, nil }()
+
+	renderColumns()
+	store.Push(map[string]interface{}{"level": "INFO", "message": "a"})
+	store.Push(map[string]interface{}{"level": "INFO", "message": "a"})
+	renderRows()
+	store.Push(map[string]interface{}{"level": "ERROR", "message": "disk full"})
+	renderRows()
+
+	want := [][]string{
+		{"trend", "count", "rate", "level", "message"},
+		{Spark(store.Get(0).GetTrend(), SparkBlock), "2", "2.0/s", "INFO", "a"},
+		{Spark(store.Get(1).GetTrend(), SparkBlock), "1", "1.0/s", "ERROR", "disk full"},
+	}
+	if table.GetRowCount() != len(want) {
+		t.Fatalf("%d table rows, want %d", table.GetRowCount(), len(want))
+	}
+	for row := range want {
+		for column, text := range want[row] {
+			if got := table.GetCell(row, column).Text; got != text {
+				t.Errorf("cell %d,%d is %q, want %q", row, column, got, text)
+			}
+		}
+	}
+}
+
+func TestRenderRowsChanged(t *testing.T) {
+	keys = []string{"message"}
+	table = tview.NewTable().SetFixed(1, 2)
+	store = NewStore(time.Second, defaultTrendBuckets, 3, keys, nil)
+	defer func() { keys, table, store, rows = nil, nil, nil, nil }()
+
+	renderColumns()
+	store.Push(map[string]interface{}{"message": "a"})
+	store.Push(map[string]interface{}{"message": "disk full"})
+	renderRows()
+
+	// unchanged rows aren't rewritten
+	table.GetCell(1, countColumn).SetText("stale")
+	table.GetCell(2, countColumn).SetText("stale")
+	store.Push(map[string]interface{}{"message": "disk full"})
+	renderRows()
+	if got := table.GetCell(1, countColumn).Text; got != "stale" {
+		t.Errorf("unchanged row rewritten to %q", got)
+	}
+	if got := table.GetCell(2, countColumn).Text; got != "2" {
+		t.Errorf("changed row count is %q, want 2", got)
+	}
+
+	// a row taking another's place is rewritten
+	sortMode = SortCount
+	defer func() { sortMode = SortFirstSeen }()
+	renderRows()
+	if got := table.GetCell(1, firstDataColumn).Text; got != "disk full" {
+		t.Errorf("first row is %q after sorting, want disk full", got)
+	}
+	if got := table.GetCell(2, countColumn).Text; got != "1" {
+		t.Errorf("moved row count is %q, want 1", got)
+	}
+
+	// a new search rewrites every row
+	setSearch("a")
+	defer setSearch("")
+	renderRows()
+	if table.GetRowCount() != 2 {
+		t.Fatalf("%d table rows, want 2", table.GetRowCount())
+	}
+	if got, want := table.GetCell(1, firstDataColumn).Text, "[black:yellow]a[-:-]"; got != want {
+		t.Errorf("matching row is %q, want %q", got, want)
+	}
+}
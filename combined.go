@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// combinedDecoder decodes the Apache/nginx combined log format without
+// requiring an nginx config, e.g.
+// 127.0.0.1 - frank [22/Aug/2024:09:00:06 +0000] "GET /api HTTP/1.1" 200 157 "-" "curl/8.0"
+//
+// Lines of the common log format, lacking referer and user agent, are
+// accepted as well.
+type combinedDecoder struct {
+	*lineScanner
+}
+
+func newCombinedDecoder(r io.Reader) *combinedDecoder {
+	return &combinedDecoder{
+		lineScanner: newLineScanner(r),
+	}
+}
+
+var combinedRE = regexp.MustCompile(`^(\S+) \S+ (\S+) \[([^\]]+)\] "((?:[^"\\]|\\.)*)" (\d{3}) (\d+|-)(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?`)
+
+func (d *combinedDecoder) Decode() (map[string]interface{}, error) {
+	for {
+		d.Begin()
+		line, ok := d.Next()
+		if !ok {
+			if err := d.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		m, ok := parseCombined(line)
+		if !ok {
+			invalidEntry(line)
+			continue
+		}
+		return m, nil
+	}
+}
+
+func parseCombined(line string) (map[string]interface{}, bool) {
+	matches := combinedRE.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, false
+	}
+
+	m := map[string]interface{}{
+		"remote_addr": matches[1],
+		"datetime":    matches[3],
+		"request":     matches[4],
+		"status":      toValue(matches[5]),
+		"bytes":       toValue(matches[6]),
+	}
+	if matches[2] != "-" {
+		m["remote_user"] = matches[2]
+	}
+	if method, rest, ok := strings.Cut(matches[4], " "); ok {
+		m["method"] = method
+		path, protocol, _ := strings.Cut(rest, " ")
+		m["path"] = path
+		if protocol != "" {
+			m["protocol"] = protocol
+		}
+	}
+	if matches[7] != "" || matches[8] != "" {
+		m["referer"] = matches[7]
+		m["user_agent"] = matches[8]
+	}
+	return m, true
+}
+
+// accessLogFormat reports whether the format name decodes HTTP access logs.
+func accessLogFormat(name string) bool {
+	return name == "nginx" || name == "combined"
+}
+
+// addStatusClass derives status_class, e.g. 5xx, from a numeric status so
+// access logs can be grouped by it.
+func addStatusClass(m map[string]interface{}) {
+	var status string
+	switch v := m["status"].(type) {
+	case json.Number:
+		status = v.String()
+	case string:
+		status = v
+	default:
+		return
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil || code < 100 || code > 599 {
+		return
+	}
+	m["status_class"] = fmt.Sprintf("%dxx", code/100)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCombined(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]interface{}
+	}{
+		{
+			`127.0.0.1 - frank [22/Aug/2024:09:00:06 +0000] "GET /api/counter?id=1 HTTP/1.1" 502 157 "https://example.com/" "Mozilla/5.0 (X11)"`,
+			map[string]interface{}{
+				"remote_addr": "127.0.0.1",
+				"remote_user": "frank",
+				"datetime":    "22/Aug/2024:09:00:06 +0000",
+				"request":     "GET /api/counter?id=1 HTTP/1.1",
+				"method":      "GET",
+				"path":        "/api/counter?id=1",
+				"protocol":    "HTTP/1.1",
+				"status":      json.Number("502"),
+				"bytes":       json.Number("157"),
+				"referer":     "https://example.com/",
+				"user_agent":  "Mozilla/5.0 (X11)",
+			},
+		},
+		{
+			`10.0.0.1 - - [22/Aug/2024:09:00:07 +0000] "-" 400 - `,
+			map[string]interface{}{
+				"remote_addr": "10.0.0.1",
+				"datetime":    "22/Aug/2024:09:00:07 +0000",
+				"request":     "-",
+				"status":      json.Number("400"),
+				"bytes":       "-",
+			},
+		},
+	}
+	for i, tt := range tests {
+		got, ok := parseCombined(tt.line)
+		if !ok {
+			t.Errorf("Test[%d]: parseCombined(%q) rejected the line", i, tt.line)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Test[%d]: parseCombined(%q) = %v, want %v", i, tt.line, got, tt.want)
+		}
+	}
+
+	if _, ok := parseCombined("not an access log"); ok {
+		t.Error("parseCombined accepted an invalid line")
+	}
+
+	got, _ := parseCombined(tests[0].line)
+	if dt, ok := recordTime(got); !ok || !dt.Equal(time.Date(2024, 8, 22, 9, 0, 6, 0, time.UTC)) {
+		t.Errorf("recordTime() = %v, %v", dt, ok)
+	}
+}
+
+func TestAddStatusClass(t *testing.T) {
+	tests := []struct {
+		status interface{}
+		want   interface{}
+	}{
+		{json.Number("200"), "2xx"},
+		{json.Number("304"), "3xx"},
+		{json.Number("404"), "4xx"},
+		{json.Number("502"), "5xx"},
+		{"503", "5xx"},
+		{"-", nil},
+		{json.Number("42"), nil},
+		{nil, nil},
+	}
+	for i, tt := range tests {
+		m := map[string]interface{}{}
+		if tt.status != nil {
+			m["status"] = tt.status
+		}
+		addStatusClass(m)
+		if got := m["status_class"]; got != tt.want {
+			t.Errorf("Test[%d]: status_class of %v = %v, want %v", i, tt.status, got, tt.want)
+		}
+	}
+}
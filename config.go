@@ -0,0 +1,158 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// keyAliases maps the keys set in the keybindings of -config to the keys
+// they act as.
+var keyAliases map[rune]rune
+
+// config holds the settings of a -config file which aren't flags.
+type config struct {
+	// columns are the keys displayed if none are given on the command line.
+	columns []string
+
+	// keyBindings maps a key to the key it acts as.
+	keyBindings map[rune]rune
+}
+
+// loadConfig reads a YAML config file and sets the flags of fs from it,
+// except those already set on the command line. The keys are flag names,
+// flags which can be repeated take a list, e.g.
+//
+//	format: zaplog
+//	group-by: message
+//	mask: ['\d+=>N', '0x[0-9a-f]+=>HEX']
+//	columns: [level, message]
+//	keybindings: {f: /}
+//
+// Unknown keys are an error.
+func loadConfig(r io.Reader, fs *flag.FlagSet) (config, error) {
+	var conf config
+	var values map[string]interface{}
+	if err := yaml.NewDecoder(r).Decode(&values); err != nil && err != io.EOF {
+		return conf, fmt.Errorf("config: %v", err)
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var err error
+		switch name {
+		case "columns":
+			conf.columns, err = configList(values[name])
+		case "keybindings":
+			conf.keyBindings, err = configKeyBindings(values[name])
+		case "config", "help":
+			err = fmt.Errorf("can't be set in a config file")
+		default:
+			err = setConfigFlag(fs, name, values[name], set[name])
+		}
+		if err != nil {
+			return conf, fmt.Errorf("config: %s: %v", name, err)
+		}
+	}
+	return conf, nil
+}
+
+// setConfigFlag sets flag name to value, unless it was set on the command
+// line. Flags set this way count as set for fs.Visit.
+func setConfigFlag(fs *flag.FlagSet, name string, value interface{}, set bool) error {
+	f := fs.Lookup(name)
+	if f == nil {
+		return fmt.Errorf("unknown key")
+	}
+	list, isList := value.([]interface{})
+	if _, repeated := f.Value.(*stringsFlag); isList && !repeated {
+		return fmt.Errorf("takes a single value, not a list")
+	}
+	if set {
+		return nil
+	}
+	if !isList {
+		list = []interface{}{value}
+	}
+	for _, v := range list {
+		s, err := configScalar(v)
+		if err != nil {
+			return err
+		}
+		if err := fs.Set(name, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configScalar formats a single value of the config file as a flag value.
+func configScalar(v interface{}) (string, error) {
+	switch v.(type) {
+	case []interface{}, map[string]interface{}:
+		return "", fmt.Errorf("want a single value, got %v", v)
+	case nil:
+		return "", nil
+	}
+	return fmt.Sprint(v), nil
+}
+
+func configList(v interface{}) ([]string, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("want a list, got %v", v)
+	}
+	values := make([]string, len(list))
+	for i, item := range list {
+		s, err := configScalar(item)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = s
+	}
+	return values, nil
+}
+
+func configKeyBindings(v interface{}) (map[rune]rune, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("want a mapping of keys, got %v", v)
+	}
+	bindings := make(map[rune]rune, len(m))
+	for key, target := range m {
+		s, err := configScalar(target)
+		if err != nil {
+			return nil, err
+		}
+		if utf8.RuneCountInString(key) != 1 || utf8.RuneCountInString(s) != 1 {
+			return nil, fmt.Errorf("want single characters, got %s: %s", key, s)
+		}
+		r, _ := utf8.DecodeRuneInString(key)
+		bindings[r], _ = utf8.DecodeRuneInString(s)
+	}
+	return bindings, nil
+}
+
+// readConfig loads the config file name into the command line flags.
+func readConfig(name string) (config, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return config{}, err
+	}
+	defer f.Close()
+	return loadConfig(f, flag.CommandLine)
+}
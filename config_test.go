@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	var format, groupBy string
+	var distance int
+	var follow bool
+	var masks stringsFlag
+	fs := flag.NewFlagSet("red", flag.ContinueOnError)
+	fs.StringVar(&format, "format", "auto", "")
+	fs.StringVar(&groupBy, "group-by", "", "")
+	fs.IntVar(&distance, "distance", 20, "")
+	fs.BoolVar(&follow, "follow", false, "")
+	fs.Var(&masks, "mask", "")
+	if err := fs.Parse([]string{"-group-by", "position"}); err != nil {
+		t.Fatal(err)
+	}
+
+	input := `
+format: zaplog
+group-by: message
+distance: 5
+follow: true
+mask: ['\d+=>N', 'x=>y']
+columns: [level, message]
+keybindings: {f: /, q: "?"}
+`
+	conf, err := loadConfig(strings.NewReader(input), fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "zaplog" || distance != 5 || !follow {
+		t.Errorf("format, distance, follow = %q, %d, %v, want zaplog, 5, true", format, distance, follow)
+	}
+	if groupBy != "position" {
+		t.Errorf("group-by = %q, want the command line's position", groupBy)
+	}
+	if want := (stringsFlag{`\d+=>N`, "x=>y"}); !reflect.DeepEqual(masks, want) {
+		t.Errorf("mask = %v, want %v", masks, want)
+	}
+	if want := []string{"level", "message"}; !reflect.DeepEqual(conf.columns, want) {
+		t.Errorf("columns = %v, want %v", conf.columns, want)
+	}
+	if want := map[rune]rune{'f': '/', 'q': '?'}; !reflect.DeepEqual(conf.keyBindings, want) {
+		t.Errorf("keybindings = %v, want %v", conf.keyBindings, want)
+	}
+}
+
+func TestLoadConfigSetsFlags(t *testing.T) {
+	var distance float64
+	var similarity string
+	fs := flag.NewFlagSet("red", flag.ContinueOnError)
+	fs.Float64Var(&distance, "distance", 3, "")
+	fs.StringVar(&similarity, "similarity", "levenshtein", "")
+	defer func(commandLine *flag.FlagSet) { flag.CommandLine = commandLine }(flag.CommandLine)
+	flag.CommandLine = fs
+
+	if _, err := loadConfig(strings.NewReader("similarity: jaccard\ndistance: 0.3\n"), fs); err != nil {
+		t.Fatal(err)
+	}
+	if similarity != "jaccard" || distance != 0.3 {
+		t.Errorf("similarity, distance = %q, %v, want jaccard, 0.3", similarity, distance)
+	}
+	// The jaccard default distance only applies if distance wasn't set.
+	if !isFlagSet("distance") || !isFlagSet("similarity") {
+		t.Error("flags set by the config file don't count as set")
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []string{
+		"colour: red",
+		"format: [json, zaplog]",
+		"distance: far",
+		"columns: level",
+		"keybindings: {ff: /}",
+		"config: other.yaml",
+		"format: {name: json}",
+		"format: json\n  bad: indent",
+	}
+	for i, input := range tests {
+		fs := flag.NewFlagSet("red", flag.ContinueOnError)
+		fs.String("format", "auto", "")
+		fs.Int("distance", 20, "")
+		fs.String("config", "", "")
+		if _, err := loadConfig(strings.NewReader(input), fs); err == nil {
+			t.Errorf("Test[%d]: loadConfig(%q) succeeded, want an error", i, input)
+		}
+	}
+}
+
+func TestLoadConfigEmpty(t *testing.T) {
+	fs := flag.NewFlagSet("red", flag.ContinueOnError)
+	conf, err := loadConfig(strings.NewReader(""), fs)
+	if err != nil || conf.columns != nil || conf.keyBindings != nil {
+		t.Errorf("loadConfig(\"\") = %v, %v, want an empty config", conf, err)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// textFormat keeps every line as the message, for -cri-format.
+const textFormat = "text"
+
+// criDecoder decodes the logs container runtimes write for Kubernetes, e.g.
+// 2024-08-22T09:00:06.956Z stdout F {"level": "ERROR", "msg": "empty counter list"}
+//
+// Lines split by the runtime are marked P, partial, and joined up to the final
+// F line. The joined output is decoded with format, the runtime's timestamp
+// is used unless the output has a datetime of its own.
+type criDecoder struct {
+	*lineScanner
+	format  string
+	parse   outputParser
+	partial strings.Builder
+}
+
+func newCriDecoder(r io.Reader, format string) *criDecoder {
+	return &criDecoder{
+		lineScanner: newLineScanner(r),
+		format:      format,
+	}
+}
+
+var criRE = regexp.MustCompile(`^(\S+) (stdout|stderr) ([FP])(?: (.*))?$`)
+
+func (d *criDecoder) Decode() (map[string]interface{}, error) {
+	for {
+		// Partial lines are part of the record they end in.
+		if d.partial.Len() == 0 {
+			d.Begin()
+		}
+		line, ok := d.Next()
+		if !ok {
+			if err := d.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+
+		matches := criRE.FindStringSubmatch(line)
+		if matches == nil {
+			invalidEntry(line)
+			continue
+		}
+		if matches[3] == "P" {
+			d.partial.WriteString(matches[4])
+			continue
+		}
+		output := d.partial.String() + matches[4]
+		d.partial.Reset()
+
+		m, ok := d.decodeOutput(output)
+		if !ok {
+			continue
+		}
+		if _, ok := m["stream"]; ok {
+			m[origKey("stream")] = m["stream"]
+		}
+		m["stream"] = matches[2]
+		if _, ok := m["datetime"]; !ok {
+			if t, err := time.Parse(time.RFC3339Nano, matches[1]); err == nil {
+				m["datetime"] = t
+			}
+		}
+		return m, nil
+	}
+}
+
+// decodeOutput decodes a line of container output with the format, detecting
+// it on the first line with auto.
+func (d *criDecoder) decodeOutput(output string) (map[string]interface{}, bool) {
+	if d.format == autoFormat {
+		d.format = detectCriFormat(output)
+		log.Printf("info: detected %s format of cri output", d.format)
+	}
+	if d.parse == nil {
+		d.parse = newOutputParser(d.format)
+	}
+	m, ok := d.parse(output)
+	if !ok || m == nil {
+		return nil, false
+	}
+	// The -format is cri, so update doesn't see the access log.
+	if accessLogFormat(d.format) {
+		addStatusClass(m)
+	}
+	return m, true
+}
+
+// detectCriFormat detects the format of container output, plain text lines
+// which detectFormat falls back to zaplog for are kept as text.
+func detectCriFormat(output string) string {
+	f := detectFormat(bufio.NewReader(strings.NewReader(output)))
+	switch f {
+	case "cri", "journald":
+		return textFormat
+	case "zaplog":
+		if _, ok := newZaplogDecoder(strings.NewReader(""), timeLayout).parse(strings.TrimSpace(output)); !ok {
+			return textFormat
+		}
+	}
+	return f
+}
+
+// outputParser parses a line of container output, false if it is invalid.
+type outputParser func(output string) (map[string]interface{}, bool)
+
+// newOutputParser returns the parser of container output in the format name,
+// or nil if the records of the format don't fit a line. The parser is kept
+// for the whole input, like the decoder of the format would be.
+func newOutputParser(name string) outputParser {
+	var parse outputParser
+	switch name {
+	case textFormat:
+		return func(output string) (map[string]interface{}, bool) {
+			return map[string]interface{}{"message": output}, true
+		}
+	case "json":
+		parse = parseJsonObject
+	case "zaplog":
+		d := &zaplogDecoder{layout: timeLayout, sep: zapSep, nest: nestFields}
+		parse = d.parse
+	case "logfmt":
+		parse = func(line string) (map[string]interface{}, bool) {
+			m := parseLogfmt(line)
+			return m, len(m) > 0
+		}
+	case "syslog":
+		parse = func(line string) (map[string]interface{}, bool) {
+			return parseSyslog(line), true
+		}
+	case "nginx":
+		parse = (&nginxDecoder{format: nginxLog}).parse
+	case "combined":
+		parse = parseCombined
+	case "gelf":
+		parse = parseGelf
+	case "klog":
+		parse = (&klogDecoder{now: time.Now}).parse
+	case "csv":
+		parse = (&csvDecoder{delim: csvDelim}).parse
+	default:
+		return nil
+	}
+	return func(output string) (map[string]interface{}, bool) {
+		return parse(strings.TrimSpace(output))
+	}
+}
+
+// parseJsonObject parses a line holding a JSON object.
+func parseJsonObject(line string) (map[string]interface{}, bool) {
+	m := map[string]interface{}{}
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil || dec.More() {
+		return nil, false
+	}
+	keepDuplicates([]byte(line), m)
+	return m, true
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCriDecoder(t *testing.T) {
+	input := strings.Join([]string{
+		`2024-08-22T09:00:06.956Z stdout F {"level": "ERROR", "message": "empty counter list"}`,
+		`2024-08-22T09:00:07Z stderr P {"level": "INFO", `,
+		`2024-08-22T09:00:07Z stderr P "message": `,
+		`2024-08-22T09:00:07Z stderr F "joined"}`,
+		`not cri`,
+		`2024-08-22T09:00:08Z stdout F {"datetime": "own", "message": "kept"}`,
+	}, "\n")
+
+	invalid := invalidLines.Load()
+	got := decodeAll(t, newCriDecoder(strings.NewReader(input), "json"))
+	want := []map[string]interface{}{
+		{
+			"level":    "ERROR",
+			"message":  "empty counter list",
+			"stream":   "stdout",
+			"datetime": time.Date(2024, 8, 22, 9, 0, 6, 956000000, time.UTC),
+		},
+		{
+			"level":    "INFO",
+			"message":  "joined",
+			"stream":   "stderr",
+			"datetime": time.Date(2024, 8, 22, 9, 0, 7, 0, time.UTC),
+		},
+		{
+			"datetime": "own",
+			"message":  "kept",
+			"stream":   "stdout",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded\n%v\nwant\n%v", got, want)
+	}
+	if n := invalidLines.Load() - invalid; n != 1 {
+		t.Errorf("%d invalid lines, want 1", n)
+	}
+}
+
+func TestCriDecoderText(t *testing.T) {
+	input := "2024-08-22T09:00:06Z stdout F listening on :8080\n2024-08-22T09:00:07Z stdout F\n"
+
+	dec := newCriDecoder(strings.NewReader(input), autoFormat)
+	got := decodeAll(t, dec)
+	want := []map[string]interface{}{
+		{
+			"message":  "listening on :8080",
+			"stream":   "stdout",
+			"datetime": time.Date(2024, 8, 22, 9, 0, 6, 0, time.UTC),
+		},
+		{
+			"message":  "",
+			"stream":   "stdout",
+			"datetime": time.Date(2024, 8, 22, 9, 0, 7, 0, time.UTC),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded\n%v\nwant\n%v", got, want)
+	}
+	if dec.format != textFormat {
+		t.Errorf("detected %s format, want %s", dec.format, textFormat)
+	}
+}
+
+func TestCriDecoderAccessLog(t *testing.T) {
+	input := `2024-08-22T09:00:06Z stdout F 127.0.0.1 - - [22/Aug/2024:09:00:06 +0000] "GET /api HTTP/1.1" 502 157 "-" "curl/8.0"` + "\n"
+
+	dec := newCriDecoder(strings.NewReader(input), autoFormat)
+	got := decodeAll(t, dec)
+	if dec.format != "combined" {
+		t.Errorf("detected %s format, want combined", dec.format)
+	}
+	if len(got) != 1 || got[0]["status_class"] != "5xx" {
+		t.Errorf("decoded %v, want a status_class of 5xx", got)
+	}
+}
+
+func TestCriDecoderCsv(t *testing.T) {
+	input := strings.Join([]string{
+		`2024-08-22T09:00:06Z stdout F level,message`,
+		`2024-08-22T09:00:07Z stdout F ERROR,empty counter list`,
+		`2024-08-22T09:00:08Z stdout F INFO,done`,
+	}, "\n")
+	defer func(d rune) { csvDelim = d }(csvDelim)
+	csvDelim = ','
+
+	got := decodeAll(t, newCriDecoder(strings.NewReader(input), "csv"))
+	want := []map[string]interface{}{
+		{
+			"level":    "ERROR",
+			"message":  "empty counter list",
+			"stream":   "stdout",
+			"datetime": time.Date(2024, 8, 22, 9, 0, 7, 0, time.UTC),
+		},
+		{
+			"level":    "INFO",
+			"message":  "done",
+			"stream":   "stdout",
+			"datetime": time.Date(2024, 8, 22, 9, 0, 8, 0, time.UTC),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded\n%v\nwant\n%v", got, want)
+	}
+}
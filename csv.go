@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// csvDecoder decodes CSV rows into records keyed by the columns of the
+// header row, e.g.
+// datetime,level,msg
+// 2024-08-22 09:00:06.956,ERROR,"empty counter list, retrying"
+// Quoted cells may hold the delimiter, quotes doubled and line breaks.
+type csvDecoder struct {
+	*lineScanner
+	delim  rune
+	header []string
+}
+
+func newCsvDecoder(r io.Reader, delim rune) *csvDecoder {
+	return &csvDecoder{
+		lineScanner: newLineScanner(r),
+		delim:       delim,
+	}
+}
+
+// parseDelim parses a -csv-delim, a single character, or tab or \t.
+func parseDelim(s string) (rune, error) {
+	if s == "tab" || s == `\t` {
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || size != len(s) || r == '"' || r == '\n' || r == '\r' || r == utf8.RuneError {
+		return 0, fmt.Errorf("invalid -csv-delim %q, want a single character or tab", s)
+	}
+	return r, nil
+}
+
+func (d *csvDecoder) Decode() (map[string]interface{}, error) {
+	for {
+		d.Begin()
+		text, ok := d.row()
+		if !ok {
+			if err := d.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		m, ok := d.parse(text)
+		if !ok {
+			invalidEntry(text)
+			continue
+		}
+		if m == nil {
+			continue
+		}
+		return m, nil
+	}
+}
+
+// parse parses the text of a row, the first row is kept as the header and
+// returns no record.
+func (d *csvDecoder) parse(text string) (map[string]interface{}, bool) {
+	r := csv.NewReader(strings.NewReader(text))
+	r.Comma = d.delim
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	cells, err := r.Read()
+	if err != nil {
+		return nil, false
+	}
+	if d.header == nil {
+		d.header = make([]string, len(cells))
+		for i, cell := range cells {
+			d.header[i] = strings.TrimSpace(cell)
+		}
+		return nil, true
+	}
+
+	m := make(map[string]interface{}, len(cells))
+	for i, cell := range cells {
+		m[d.column(i)] = toValue(cell)
+	}
+	return m, true
+}
+
+// row returns the lines of the next row, which continues on the following
+// lines while a quoted cell is open.
+func (d *csvDecoder) row() (string, bool) {
+	line, ok := d.Next()
+	if !ok {
+		return "", false
+	}
+	for strings.Count(line, `"`)%2 == 1 {
+		next, ok := d.Next()
+		if !ok {
+			break
+		}
+		line += "\n" + next
+	}
+	return line, true
+}
+
+// column returns the header of column i, or its number counting from 1 for
+// cells beyond the header.
+func (d *csvDecoder) column(i int) string {
+	if i < len(d.header) && d.header[i] != "" {
+		return d.header[i]
+	}
+	return strconv.Itoa(i + 1)
+}
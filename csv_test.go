@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCsvDecoder(t *testing.T) {
+	input := "datetime,level, msg ,latency\n" +
+		"2024-08-22 09:00:06.956,ERROR,\"empty counter list, retrying\",12.5\n" +
+		"\n" +
+		"2024-08-22 09:00:07.001,WARN,\"say \"\"hi\"\"\nnow\",7,extra\n"
+	got := decodeAll(t, newCsvDecoder(strings.NewReader(input), ','))
+	want := []map[string]interface{}{
+		{"datetime": "2024-08-22 09:00:06.956", "level": "ERROR", "msg": "empty counter list, retrying", "latency": json.Number("12.5")},
+		{"datetime": "2024-08-22 09:00:07.001", "level": "WARN", "msg": "say \"hi\"\nnow", "latency": json.Number("7"), "5": "extra"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %v, want %v", got, want)
+	}
+}
+
+func TestCsvDecoderDelim(t *testing.T) {
+	input := "level\tmsg\nINFO\tstarted; ok\n"
+	got := decodeAll(t, newCsvDecoder(strings.NewReader(input), '\t'))
+	if len(got) != 1 || got[0]["msg"] != "started; ok" {
+		t.Errorf("decoded %v, want the message with its semicolon", got)
+	}
+}
+
+func TestParseDelim(t *testing.T) {
+	tests := []struct {
+		s    string
+		want rune
+		err  bool
+	}{
+		{",", ',', false},
+		{";", ';', false},
+		{"tab", '\t', false},
+		{`\t`, '\t', false},
+		{"", 0, true},
+		{";;", 0, true},
+		{`"`, 0, true},
+	}
+	for i, tt := range tests {
+		got, err := parseDelim(tt.s)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("Test[%d]: parseDelim(%q) = %q, %v, want %q", i, tt.s, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// epochLayout is a pseudo layout which parses unix timestamps in seconds,
+// milliseconds, microseconds or nanoseconds, guessed from the magnitude.
+const epochLayout = "epoch"
+
+// timeLayouts are tried in order when no explicit layout is given.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05", // also accepts fractional seconds
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",   // zap ISO8601TimeEncoder
+	"02/Jan/2006:15:04:05 -0700", // access logs
+	epochLayout,
+}
+
+// parseTime parses s with layout, or with each of timeLayouts if layout is
+// empty. Timestamps without zone are assumed to be local.
+func parseTime(s, layout string) (time.Time, bool) {
+	return parseTimeIn(s, layout, time.Local)
+}
+
+// parseTimeIn is parseTime assuming loc for timestamps without zone.
+func parseTimeIn(s, layout string, loc *time.Location) (time.Time, bool) {
+	if layout != "" {
+		return parseTimeLayout(s, layout, loc)
+	}
+	for _, l := range timeLayouts {
+		if t, ok := parseTimeLayout(s, l, loc); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func parseTimeLayout(s, layout string, loc *time.Location) (time.Time, bool) {
+	if layout == epochLayout {
+		return parseEpoch(s)
+	}
+	t, err := time.ParseInLocation(layout, s, loc)
+	return t, err == nil
+}
+
+func parseEpoch(s string) (time.Time, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return time.Time{}, false
+	}
+	switch {
+	case f < 1e11:
+		// Parse the fraction digits separately, float64 would lose the
+		// nanosecond precision.
+		sec, frac, ok := strings.Cut(s, ".")
+		if i, err := strconv.ParseInt(sec, 10, 64); err == nil && ok && len(frac) <= 9 {
+			if ns, err := strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64); err == nil {
+				return time.Unix(i, ns), true
+			}
+		}
+		sf, ff := math.Modf(f)
+		return time.Unix(int64(sf), int64(ff*1e9)), true
+	case f < 1e14:
+		return time.Unix(0, int64(f*1e6)), true
+	case f < 1e17:
+		return time.Unix(0, int64(f*1e3)), true
+	default:
+		return time.Unix(0, int64(f)), true
+	}
+}
+
+// recordTime returns the datetime of a decoded record.
+func recordTime(m map[string]interface{}) (time.Time, bool) {
+	switch v := m["datetime"].(type) {
+	case time.Time:
+		return v, true
+	case string:
+		return parseTime(v, "")
+	}
+	return time.Time{}, false
+}
+
+// formatValue formats a field value for display. Datetimes decoded to a
+// time.Time show as RFC 3339, as in the JSON of the viewer, rather than in
+// the default format of Go.
+func formatValue(v interface{}) string {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprintf("%v", v)
+}
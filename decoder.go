@@ -8,6 +8,7 @@ import (
 	"log"
 	"regexp"
 	"strings"
+	"sync/atomic"
 )
 
 type Decoder interface {
@@ -15,91 +16,295 @@ type Decoder interface {
 	More() bool
 }
 
+// rawKey holds the original text of a record, as returned by rawText. It
+// isn't a column or a field of the column chooser, the viewer shows it below
+// the other fields.
+const rawKey = "__raw"
+
+// withoutRaw returns m without rawKey, a copy if m has it.
+func withoutRaw(m map[string]interface{}) map[string]interface{} {
+	if _, ok := m[rawKey]; !ok {
+		return m
+	}
+	c := make(map[string]interface{}, len(m)-1)
+	for k, v := range m {
+		if k != rawKey {
+			c[k] = v
+		}
+	}
+	return c
+}
+
+// rawDecoder is implemented by decoders keeping the original text of the
+// record they decoded last.
+type rawDecoder interface {
+	Raw() string
+}
+
+// rawText returns the original text of the record dec decoded last, or ""
+// if dec doesn't keep it.
+func rawText(dec Decoder) string {
+	if r, ok := dec.(rawDecoder); ok {
+		return r.Raw()
+	}
+	return ""
+}
+
+// invalidLines counts input lines that no decoder could parse.
+var invalidLines atomic.Int64
+
+// longLines counts input lines dropped for exceeding maxLineSize.
+var longLines atomic.Int64
+
+// maxLineSize is the longest line line based decoders read, see
+// -max-line-size.
+var maxLineSize = defaultMaxLineSize
+
+const defaultMaxLineSize = 1 << 20
+
+// invalidEntry records a line that couldn't be parsed, logging it unless
+// -quiet is set.
+func invalidEntry(line string) {
+	invalidLines.Add(1)
+	if quiet {
+		return
+	}
+	log.Printf("warn: invalid log entry - %s", line)
+}
+
+var numberRE = regexp.MustCompile(`^-?(0|[1-9]\d*)(\.\d+)?([eE][+-]?\d+)?$`)
+
+// toValue converts a textual value into json.Number if it looks numeric,
+// to match values produced by json.Decoder.UseNumber.
+func toValue(s string) interface{} {
+	if numberRE.MatchString(s) {
+		return json.Number(s)
+	}
+	return s
+}
+
+// jsonDecoder decodes a stream of JSON objects, which may span several lines.
+// Top-level arrays are streamed as their elements, so a file holding one
+// array of records decodes like concatenated objects.
 type jsonDecoder struct {
-	rd  io.Reader
-	dec *json.Decoder
+	dec     *json.Decoder
+	err     error
+	inArray bool
+	raw     json.RawMessage
 }
 
 func newJsonDecoder(r io.Reader) *jsonDecoder {
-	d := json.NewDecoder(r)
+	d := json.NewDecoder(skipBOM(r))
 	d.UseNumber()
 
 	return &jsonDecoder{
-		rd:  r,
 		dec: d,
 	}
 }
 
 func (d *jsonDecoder) Decode() (map[string]interface{}, error) {
+	if !d.next() {
+		if d.err != nil {
+			return nil, d.err
+		}
+		return nil, io.EOF
+	}
+	d.raw = d.raw[:0]
+	if err := d.dec.Decode(&d.raw); err != nil {
+		d.err = err
+		return nil, err
+	}
 	m := map[string]interface{}{}
-	if err := d.dec.Decode(&m); err != nil {
+	dec := json.NewDecoder(bytes.NewReader(d.raw))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		d.err = err
 		return nil, err
 	}
+	keepDuplicates(d.raw, m)
 	return m, nil
 }
 
+// Raw returns the text of the object decoded last.
+func (d *jsonDecoder) Raw() string {
+	return string(d.raw)
+}
+
 func (d *jsonDecoder) More() bool {
-	// len(nil)==0, 此时读到文件末尾，返回io.EOF
-	_, err := d.rd.Read(nil)
-	return err == nil
+	// Once decoding failed the stream can't be resumed.
+	return d.next()
 }
 
-type zaplogDecoder struct {
-	// rd io.Reader
-	scanner *bufio.Scanner
+// next advances to the next value to decode, entering and leaving top-level
+// arrays, and reports whether there is one.
+func (d *jsonDecoder) next() bool {
+	for d.err == nil {
+		// json.Decoder.More skips whitespace and peeks the next token, it
+		// returns false at EOF and at the end of an array.
+		if !d.dec.More() {
+			if !d.inArray {
+				return false
+			}
+			if _, err := d.dec.Token(); err != nil {
+				d.err = err
+				return false
+			}
+			d.inArray = false
+			continue
+		}
+		if d.inArray || d.peek() != '[' {
+			return true
+		}
+		if _, err := d.dec.Token(); err != nil {
+			d.err = err
+			return false
+		}
+		d.inArray = true
+	}
+	return false
 }
 
-func newZaplogDecoder(r io.Reader) *zaplogDecoder {
-	sc := bufio.NewScanner(r)
-	sc.Split(bufio.ScanLines)
+// peek returns the next non-space byte, More has already buffered it.
+func (d *jsonDecoder) peek() byte {
+	r := d.dec.Buffered()
+	b := make([]byte, 1)
+	for {
+		if _, err := r.Read(b); err != nil {
+			return 0
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b[0]
+	}
+}
 
-	return &zaplogDecoder{
-		scanner: sc,
+// bom is the UTF-8 byte order mark some Windows tools start files with.
+const bom = "\ufeff"
+
+// skipBOM returns r without a leading byte order mark.
+func skipBOM(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(len(bom)); err == nil && string(b) == bom {
+		br.Discard(len(bom))
 	}
+	return br
 }
 
-var zaplogRE = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d{3}) (TRACE|DEBUG|INFO|WARN|ERROR) (.*.go:\d+) \[.*\] (.*) (\{.*\})$`)
+// lineScanner wraps a bufio.Scanner with a single line of look-ahead, so
+// More can report whether input remains without consuming a line.
+type lineScanner struct {
+	scanner *bufio.Scanner
+	line    string
+	peeked  bool
+	started bool
 
-var emptyMap = map[string]interface{}{}
+	// base splits the input into lines, bufio.ScanLines unless set with
+	// splitBy. skipping is set while dropping the rest of a line too long to
+	// buffer.
+	base     bufio.SplitFunc
+	skipping bool
 
-func (d *zaplogDecoder) Decode() (map[string]interface{}, error) {
-	for d.scanner.Scan() {
-		line := d.scanner.Text()
-		line = strings.TrimSpace(line)
+	// raw holds the lines consumed since Begin, the text of the record
+	// being decoded.
+	raw []string
+}
 
-		ok := zaplogRE.MatchString(line)
-		if !ok {
-			log.Printf("warn: invalid log entry - %s", line)
-			continue
-		} else {
-			var m = map[string]interface{}{}
+func newLineScanner(r io.Reader) *lineScanner {
+	s := &lineScanner{
+		scanner: bufio.NewScanner(r),
+		base:    bufio.ScanLines,
+	}
+	s.scanner.Buffer(make([]byte, min(bufio.MaxScanTokenSize, maxLineSize)), maxLineSize)
+	s.scanner.Split(s.split)
+	return s
+}
 
-			matches := zaplogRE.FindStringSubmatch(line)
-			if len(matches) != 6 {
-				log.Printf("warn: invalid log entry - %s", line)
-				continue
-			}
-			// 0: line
-			m["datetime"] = matches[1] // 1: datetime
-			m["level"] = matches[2]    // 2: level
-			m["position"] = matches[3] // 3: position
-			m["message"] = matches[4]  // 4: message
-			// 5: zapfields
-			zapfields := map[string]interface{}{}
-			dec := json.NewDecoder(bytes.NewBufferString(matches[5]))
-			dec.UseNumber()
-			_ = dec.Decode(&zapfields)
-			// m["zapfields"] = zapfields
-			for k, v := range zapfields {
-				m[k] = v
-			}
+// splitBy splits the input with base instead of bufio.ScanLines, long lines
+// are still dropped. It must be called before reading.
+func (s *lineScanner) splitBy(base bufio.SplitFunc) {
+	s.base = base
+}
 
-			return m, nil
+// split is the base split function, except that a line filling the buffer is
+// dropped up to its end instead of failing the scanner with
+// bufio.ErrTooLong.
+func (s *lineScanner) split(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := s.base(data, atEOF)
+	if s.skipping {
+		switch {
+		case err != nil:
+			return advance, token, err
+		case token != nil:
+			// The end of the long line.
+			s.skipping = false
+			return advance, nil, nil
+		case advance == 0:
+			return len(data), nil, nil
 		}
+		return advance, nil, nil
 	}
-	return emptyMap, nil
+	if advance == 0 && token == nil && err == nil && len(data) >= maxLineSize {
+		s.skipping = true
+		longLines.Add(1)
+		if !quiet {
+			log.Printf("warn: dropped a line longer than -max-line-size %d, starting %.80q", maxLineSize, data)
+		}
+		return len(data), nil, nil
+	}
+	return advance, token, err
+}
+
+// More reports whether another line is available, buffering it for Next.
+func (s *lineScanner) More() bool {
+	if s.peeked {
+		return true
+	}
+	if !s.scanner.Scan() {
+		return false
+	}
+	// ScanLines drops the \r of CRLF line ends, a BOM heading the input is
+	// dropped here.
+	s.line = s.scanner.Text()
+	if !s.started {
+		s.line = strings.TrimPrefix(s.line, bom)
+		s.started = true
+	}
+	s.peeked = true
+	return true
+}
+
+// Peek returns the next line without consuming it.
+func (s *lineScanner) Peek() (string, bool) {
+	if !s.More() {
+		return "", false
+	}
+	return s.line, true
+}
+
+// Next returns the buffered look-ahead line, or scans a new one.
+func (s *lineScanner) Next() (string, bool) {
+	if !s.More() {
+		return "", false
+	}
+	s.peeked = false
+	s.raw = append(s.raw, s.line)
+	return s.line, true
+}
+
+// Begin starts the raw text of a record, decoders call it before reading the
+// first line of every record.
+func (s *lineScanner) Begin() {
+	s.raw = s.raw[:0]
+}
+
+// Raw returns the lines of the record decoded last.
+func (s *lineScanner) Raw() string {
+	return strings.Join(s.raw, "\n")
 }
 
-func (d *zaplogDecoder) More() bool {
-	return d.scanner.Scan()
+// Err returns the first non-EOF error encountered by the scanner.
+func (s *lineScanner) Err() error {
+	return s.scanner.Err()
 }
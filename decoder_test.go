@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decodeAll(t *testing.T, dec Decoder) []map[string]interface{} {
+	t.Helper()
+	var got []map[string]interface{}
+	for dec.More() {
+		m, err := dec.Decode()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Decode() returned error: %v", err)
+		}
+		got = append(got, m)
+	}
+	return got
+}
+
+func TestJsonDecoderMore(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{"empty", "", 0},
+		{"whitespace only", " \n\t\n", 0},
+		{"trailing whitespace", "{\"a\": 1}\n\n  \n", 1},
+		{"concatenated", `{"a": 1}{"a": 2} {"a": 3}`, 3},
+		{"lines", "{\"a\": 1}\n{\"a\": 2}\n", 2},
+		{"array", `[{"a": 1}, {"a": 2}]`, 2},
+		{"empty array", " [ ] \n", 0},
+		{"arrays and objects", "[{\"a\": 1}]\n{\"a\": 2}\n[{\"a\": 3},\n {\"a\": 4}]", 4},
+		{"pretty printed", "{\n  \"a\": 1,\n  \"b\": {\n    \"c\": [1, 2]\n  }\n}\n\n{\n  \"a\": 2\n}\n", 2},
+	}
+	for _, tt := range tests {
+		got := decodeAll(t, newJsonDecoder(strings.NewReader(tt.input)))
+		if len(got) != tt.want {
+			t.Errorf("%s: decoded %d records, want %d", tt.name, len(got), tt.want)
+		}
+	}
+}
+
+func TestJsonDecoderShapes(t *testing.T) {
+	input := "[\n  {\n    \"level\": \"ERROR\",\n    \"id\": 16029078675928157035\n  },\n  {\"level\": \"INFO\"}\n]\n"
+	got := decodeAll(t, newJsonDecoder(strings.NewReader(input)))
+	if len(got) != 2 || got[0]["level"] != "ERROR" || got[1]["level"] != "INFO" {
+		t.Fatalf("decoded %v", got)
+	}
+	if got[0]["id"] != json.Number("16029078675928157035") {
+		t.Errorf("id decoded as %#v", got[0]["id"])
+	}
+
+	// elements of an array must be objects too
+	dec := newJsonDecoder(strings.NewReader(`[{"a": 1}, 2]`))
+	if _, err := dec.Decode(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dec.Decode(); err == nil || err == io.EOF {
+		t.Errorf("Decode() of a scalar element returned %v, want an error", err)
+	}
+	if dec.More() {
+		t.Error("More() after an error")
+	}
+}
+
+func TestJsonLinesDecoderRejects(t *testing.T) {
+	input := strings.Join([]string{
+		`{"a": 1}`,
+		`{"a": `,
+		`[1, 2]`,
+		`"scalar"`,
+		``,
+		`{"a": 2} trailing`,
+		`{"a": 3}`,
+	}, "\n")
+
+	syntax, notObject := jsonSyntaxErrors.Load(), jsonNotObjects.Load()
+	var rejected strings.Builder
+	got := decodeAll(t, newJsonLinesDecoder(strings.NewReader(input), &rejected))
+	if len(got) != 2 {
+		t.Errorf("decoded %d records, want 2", len(got))
+	}
+	if n := jsonSyntaxErrors.Load() - syntax; n != 2 {
+		t.Errorf("%d syntax errors, want 2", n)
+	}
+	if n := jsonNotObjects.Load() - notObject; n != 2 {
+		t.Errorf("%d non-object lines, want 2", n)
+	}
+	want := "{\"a\": \n[1, 2]\n\"scalar\"\n{\"a\": 2} trailing\n"
+	if rejected.String() != want {
+		t.Errorf("rejected %q, want %q", rejected.String(), want)
+	}
+}
+
+func TestInvalidEntryQuiet(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	defer func() { quiet = false }()
+
+	for _, q := range []bool{true, false} {
+		quiet = q
+		buf.Reset()
+		invalid := invalidLines.Load()
+		invalidEntry("not json")
+		if n := invalidLines.Load() - invalid; n != 1 {
+			t.Errorf("quiet=%v: %d invalid lines counted, want 1", q, n)
+		}
+		if logged := strings.Contains(buf.String(), "not json"); logged == q {
+			t.Errorf("quiet=%v: logged %q", q, buf.String())
+		}
+	}
+}
+
+// rawAll decodes dec like decodeAll, returning the raw text of every record.
+func rawAll(t *testing.T, dec Decoder) []string {
+	t.Helper()
+	var got []string
+	for dec.More() {
+		_, err := dec.Decode()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Decode() returned error: %v", err)
+		}
+		got = append(got, rawText(dec))
+	}
+	return got
+}
+
+func TestDecoderRaw(t *testing.T) {
+	defer func(v bool) { quiet = v }(quiet)
+	quiet = true
+
+	tests := []struct {
+		name string
+		dec  Decoder
+		want []string
+	}{
+		{
+			"json",
+			newJsonDecoder(strings.NewReader("{\"a\": 1}\n[{\"b\":\n 2}]")),
+			[]string{`{"a": 1}`, "{\"b\":\n 2}"},
+		},
+		{
+			"logfmt skips invalid lines",
+			newLogfmtDecoder(strings.NewReader("level=info msg=a\n\n=\nlevel=warn msg=b")),
+			[]string{"level=info msg=a", "level=warn msg=b"},
+		},
+		{
+			"zaplog keeps the stacktrace",
+			newZaplogDecoder(strings.NewReader("2024-08-22 09:00:06.956 ERROR panic\ngoroutine 1 [running]:\n2024-08-22 09:00:07.001 INFO next"), zaplogTimeLayout),
+			[]string{"2024-08-22 09:00:06.956 ERROR panic\ngoroutine 1 [running]:", "2024-08-22 09:00:07.001 INFO next"},
+		},
+		{
+			"cri joins partial lines",
+			newCriDecoder(strings.NewReader("2024-08-22T09:00:06.956Z stdout P hello \n2024-08-22T09:00:06.957Z stdout F world"), textFormat),
+			[]string{"2024-08-22T09:00:06.956Z stdout P hello \n2024-08-22T09:00:06.957Z stdout F world"},
+		},
+		{
+			"journald",
+			newJournaldDecoder(strings.NewReader("PRIORITY=3\nMESSAGE=a\n\nMESSAGE=b\n")),
+			[]string{"PRIORITY=3\nMESSAGE=a", "MESSAGE=b"},
+		},
+		{
+			"flattened and merged",
+			newMergeDecoder([]Decoder{
+				flattenDecoder{newJsonDecoder(strings.NewReader(`{"datetime": "2024-08-22T09:00:07Z", "m": {"x": 1}}`))},
+				newJsonDecoder(strings.NewReader(`{"datetime": "2024-08-22T09:00:06Z"}`)),
+			}),
+			[]string{`{"datetime": "2024-08-22T09:00:06Z"}`, `{"datetime": "2024-08-22T09:00:07Z", "m": {"x": 1}}`},
+		},
+	}
+	for _, tt := range tests {
+		if got := rawAll(t, tt.dec); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: raw = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestJsonDecoderBOM(t *testing.T) {
+	got := decodeAll(t, newJsonDecoder(strings.NewReader("\ufeff{\"level\": \"ERROR\"}\r\n{\"level\": \"INFO\"}\r\n")))
+	if len(got) != 2 || got[0]["level"] != "ERROR" || got[1]["level"] != "INFO" {
+		t.Errorf("decoded %v, want the ERROR and INFO records", got)
+	}
+}
+
+func TestLineScannerLongLines(t *testing.T) {
+	defer func(size int) { maxLineSize = size }(maxLineSize)
+	maxLineSize = 64
+	longLines.Store(0)
+
+	long := strings.Repeat("x", 200)
+	input := "level=info msg=a\nlevel=warn msg=" + long + "\nlevel=error msg=b\n" + long
+	got := decodeAll(t, newLogfmtDecoder(strings.NewReader(input)))
+	if len(got) != 2 || got[0]["msg"] != "a" || got[1]["msg"] != "b" {
+		t.Errorf("decoded %v, want messages a and b around the long line", got)
+	}
+	if n := longLines.Load(); n != 2 {
+		t.Errorf("counted %d long lines, want 2", n)
+	}
+}
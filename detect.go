@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// autoFormat detects the format from the first non-empty line of the input.
+const autoFormat = "auto"
+
+// detectFormat peeks at the first non-empty line of r, without consuming it,
+// and guesses its format. Ambiguous lines fall back to zaplog.
+func detectFormat(r *bufio.Reader) string {
+	line := strings.TrimPrefix(peekLine(r), bom)
+	switch {
+	case line == "":
+		return "zaplog"
+	case line[0] == '{' && strings.Contains(line, `"short_message"`):
+		return "gelf"
+	case line[0] == '{' || line[0] == '[':
+		return "json"
+	case syslogPriRE.MatchString(line):
+		return "syslog"
+	case criRE.MatchString(line):
+		return "cri"
+	case strings.HasPrefix(line, "__CURSOR=") || strings.HasPrefix(line, "__REALTIME_TIMESTAMP="):
+		return "journald"
+	}
+	if _, ok := parseCombined(line); ok {
+		return "combined"
+	}
+	if klogRE.MatchString(line) {
+		return "klog"
+	}
+	if _, ok := newZaplogDecoder(strings.NewReader(""), timeLayout).parse(line); ok {
+		return "zaplog"
+	}
+	if isLogfmt(line) {
+		return "logfmt"
+	}
+	return "zaplog"
+}
+
+// peekLine returns the first non-empty line buffered in r, reading more input
+// as needed until a line is complete, the buffer is full or the input ends.
+func peekLine(r *bufio.Reader) string {
+	for {
+		// Peek one more byte than buffered, blocking until input arrives.
+		_, err := r.Peek(r.Buffered() + 1)
+		buf, _ := r.Peek(r.Buffered())
+		for {
+			i := bytes.IndexByte(buf, '\n')
+			if i < 0 {
+				break
+			}
+			if line := strings.TrimSpace(string(buf[:i])); line != "" {
+				return line
+			}
+			buf = buf[i+1:]
+		}
+		if err != nil || r.Buffered() == r.Size() {
+			return strings.TrimSpace(string(buf))
+		}
+	}
+}
+
+// isLogfmt reports whether most space separated tokens of line are key=value
+// pairs, at least two of them.
+func isLogfmt(line string) bool {
+	pairs := 0
+	for _, v := range parseLogfmt(line) {
+		if v != true {
+			pairs++
+		}
+	}
+	return pairs >= 2 && pairs*2 >= len(strings.Fields(line))
+}
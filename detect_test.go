@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{"level": "ERROR", "message": "empty counter list"}`, "json"},
+		{"\n\n  [{\"level\": \"ERROR\"}]", "json"},
+		{`{"version": "1.1", "host": "dbsvr", "short_message": "empty counter list"}`, "gelf"},
+		{"2024-08-22 09:00:06.956 ERROR dbsvr/counter.go:202 [GetCounterBatch] empty counter list", "zaplog"},
+		{`ts=2024-08-22T09:00:06Z level=error msg="empty counter list"`, "logfmt"},
+		{`<165>1 2024-08-22T09:00:06.956Z host dbsvr 8982 - - empty counter list`, "syslog"},
+		{`2024-08-22T09:00:06.956Z stdout F {"level": "INFO"}`, "cri"},
+		{"__CURSOR=s=739ad463348b4ceca5a9e69c95a3c93f;i=4ece7\nMESSAGE=started\n", "journald"},
+		{`127.0.0.1 - - [22/Aug/2024:09:00:06 +0000] "GET /api HTTP/1.1" 502 157 "-" "curl/8.0"`, "combined"},
+		{"E0822 09:00:06.956789    8982 counter.go:202] empty counter list", "klog"},
+		{"\ufeff{\"level\": \"ERROR\"}", "json"},
+		{"just some text with a=b", "zaplog"},
+		{"", "zaplog"},
+	}
+	for i, tt := range tests {
+		r := bufio.NewReader(strings.NewReader(tt.input + "\nnext line\n"))
+		if got := detectFormat(r); got != tt.want {
+			t.Errorf("Test[%d]: detectFormat(%q) = %s, want %s", i, tt.input, got, tt.want)
+		}
+		// the real decoder still reads every line
+		if rest, _ := io.ReadAll(r); string(rest) != tt.input+"\nnext line\n" {
+			t.Errorf("Test[%d]: detectFormat consumed input, left %q", i, rest)
+		}
+	}
+}
+
+func TestPeekLineWaitsForCompleteLine(t *testing.T) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("\n{\"level\":"))
+		pw.Write([]byte(" \"INFO\"}\n"))
+		pw.Write([]byte("unread"))
+	}()
+	r := bufio.NewReader(pr)
+	if got, want := peekLine(r), `{"level": "INFO"}`; got != want {
+		t.Errorf("peekLine() = %q, want %q", got, want)
+	}
+}
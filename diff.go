@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/antonmedv/red/internal/prettyjson"
+)
+
+// diffRecords compares the fields of the marked record a and the selected
+// record b key by key, in the order of the viewer, see -key-order. Equal
+// fields are listed once, differing ones as a line per record, a's prefixed
+// with - in red and b's with + in green. It also returns the number of
+// differing fields.
+func diffRecords(a, b map[string]interface{}) (string, int) {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	prettyjson.OrderKeys(keys, splitFields(keyOrder))
+
+	var sb strings.Builder
+	line := func(sign, tag, key string, value interface{}) {
+		text := escape(fmt.Sprintf("%s %s: %s", sign, key, diffValue(value)))
+		if tag != "" && !noColor {
+			text = "[" + tag + "]" + text + "[-]"
+		}
+		sb.WriteString(text + "\n")
+	}
+	differ := 0
+	for _, k := range keys {
+		va, inA := a[k]
+		vb, inB := b[k]
+		if inA && inB && reflect.DeepEqual(va, vb) {
+			line(" ", "", k, va)
+			continue
+		}
+		differ++
+		if inA {
+			line("-", "red", k, va)
+		}
+		if inB {
+			line("+", "green", k, vb)
+		}
+	}
+	return sb.String(), differ
+}
+
+// diffValue formats a field value as compact JSON on a single line.
+func diffValue(v interface{}) string {
+	formatter := prettyjson.NewFormatter()
+	formatter.DisabledColor = true
+	formatter.Indent = 0
+	formatter.Newline = ""
+	formatter.KeyOrder = splitFields(keyOrder)
+	text, err := formatter.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(text)
+}
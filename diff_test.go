@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiffRecords(t *testing.T) {
+	tests := []struct {
+		a, b    map[string]interface{}
+		noColor bool
+		want    string
+		differ  int
+	}{
+		{
+			map[string]interface{}{"level": "ERROR", "message": "user 1 not found"},
+			map[string]interface{}{"level": "ERROR", "message": "user 1 not found"},
+			false,
+			"  level: \"ERROR\"\n  message: \"user 1 not found\"\n",
+			0,
+		},
+		{
+			map[string]interface{}{"code": json.Number("404"), "message": "not found"},
+			map[string]interface{}{"code": json.Number("500"), "message": "not found"},
+			false,
+			"  message: \"not found\"\n[red]- code: 404[-]\n[green]+ code: 500[-]\n",
+			1,
+		},
+		{
+			map[string]interface{}{"func": "[main]", "user": map[string]interface{}{"id": json.Number("1")}},
+			map[string]interface{}{"host": "db1", "user": map[string]interface{}{"id": json.Number("1")}},
+			true,
+			"- func: \"[main[]\"\n+ host: \"db1\"\n  user: {\"id\":1}\n",
+			2,
+		},
+	}
+	defer func(v bool) { noColor = v }(noColor)
+	for i, tt := range tests {
+		noColor = tt.noColor
+		got, differ := diffRecords(tt.a, tt.b)
+		if got != tt.want || differ != tt.differ {
+			t.Errorf("Test[%d]: diffRecords() = %q, %d, want %q, %d", i, got, differ, tt.want, tt.differ)
+		}
+	}
+}
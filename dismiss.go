@@ -0,0 +1,45 @@
+package main
+
+// dismissedKey is the key of a group dismissed with sticky dismissal.
+type dismissedKey struct {
+	key    [][]string
+	masked bool
+}
+
+// SetDismissSticky sets whether entries combining with a removed group are
+// dropped rather than starting the group again.
+func (s *Store) SetDismissSticky(sticky bool) {
+	s.dismissSticky = sticky
+}
+
+// Remove deletes row i and frees its entries, as when dismissing a group in
+// the table. Later indices shift down by one.
+func (s *Store) Remove(i int) {
+	if i < 0 || i >= len(s.rows) {
+		return
+	}
+	if s.dismissSticky {
+		s.dismissed = append(s.dismissed, dismissedKey{key: s.rows[i].key, masked: s.rows[i].masked})
+	}
+	s.remove(i)
+}
+
+// isDismissed reports whether key combines with a group dismissed with
+// sticky dismissal.
+func (s *Store) isDismissed(key [][]string, masked bool) bool {
+	for _, d := range s.dismissed {
+		if d.masked != masked {
+			continue
+		}
+		if masked && exactKey(d.key) == exactKey(key) || !masked && s.similar(key, d.key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dropped returns the number of entries dropped for combining with a
+// dismissed group.
+func (s *Store) Dropped() int {
+	return s.dropped
+}
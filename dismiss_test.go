@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStoreRemove(t *testing.T) {
+	for _, sticky := range []bool{false, true} {
+		s := NewStore(time.Second, defaultTrendBuckets, 3, []string{"message"}, nil)
+		s.SetDismissSticky(sticky)
+		push := func(message string) {
+			s.Push(map[string]interface{}{"message": message})
+		}
+		push("user 1 not found")
+		push("disk full")
+		push("cache miss")
+
+		s.Remove(0)
+		s.Remove(5)
+		if s.Len() != 2 || s.Get(0).Get("message") != "disk full" {
+			t.Fatalf("sticky %v: rows after removing the first are %d, first %v", sticky, s.Len(), s.Get(0).GetData())
+		}
+		push("disk full")
+		if s.Get(0).count != 2 {
+			t.Errorf("sticky %v: the shifted row counts %d, want 2", sticky, s.Get(0).count)
+		}
+
+		push("user 2 not found")
+		want, dropped := 3, 0
+		if sticky {
+			want, dropped = 2, 1
+		}
+		if s.Len() != want || s.Dropped() != dropped {
+			t.Errorf("sticky %v: %d rows and %d dropped after a dismissed entry, want %d and %d", sticky, s.Len(), s.Dropped(), want, dropped)
+		}
+	}
+}
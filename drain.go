@@ -0,0 +1,170 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// templateKey is the field holding the learned template of the message
+	// with -group-by template.
+	templateKey = "template"
+
+	// wildcard replaces the tokens where the messages of a template differ.
+	wildcard = "<*>"
+)
+
+// Drain learns message templates as entries arrive, after "Drain: An Online
+// Log Parsing Approach with Fixed Depth Tree" (He et al., 2017). Messages are
+// routed by their number of tokens and first token to a short list of
+// templates and join the most similar one, or start a new template if none
+// has enough tokens in common. Tokens where the messages of a template differ
+// become <*>.
+type Drain struct {
+	// depth is the number of leading tokens routing a message.
+	depth int
+	// similarity is the fraction of equal tokens a message needs to join a
+	// template.
+	similarity float64
+	// maxChildren caps the distinct tokens at a level of the tree, further
+	// tokens are routed as <*>.
+	maxChildren int
+
+	root      map[int]*drainNode
+	templates int
+}
+
+type drainNode struct {
+	children  map[string]*drainNode
+	templates []*drainTemplate
+}
+
+type drainTemplate struct {
+	id     int
+	tokens []string
+}
+
+func NewDrain() *Drain {
+	return &Drain{
+		depth:       1,
+		similarity:  0.4,
+		maxChildren: 100,
+		root:        make(map[int]*drainNode),
+	}
+}
+
+// Add learns the template of tokens and returns it.
+func (d *Drain) Add(tokens []string) *drainTemplate {
+	leaf := d.leaf(tokens)
+	var best *drainTemplate
+	bestSim, bestParams := -1.0, 0
+	for _, t := range leaf.templates {
+		sim, params := seqSimilarity(t.tokens, tokens)
+		if sim > bestSim || sim == bestSim && params > bestParams {
+			best, bestSim, bestParams = t, sim, params
+		}
+	}
+	if best != nil && bestSim >= d.similarity {
+		for i := range best.tokens {
+			if best.tokens[i] != tokens[i] {
+				best.tokens[i] = wildcard
+			}
+		}
+		return best
+	}
+
+	d.templates++
+	t := &drainTemplate{id: d.templates, tokens: append([]string(nil), tokens...)}
+	leaf.templates = append(leaf.templates, t)
+	return t
+}
+
+// Reserve makes the templates learned next number from above id, so they
+// don't take the ids of groups loaded from a snapshot.
+func (d *Drain) Reserve(id int) {
+	d.templates = max(d.templates, id)
+}
+
+// leaf returns the node holding the templates tokens may belong to.
+func (d *Drain) leaf(tokens []string) *drainNode {
+	node := d.root[len(tokens)]
+	if node == nil {
+		node = &drainNode{}
+		d.root[len(tokens)] = node
+	}
+	for i := 0; i < d.depth && i < len(tokens); i++ {
+		token := tokens[i]
+		// Tokens with digits are likely variables, don't route by them.
+		if strings.IndexFunc(token, unicode.IsDigit) >= 0 {
+			token = wildcard
+		}
+		if node.children == nil {
+			node.children = make(map[string]*drainNode)
+		}
+		if _, ok := node.children[token]; !ok && len(node.children) >= d.maxChildren {
+			token = wildcard
+		}
+		child := node.children[token]
+		if child == nil {
+			child = &drainNode{}
+			node.children[token] = child
+		}
+		node = child
+	}
+	return node
+}
+
+// seqSimilarity returns the fraction of tokens equal to those of template,
+// which has as many, and the number of wildcards in template.
+func seqSimilarity(template, tokens []string) (float64, int) {
+	if len(template) == 0 {
+		return 1, 0
+	}
+	same, params := 0, 0
+	for i := range template {
+		switch template[i] {
+		case wildcard:
+			params++
+		case tokens[i]:
+			same++
+		}
+	}
+	return float64(same) / float64(len(template)), params
+}
+
+func (t *drainTemplate) String() string {
+	return strings.Join(t.tokens, " ")
+}
+
+// key returns the row key of the entries of t.
+func (t *drainTemplate) key() [][]string {
+	return [][]string{{templateKey, strconv.Itoa(t.id)}}
+}
+
+// templateID returns the template id of a row key, or 0 for other keys.
+func templateID(key [][]string) int {
+	if len(key) != 1 || len(key[0]) != 2 || key[0][0] != templateKey {
+		return 0
+	}
+	id, _ := strconv.Atoi(key[0][1])
+	return id
+}
+
+// templateColumns returns the inferred columns with the template in place of
+// the message.
+func templateColumns(keys []string) []string {
+	columns := make([]string, 0, len(keys)+1)
+	shown := false
+	for _, key := range keys {
+		if key == "message" {
+			key = templateKey
+		}
+		shown = shown || key == templateKey
+		columns = append(columns, key)
+	}
+	if !shown {
+		columns = append(columns, templateKey)
+	}
+	return columns
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	d := NewDrain()
+	tests := []struct {
+		message, template string
+		id                int
+	}{
+		{"connected to 10.0.0.1 port 22", "connected to 10.0.0.1 port 22", 1},
+		{"connected to 10.0.0.7 port 22", "connected to <*> port 22", 1},
+		{"connected to 10.0.0.9 port 2222", "connected to <*> port <*>", 1},
+		{"user alice logged in", "user alice logged in", 2},
+		{"user bob logged in", "user <*> logged in", 2},
+		// Another number of tokens never joins a template.
+		{"user bob logged in twice", "user bob logged in twice", 3},
+		// Too few tokens in common.
+		{"disk sda1 full", "disk sda1 full", 4},
+		{"cache hit ratio", "cache hit ratio", 5},
+		// Routed by the wildcard for the leading number.
+		{"3 workers started", "3 workers started", 6},
+		{"12 workers started", "<*> workers started", 6},
+	}
+	for i, tt := range tests {
+		tmpl := d.Add(strings.Fields(tt.message))
+		if tmpl.String() != tt.template || tmpl.id != tt.id {
+			t.Errorf("Test[%d]: Add(%q) = %d %q, want %d %q", i, tt.message, tmpl.id, tmpl, tt.id, tt.template)
+		}
+	}
+}
+
+func TestStoreGroupByTemplate(t *testing.T) {
+	s := NewStore(time.Second, defaultTrendBuckets, 3, []string{"message"}, []string{templateKey})
+	for _, message := range []string{
+		"request 1f3a took 12ms",
+		"request 9c0d took 340ms",
+		"shutting down",
+		"request 77b2 took 5ms",
+	} {
+		value := map[string]interface{}{"message": message}
+		s.Push(value)
+		if _, ok := value[templateKey]; ok {
+			t.Errorf("Push(%q) set the template in the pushed map", message)
+		}
+	}
+	if s.Len() != 2 {
+		t.Fatalf("%d groups, want 2", s.Len())
+	}
+	if got := s.Get(0); got.count != 3 || got.Get(templateKey) != "request <*> took <*>" {
+		t.Errorf("request group counts %d with template %v, want 3 and the learned template", got.count, got.Get(templateKey))
+	}
+	if s.Get(1).Get(templateKey) != "shutting down" {
+		t.Errorf("second group template %v", s.Get(1).Get(templateKey))
+	}
+
+	if got, want := templateColumns([]string{"level", "message"}), []string{"level", templateKey}; !reflect.DeepEqual(got, want) {
+		t.Errorf("templateColumns = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportCSV writes the rendered rows of the table as CSV. The trend column
+// holds the raw bucket counts instead of the sparkline.
+func exportCSV(w io.Writer) error {
+	store.RLock()
+	defer store.RUnlock()
+
+	cw := csv.NewWriter(w)
+	header := append([]string{"trend", "count"}, keys...)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, index := range rows {
+		data := store.Get(index)
+		buckets := make([]string, len(data.GetTrend()))
+		for i, n := range data.GetTrend() {
+			buckets[i] = strconv.FormatFloat(n, 'f', -1, 64)
+		}
+		record := []string{strings.Join(buckets, ","), data.GetCount()}
+		for _, key := range keys {
+			record = append(record, formatValue(data.Get(key)))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportCSVFile exports the table to a timestamped file in the working
+// directory and returns its name.
+func exportCSVFile() (string, error) {
+	name := "red-" + time.Now().Format("20060102-150405") + ".csv"
+	f, err := os.Create(name)
+	if err != nil {
+		return "", err
+	}
+	if err := exportCSV(f); err != nil {
+		f.Close()
+		return "", err
+	}
+	return name, f.Close()
+}
+
+// dumpJSON writes every group with its count, trend buckets and latest
+// record as a JSON array. Numbers keep the precision they were decoded with.
+func dumpJSON(w io.Writer) error {
+	store.RLock()
+	groups := make([]Group, 0, store.Len())
+	store.Each(func(g Group) bool {
+		groups = append(groups, g)
+		return true
+	})
+	store.RUnlock()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(groups)
+}
+
+// dumpJSONFile dumps the groups to name, or to a timestamped file in the
+// working directory if name is empty, and returns the file name.
+func dumpJSONFile(name string) (string, error) {
+	if name == "" {
+		name = "red-" + time.Now().Format("20060102-150405") + ".json"
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return "", err
+	}
+	if err := dumpJSON(f); err != nil {
+		f.Close()
+		return "", err
+	}
+	return name, f.Close()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestExportCSV(t *testing.T) {
+	keys = []string{"level", "message"}
+	store = NewStore(time.Second, defaultTrendBuckets, 1, keys, nil)
+	defer func() { keys, store, rows = nil, nil, nil }()
+
+	store.Push(map[string]interface{}{"level": "ERROR", "message": "b, failed"})
+	store.Push(map[string]interface{}{"level": "INFO", "message": "a"})
+	store.Push(map[string]interface{}{"level": "INFO", "message": "a"})
+	rows = []int{1, 0}
+
+	var buf bytes.Buffer
+	if err := exportCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "trend,count,level,message\n" +
+		"\"0,0,0,0,0,0,2\",2,INFO,a\n" +
+		"\"0,0,0,0,0,0,1\",1,ERROR,\"b, failed\"\n"
+	if buf.String() != want {
+		t.Errorf("exportCSV() wrote\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestDumpJSON(t *testing.T) {
+	store = NewStore(time.Second, defaultTrendBuckets, 1, []string{"message"}, nil)
+	defer func() { store = nil }()
+
+	store.Push(map[string]interface{}{"message": "a", "traceID": json.Number("16029078675928157035")})
+	store.Push(map[string]interface{}{"message": "a", "traceID": json.Number("16029078675928157035")})
+	store.Push(map[string]interface{}{"message": "b", "cost": json.Number("0.10")})
+
+	var buf bytes.Buffer
+	if err := dumpJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := json.NewDecoder(&buf)
+	dec.UseNumber()
+	var got []Group
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Count != 2 || got[1].Count != 1 {
+		t.Fatalf("dumpJSON() groups %v, want counts 2 and 1", got)
+	}
+	if got[0].Trend[defaultTrendBuckets-1] != 2 {
+		t.Errorf("trend %v, want 2 in the last bucket", got[0].Trend)
+	}
+	if got[0].Data["traceID"] != json.Number("16029078675928157035") || got[1].Data["cost"] != json.Number("0.10") {
+		t.Errorf("numbers changed: %v %v", got[0].Data["traceID"], got[1].Data["cost"])
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Extract adds a field captured from the message of every entry.
+type Extract struct {
+	name  string
+	re    *regexp.Regexp
+	group int
+}
+
+// parseExtract parses a spec like `userid=user (\d+)`. The value is captured
+// by the group called name, the first group, or the whole match if the
+// regex has no groups.
+func parseExtract(spec string) (Extract, error) {
+	name, expr, ok := strings.Cut(spec, "=")
+	if name = strings.TrimSpace(name); !ok || name == "" {
+		return Extract{}, fmt.Errorf("invalid extract %q, want name=regex", spec)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return Extract{}, fmt.Errorf("invalid extract %q: %w", spec, err)
+	}
+	group := re.SubexpIndex(name)
+	if group < 0 && re.NumSubexp() > 0 {
+		group = 1
+	} else if group < 0 {
+		group = 0
+	}
+	return Extract{name: name, re: re, group: group}, nil
+}
+
+func parseExtracts(specs []string) ([]Extract, error) {
+	extracts := make([]Extract, 0, len(specs))
+	for _, spec := range specs {
+		e, err := parseExtract(spec)
+		if err != nil {
+			return nil, err
+		}
+		extracts = append(extracts, e)
+	}
+	return extracts, nil
+}
+
+// applyExtracts sets the fields captured from the message of value.
+// Numeric captures become numbers, so they can be aggregated with -stat.
+func applyExtracts(extracts []Extract, value map[string]interface{}) {
+	msg, ok := value["message"]
+	if !ok {
+		return
+	}
+	s := fmt.Sprintf("%v", msg)
+	for _, e := range extracts {
+		if m := e.re.FindStringSubmatchIndex(s); m != nil && m[2*e.group] >= 0 {
+			value[e.name] = toValue(s[m[2*e.group]:m[2*e.group+1]])
+		}
+	}
+}
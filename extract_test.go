@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseExtract(t *testing.T) {
+	tests := []struct {
+		spec  string
+		name  string
+		group int
+		err   bool
+	}{
+		{`userid=user (\d+)`, "userid", 1, false},
+		{`userid=(\w+) user (?P<userid>\d+)`, "userid", 2, false},
+		{`code=E\d+`, "code", 0, false},
+		{` host = on (\S+)`, "host", 1, false},
+		{`=user (\d+)`, "", 0, true},
+		{`userid`, "", 0, true},
+		{`userid=user (\d+`, "", 0, true},
+	}
+	for i, tt := range tests {
+		e, err := parseExtract(tt.spec)
+		if (err != nil) != tt.err {
+			t.Errorf("Test[%d]: parseExtract(%q) error = %v, want error %v", i, tt.spec, err, tt.err)
+			continue
+		}
+		if err == nil && (e.name != tt.name || e.group != tt.group) {
+			t.Errorf("Test[%d]: parseExtract(%q) = %s group %d, want %s group %d", i, tt.spec, e.name, e.group, tt.name, tt.group)
+		}
+	}
+}
+
+func TestApplyExtracts(t *testing.T) {
+	extracts, err := parseExtracts([]string{`userid=user (\d+)`, `code=E\d+`, `host=on (?:host )?(\S+)?`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value map[string]interface{}
+		want  map[string]interface{}
+	}{
+		{
+			map[string]interface{}{"message": "user 42 not found on db1"},
+			map[string]interface{}{"message": "user 42 not found on db1", "userid": json.Number("42"), "host": "db1"},
+		},
+		{
+			map[string]interface{}{"message": "E1005 failed on "},
+			map[string]interface{}{"message": "E1005 failed on ", "code": "E1005"},
+		},
+		{
+			map[string]interface{}{"level": "INFO"},
+			map[string]interface{}{"level": "INFO"},
+		},
+	}
+	for i, tt := range tests {
+		applyExtracts(extracts, tt.value)
+		if !reflect.DeepEqual(tt.value, tt.want) {
+			t.Errorf("Test[%d]: applyExtracts() = %v, want %v", i, tt.value, tt.want)
+		}
+	}
+}
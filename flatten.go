@@ -0,0 +1,51 @@
+package main
+
+import "strconv"
+
+// flattenDecoder flattens nested objects and arrays of decoded records into
+// dotted keys, so {"meta":{"PlayerID":0},"tags":["a"]} becomes
+// {"meta.PlayerID":0,"tags.0":"a"} and every value can be used as a column.
+type flattenDecoder struct {
+	Decoder
+}
+
+func (d flattenDecoder) Decode() (map[string]interface{}, error) {
+	m, err := d.Decoder.Decode()
+	if err != nil {
+		return m, err
+	}
+	return flattenMap(m), nil
+}
+
+func (d flattenDecoder) Raw() string {
+	return rawText(d.Decoder)
+}
+
+func flattenMap(m map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		flattenValue(flat, k, v)
+	}
+	return flat
+}
+
+func flattenValue(flat map[string]interface{}, key string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			flat[key] = v
+		}
+		for k, sub := range v {
+			flattenValue(flat, key+"."+k, sub)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			flat[key] = v
+		}
+		for i, sub := range v {
+			flattenValue(flat, key+"."+strconv.Itoa(i), sub)
+		}
+	default:
+		flat[key] = v
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFlattenMap(t *testing.T) {
+	m := map[string]interface{}{
+		"level": "ERROR",
+		"meta": map[string]interface{}{
+			"PlayerID": json.Number("0"),
+			"region":   "us",
+			"deep":     map[string]interface{}{"x": true},
+		},
+		"tags":  []interface{}{"a", map[string]interface{}{"b": nil}},
+		"empty": map[string]interface{}{},
+	}
+	want := map[string]interface{}{
+		"level":         "ERROR",
+		"meta.PlayerID": json.Number("0"),
+		"meta.region":   "us",
+		"meta.deep.x":   true,
+		"tags.0":        "a",
+		"tags.1.b":      nil,
+		"empty":         map[string]interface{}{},
+	}
+	if got := flattenMap(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("flattenMap() = %v, want %v", got, want)
+	}
+}
+
+func TestFlattenDecoder(t *testing.T) {
+	input := `{"message": "failed", "err": {"code": 5}}`
+	got := decodeAll(t, flattenDecoder{newJsonDecoder(strings.NewReader(input))})
+	if len(got) != 1 || got[0]["err.code"] != json.Number("5") {
+		t.Errorf("decoded %v, want err.code 5", got)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+const followInterval = 250 * time.Millisecond
+
+// followReader reads a file like tail -f: at EOF it polls for appended data
+// instead of returning io.EOF, and reopens the file by name when it was
+// truncated or rotated.
+type followReader struct {
+	name   string
+	file   *os.File
+	offset int64
+	done   chan struct{}
+}
+
+// newFollowReader follows f from its current offset, which is past the
+// lines skipped by -tail.
+func newFollowReader(f *os.File) *followReader {
+	offset, _ := f.Seek(0, io.SeekCurrent)
+	return &followReader{
+		name:   f.Name(),
+		file:   f,
+		offset: offset,
+		done:   make(chan struct{}),
+	}
+}
+
+func (r *followReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.file.Read(p)
+		r.offset += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		select {
+		case <-r.done:
+			r.file.Close()
+			return 0, io.EOF
+		case <-time.After(followInterval):
+		}
+		r.reopen()
+	}
+}
+
+// reopen rewinds a truncated file, or switches to a new file created under
+// the same name after rotation.
+func (r *followReader) reopen() {
+	fi, err := os.Stat(r.name)
+	if err != nil {
+		return // rotated away, wait for the new file
+	}
+	cur, err := r.file.Stat()
+	if err == nil && os.SameFile(fi, cur) {
+		if fi.Size() < r.offset {
+			if _, err := r.file.Seek(0, io.SeekStart); err == nil {
+				r.offset = 0
+			}
+		}
+		return
+	}
+
+	f, err := os.Open(r.name)
+	if err != nil {
+		return
+	}
+	r.file.Close()
+	r.file = f
+	r.offset = 0
+}
+
+// Close stops following, the file is closed by the pending Read.
+func (r *followReader) Close() error {
+	select {
+	case <-r.done:
+	default:
+		close(r.done)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// gelfLevels maps the syslog severity of a GELF level to red's levels.
+var gelfLevels = []string{"ERROR", "ERROR", "ERROR", "ERROR", "WARN", "INFO", "INFO", "DEBUG"}
+
+// gelfDecoder decodes GELF messages as written by Graylog clients, e.g.
+// {"version": "1.1", "host": "dbsvr", "short_message": "empty counter list", "timestamp": 1724317206.956, "level": 3, "_traceID": "16029078675928157035"}
+//
+// Messages are delimited by newlines or, like GELF over TCP, by null bytes.
+// Chunked and compressed GELF are only sent over UDP, which red doesn't
+// listen on.
+type gelfDecoder struct {
+	*lineScanner
+}
+
+func newGelfDecoder(r io.Reader) *gelfDecoder {
+	s := newLineScanner(r)
+	s.splitBy(scanGelf)
+	return &gelfDecoder{
+		lineScanner: s,
+	}
+}
+
+// scanGelf splits at newlines and null bytes.
+func scanGelf(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\n\x00"); i >= 0 {
+		return i + 1, bytes.TrimSuffix(data[:i], []byte("\r")), nil
+	}
+	return bufio.ScanLines(data, atEOF)
+}
+
+func (d *gelfDecoder) Decode() (map[string]interface{}, error) {
+	for {
+		d.Begin()
+		line, ok := d.Next()
+		if !ok {
+			if err := d.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		m, ok := parseGelf(line)
+		if !ok {
+			invalidEntry(line)
+			continue
+		}
+		return m, nil
+	}
+}
+
+// gelfReserved are the keys parseGelf fills from the standard fields.
+var gelfReserved = map[string]bool{
+	"message":   true,
+	"level":     true,
+	"severity":  true,
+	"timestamp": true,
+	"datetime":  true,
+}
+
+// parseGelf maps a GELF message to red's keys: short_message becomes message,
+// the numeric level a level name and timestamp the datetime. Additional
+// fields lose their leading underscore, those named like a standard field
+// are kept as <key>_orig, see origKey.
+func parseGelf(line string) (map[string]interface{}, bool) {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	var msg map[string]interface{}
+	if err := dec.Decode(&msg); err != nil || msg == nil {
+		return nil, false
+	}
+
+	m := make(map[string]interface{}, len(msg))
+	for k, v := range msg {
+		switch k {
+		case "short_message":
+			m["message"] = v
+		case "full_message":
+			if _, ok := msg["short_message"]; !ok {
+				m["message"] = v
+			} else {
+				m[k] = v
+			}
+		case "level":
+			m[k] = v
+			if n, ok := v.(json.Number); ok {
+				if i, err := n.Int64(); err == nil && i >= 0 && i < int64(len(gelfLevels)) {
+					m[k] = gelfLevels[i]
+					m["severity"] = syslogSeverities[i]
+				}
+			}
+		case "timestamp":
+			m[k] = v
+			if n, ok := v.(json.Number); ok {
+				if t, ok := parseEpoch(n.String()); ok {
+					m["datetime"] = t
+					delete(m, k)
+				}
+			}
+		default:
+			if name := strings.TrimPrefix(k, "_"); name != "" {
+				k = name
+			}
+			if gelfReserved[k] {
+				k = origKey(k)
+			}
+			m[k] = v
+		}
+	}
+	if _, ok := m["message"]; !ok {
+		return nil, false
+	}
+	return m, true
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGelfDecoder(t *testing.T) {
+	input := `{"version": "1.1", "host": "dbsvr", "short_message": "empty counter list", "full_message": "empty counter list\nat GetCounterBatch", "timestamp": 1724317206.956, "level": 3, "_traceID": "16029078675928157035", "_process": 8982}` +
+		"\x00" + `{"version": "1.1", "host": "dbsvr", "full_message": "connection refused", "level": 4}` +
+		"\n" + `{"version": "1.1", "host": "dbsvr"}` + // no message
+		"\n" + `not json` +
+		"\n" + `{"version": "1.1", "host": "dbsvr", "short_message": "started", "level": 6, "_message": "own", "_level": "custom"}` + "\x00"
+
+	invalid := invalidLines.Load()
+	got := decodeAll(t, newGelfDecoder(strings.NewReader(input)))
+	want := []map[string]interface{}{
+		{
+			"version":      "1.1",
+			"host":         "dbsvr",
+			"message":      "empty counter list",
+			"full_message": "empty counter list\nat GetCounterBatch",
+			"datetime":     time.Unix(1724317206, 956000000),
+			"level":        "ERROR",
+			"severity":     "err",
+			"traceID":      "16029078675928157035",
+			"process":      json.Number("8982"),
+		},
+		{"version": "1.1", "host": "dbsvr", "message": "connection refused", "level": "WARN", "severity": "warning"},
+		{"version": "1.1", "host": "dbsvr", "message": "started", "level": "INFO", "severity": "info", "message_orig": "own", "level_orig": "custom"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded\n%v\nwant\n%v", got, want)
+	}
+	if n := invalidLines.Load() - invalid; n != 2 {
+		t.Errorf("%d invalid messages, want 2", n)
+	}
+}
+
+func TestGelfDecoderLongMessages(t *testing.T) {
+	defer func(size int) { maxLineSize = size }(maxLineSize)
+	maxLineSize = 64
+	longLines.Store(0)
+
+	long := `{"version": "1.1", "short_message": "` + strings.Repeat("x", 200) + `"}`
+	input := `{"short_message": "a"}` + "\x00" + long + "\x00" + `{"short_message": "b"}` + "\x00" + long
+	got := decodeAll(t, newGelfDecoder(strings.NewReader(input)))
+	if len(got) != 2 || got[0]["message"] != "a" || got[1]["message"] != "b" {
+		t.Errorf("decoded %v, want messages a and b around the long one", got)
+	}
+	if n := longLines.Load(); n != 2 {
+		t.Errorf("counted %d long messages, want 2", n)
+	}
+}
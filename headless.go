@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// cellReplacer keeps a value on one line of the summary table.
+var cellReplacer = strings.NewReplacer("\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// printSummary writes the groups most frequent first, only the first -top
+// ones if set, as a table of the count, keys and stats columns aligned like
+// the interactive table, or as a JSON array of groups like dumpJSON.
+func printSummary(w io.Writer, asJSON bool) error {
+	store.RLock()
+	defer store.RUnlock()
+
+	indices := store.Sorted(SortCount)
+	if topN > 0 && len(indices) > topN {
+		indices = indices[:topN]
+	}
+	if asJSON {
+		groups := make([]Group, len(indices))
+		for i, index := range indices {
+			groups[i] = store.Group(index)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(groups)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := append([]string{"count"}, keys...)
+	for _, st := range stats {
+		header = append(header, st.String())
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, index := range indices {
+		data := store.Get(index)
+		record := []string{data.GetCount()}
+		for _, key := range keys {
+			record = append(record, cellReplacer.Replace(formatValue(data.Get(key))))
+		}
+		for i, st := range stats {
+			record = append(record, data.GetStat(i, st))
+		}
+		fmt.Fprintln(tw, strings.Join(record, "\t"))
+	}
+	return tw.Flush()
+}
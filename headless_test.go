@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrintSummary(t *testing.T) {
+	keys = []string{"level", "message"}
+	stats = []Stat{{field: "ms", agg: "max"}}
+	store = NewStore(time.Second, defaultTrendBuckets, 3, keys, nil)
+	store.SetStats(stats)
+	defer func() { keys, stats, store = nil, nil, nil }()
+
+	store.Push(map[string]interface{}{"level": "INFO", "message": "started"})
+	store.Push(map[string]interface{}{"level": "ERROR", "message": "disk full\non /var", "ms": json.Number("12")})
+	store.Push(map[string]interface{}{"level": "ERROR", "message": "disk full\non /var", "ms": json.Number("7")})
+
+	var buf bytes.Buffer
+	if err := printSummary(&buf, false); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"count  level  message             ms:max",
+		`2      ERROR  disk full\non /var  12`,
+		"1      INFO   started             ",
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Errorf("printSummary() =\n%s\nwant\n%s", got, want)
+	}
+
+	buf.Reset()
+	if err := printSummary(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	var groups []Group
+	if err := json.Unmarshal(buf.Bytes(), &groups); err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || groups[0].Count != 2 || groups[1].Data["message"] != "started" {
+		t.Errorf("printSummary() JSON groups %v, want ERROR then INFO", groups)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+var (
+	pages     *tview.Pages
+	helpView  *tview.TextView
+	helpOpen  bool
+	helpFocus tview.Primitive
+)
+
+// keyBindings documents the keys of the TUI in the help overlay.
+var keyBindings = []struct {
+	keys, help string
+}{
+	{"↑/↓, j/k", "move the selection"},
+	{"g/G, Home/End", "jump to the first or last row"},
+	{"Ctrl-d/Ctrl-u", "move half a page, scroll the viewer when it has focus"},
+	{"←/→, h/l", "scroll the columns right of the rate, also with Shift"},
+	{"Enter", "open the selected row in the viewer"},
+	{"Esc", "close the viewer, or the pinned viewer with focus"},
+	{"Tab", "move the focus to the next of the table, the viewer and the pinned viewers"},
+	{"p", "pin the selected group in a viewer of its own, next to the others"},
+	{"/", "search, Enter keeps the query, Esc clears it; prefix re: for a regex, case: to match case"},
+	{"e/w/i/a", "show ERROR, WARN and above, INFO and above, or all levels"},
+	{"space", "pause or resume the table and the trend"},
+	{"s", "cycle the sort order"},
+	{"+/-", "raise or lower the distance within which entries combine, regrouping the table"},
+	{"t", "toggle truncating long cells to -max-col-width"},
+	{"c", "choose the displayed columns, Enter toggles a column"},
+	{"x", "export the table as CSV"},
+	{"J", "dump all groups as JSON"},
+	{"y", "copy the selected record to the clipboard"},
+	{"v", "cycle the viewed record through the latest ones kept and the first, in the pinned viewer with focus too"},
+	{"d", "dismiss the selected group, with -dismiss-sticky its entries are dropped from then on"},
+	{"m", "mark the selected group, the viewer then diffs other groups against it, m again clears"},
+	{"?", "show or close this help"},
+	{"Ctrl-C", "quit"},
+}
+
+func newHelpView() *tview.TextView {
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true)
+	view.SetBorder(true).SetTitle(" help, ? or Esc to close ")
+	return view
+}
+
+// helpText lists the key bindings and the current value of every flag.
+func helpText() string {
+	var b strings.Builder
+	b.WriteString("[::b]keys[::-]\n")
+	for _, k := range keyBindings {
+		fmt.Fprintf(&b, "  %-15s %s\n", k.keys, k.help)
+	}
+	if mouse {
+		fmt.Fprintf(&b, "  %-15s %s\n", "mouse", "click a row to open it, click outside to close, wheel to scroll")
+	}
+	aliases := make([]string, 0, len(keyAliases))
+	for key := range keyAliases {
+		aliases = append(aliases, string(key))
+	}
+	sort.Strings(aliases)
+	for _, key := range aliases {
+		fmt.Fprintf(&b, "  %-15s same as %c, from -config\n", key, keyAliases[[]rune(key)[0]])
+	}
+
+	b.WriteString("\n[::b]flags[::-]\n")
+	flag.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(&b, "  -%-14s %s\n", f.Name, escape(f.Value.String()))
+	})
+	return b.String()
+}
+
+// openHelp shows the help overlay above the unchanged layout.
+func openHelp() {
+	helpOpen = true
+	helpFocus = app.GetFocus()
+	helpView.SetText(helpText()).ScrollToBeginning()
+
+	// nil items leave the layout below visible around the overlay
+	overlay := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(helpView, 0, 4, true).
+			AddItem(nil, 0, 1, false), 0, 3, true).
+		AddItem(nil, 0, 1, false)
+	pages.AddPage("help", overlay, true, true)
+	app.SetFocus(helpView)
+}
+
+func closeHelp() {
+	helpOpen = false
+	pages.RemovePage("help")
+	app.SetFocus(helpFocus)
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestHelpText(t *testing.T) {
+	text := helpText()
+	for _, k := range keyBindings {
+		if !strings.Contains(text, k.keys) {
+			t.Errorf("help misses key %q", k.keys)
+		}
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		if !strings.Contains(text, "-"+f.Name+" ") {
+			t.Errorf("help misses flag -%s", f.Name)
+		}
+	})
+}
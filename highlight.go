@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// Highlight colors the substrings of messages matching a regex.
+type Highlight struct {
+	re    *regexp.Regexp
+	color string
+}
+
+// parseHighlight parses a spec like `E\d+=red` or `\d+\.\d+\.\d+\.\d+=#00aaff`.
+// The color follows the last =, so the regex may contain = itself.
+func parseHighlight(spec string) (Highlight, error) {
+	i := strings.LastIndex(spec, "=")
+	if i <= 0 {
+		return Highlight{}, fmt.Errorf("invalid highlight %q, want regex=color", spec)
+	}
+	color := strings.ToLower(strings.TrimSpace(spec[i+1:]))
+	if tcell.GetColor(color) == tcell.ColorDefault {
+		return Highlight{}, fmt.Errorf("invalid highlight %q: unknown color %q", spec, color)
+	}
+	re, err := regexp.Compile(spec[:i])
+	if err != nil {
+		return Highlight{}, fmt.Errorf("invalid highlight %q: %w", spec, err)
+	}
+	return Highlight{re: re, color: color}, nil
+}
+
+func parseHighlights(specs []string) ([]Highlight, error) {
+	highlights := make([]Highlight, 0, len(specs))
+	for _, spec := range specs {
+		h, err := parseHighlight(spec)
+		if err != nil {
+			return nil, err
+		}
+		highlights = append(highlights, h)
+	}
+	return highlights, nil
+}
+
+// span is a range of text shown with a color tag.
+type span struct {
+	start, end int
+	tag        string
+}
+
+// highlightSpans returns the matches in text of search, if not nil, and then
+// of rules as spans sorted by start. Where matches overlap the first one
+// wins, the search query before the rules and the rules in order.
+func highlightSpans(text string, search *regexp.Regexp, rules []Highlight) []span {
+	var spans []span
+	add := func(re *regexp.Regexp, tag string) {
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			if loc[0] == loc[1] || overlaps(spans, loc[0], loc[1]) {
+				continue
+			}
+			spans = append(spans, span{loc[0], loc[1], tag})
+		}
+	}
+	if search != nil {
+		add(search, "[black:yellow]")
+	}
+	if !noColor {
+		for _, h := range rules {
+			add(h.re, "["+h.color+"]")
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start < spans[j].start
+	})
+	return spans
+}
+
+func overlaps(spans []span, start, end int) bool {
+	for _, s := range spans {
+		if start < s.end && s.start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// highlightCell escapes the text of column key for a table cell and
+// highlights the occurrences of the search query, and in the message those
+// of the -highlight rules. The brackets to escape are found in the whole
+// text, a match within them like the b of [abc] must not split them up.
+func highlightCell(key, text string) string {
+	var rules []Highlight
+	if key == "message" {
+		rules = highlights
+	}
+	spans := highlightSpans(text, searchRegexp, rules)
+	if len(spans) == 0 {
+		return escape(text)
+	}
+
+	// escape adds a [ before the closing bracket of every [...] group.
+	var closing []int
+	for _, loc := range escapeRE.FindAllStringIndex(text, -1) {
+		closing = append(closing, loc[1]-1)
+	}
+	var b strings.Builder
+	last := 0
+	write := func(end int) {
+		for len(closing) > 0 && closing[0] < end {
+			b.WriteString(text[last:closing[0]])
+			b.WriteByte('[')
+			last, closing = closing[0], closing[1:]
+		}
+		b.WriteString(text[last:end])
+		last = end
+	}
+	for _, sp := range spans {
+		write(sp.start)
+		b.WriteString(sp.tag)
+		write(sp.end)
+		b.WriteString("[-:-]")
+	}
+	write(len(text))
+	return b.String()
+}
+
+// viewerTagRE matches the color tags of tview.TranslateANSI, which always
+// have a colon.
+var viewerTagRE = regexp.MustCompile(`\[[a-zA-Z0-9#\-]*:[a-zA-Z0-9#\-]*(?::[a-z\-]*)?\]`)
+
+// highlightViewer colors the matches of the -highlight rules in the viewer
+// text, which has color tags already. Matches are searched between tags, and
+// the color before a match is restored after it.
+func highlightViewer(text string) string {
+	if len(highlights) == 0 || noColor {
+		return text
+	}
+	var b strings.Builder
+	current := "[-:-:-]"
+	last := 0
+	segment := func(s string) {
+		i := 0
+		for _, sp := range highlightSpans(s, nil, highlights) {
+			b.WriteString(s[i:sp.start] + sp.tag + s[sp.start:sp.end] + current)
+			i = sp.end
+		}
+		b.WriteString(s[i:])
+	}
+	for _, loc := range viewerTagRE.FindAllStringIndex(text, -1) {
+		segment(text[last:loc[0]])
+		current = text[loc[0]:loc[1]]
+		b.WriteString(current)
+		last = loc[1]
+	}
+	segment(text[last:])
+	return b.String()
+}
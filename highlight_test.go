@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+func TestParseHighlight(t *testing.T) {
+	tests := []struct {
+		spec  string
+		expr  string
+		color string
+		err   bool
+	}{
+		{`E\d+=red`, `E\d+`, "red", false},
+		{`a=b=#00aaff`, `a=b`, "#00aaff", false},
+		{`timeout= Yellow `, `timeout`, "yellow", false},
+		{`=red`, "", "", true},
+		{`E\d+`, "", "", true},
+		{`E\d+=reddish`, "", "", true},
+		{`E(\d+=red`, "", "", true},
+	}
+	for i, tt := range tests {
+		h, err := parseHighlight(tt.spec)
+		if (err != nil) != tt.err {
+			t.Errorf("Test[%d]: parseHighlight(%q) error = %v, want error %v", i, tt.spec, err, tt.err)
+			continue
+		}
+		if err == nil && (h.re.String() != tt.expr || h.color != tt.color) {
+			t.Errorf("Test[%d]: parseHighlight(%q) = %s %s, want %s %s", i, tt.spec, h.re, h.color, tt.expr, tt.color)
+		}
+	}
+}
+
+func TestHighlightCell(t *testing.T) {
+	var err error
+	highlights, err = parseHighlights([]string{`E\d+=red`, `E1\d*=green`, `\d+\.\d+\.\d+\.\d+=blue`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { highlights = nil }()
+	defer setSearch("")
+
+	tests := []struct {
+		query, key, text, want string
+	}{
+		{"", "message", "E1005 from 10.0.0.1", "[red]E1005[-:-] from [blue]10.0.0.1[-:-]"},
+		{"", "level", "E1005", "E1005"},
+		{"", "message", "[E5] done", "[[red]E5[-:-][] done"},
+		{"from", "message", "E1005 from 10.0.0.1", "[red]E1005[-:-] [black:yellow]from[-:-] [blue]10.0.0.1[-:-]"},
+		{"e10", "message", "E1005 failed", "[black:yellow]E10[-:-]05 failed"},
+		{"b", "level", "[abc]", "[a[black:yellow]b[-:-]c[]"},
+		{"abc", "level", "x [abc] y", "x [[black:yellow]abc[-:-][] y"},
+		{"c] [d", "level", "[abc] [def]", "[ab[black:yellow]c[] [d[-:-]ef[]"},
+	}
+	for i, tt := range tests {
+		setSearch(tt.query)
+		got := highlightCell(tt.key, tt.text)
+		if got != tt.want {
+			t.Errorf("Test[%d]: highlightCell(%q, %q) = %q, want %q", i, tt.key, tt.text, got, tt.want)
+		}
+		if shown := printed(got); shown != tt.text {
+			t.Errorf("Test[%d]: highlightCell(%q, %q) shows %q", i, tt.key, tt.text, shown)
+		}
+	}
+
+	setSearch("")
+	noColor = true
+	defer func() { noColor = false }()
+	if got := highlightCell("message", "E1005"); got != "E1005" {
+		t.Errorf("highlightCell() with -no-color = %q, want E1005", got)
+	}
+}
+
+// printed returns the text tview prints for s, without the color tags.
+func printed(s string) string {
+	screen := tcell.NewSimulationScreen("")
+	screen.Init()
+	defer screen.Fini()
+	screen.SetSize(80, 1)
+	tview.Print(screen, s, 0, 0, 80, tview.AlignLeft, tcell.ColorDefault)
+	screen.Show()
+	cells, _, _ := screen.GetContents()
+	var b strings.Builder
+	for _, cell := range cells {
+		b.WriteString(string(cell.Runes))
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+func TestHighlightViewer(t *testing.T) {
+	var err error
+	highlights, err = parseHighlights([]string{`E\d+=red`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { highlights = nil }()
+
+	text := `{` + "\n" + `  [blue::b]"message"[-:-:-]: [green::b]"E1005 and E7"[-:-:-]` + "\n}"
+	want := `{` + "\n" + `  [blue::b]"message"[-:-:-]: [green::b]"[red]E1005[green::b] and [red]E7[green::b]"[-:-:-]` + "\n}"
+	if got := highlightViewer(text); got != want {
+		t.Errorf("highlightViewer() = %q, want %q", got, want)
+	}
+}
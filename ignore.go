@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// ignoredLines counts the lines and records dropped by -ignore and
+// -ignore-field.
+var ignoredLines atomic.Int64
+
+var (
+	// ignores drop the input lines they match before decoding, see -ignore.
+	ignores []*regexp.Regexp
+	// ignoreFields drop the records whose field matches, see -ignore-field.
+	ignoreFields []IgnoreField
+)
+
+// IgnoreField drops records whose field key matches re.
+type IgnoreField struct {
+	key string
+	re  *regexp.Regexp
+}
+
+func parseIgnores(specs []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(specs))
+	for _, spec := range specs {
+		re, err := regexp.Compile(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore %q: %w", spec, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// parseIgnoreField parses a spec like `level=DEBUG|TRACE`.
+func parseIgnoreField(spec string) (IgnoreField, error) {
+	key, expr, ok := strings.Cut(spec, "=")
+	if key = strings.TrimSpace(key); !ok || key == "" {
+		return IgnoreField{}, fmt.Errorf("invalid ignore field %q, want key=regex", spec)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return IgnoreField{}, fmt.Errorf("invalid ignore field %q: %w", spec, err)
+	}
+	return IgnoreField{key: key, re: re}, nil
+}
+
+func parseIgnoreFields(specs []string) ([]IgnoreField, error) {
+	fields := make([]IgnoreField, 0, len(specs))
+	for _, spec := range specs {
+		f, err := parseIgnoreField(spec)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// ignoreRecord reports whether a field of value matches an -ignore-field
+// rule, counting the record as ignored if so. Missing fields never match.
+func ignoreRecord(fields []IgnoreField, value map[string]interface{}) bool {
+	for _, f := range fields {
+		v, ok := lookup(value, f.key)
+		if ok && f.re.MatchString(fmt.Sprintf("%v", v)) {
+			ignoredLines.Add(1)
+			return true
+		}
+	}
+	return false
+}
+
+// ignoreReader passes the lines of r not matching any of res.
+type ignoreReader struct {
+	r   *bufio.Reader
+	res []*regexp.Regexp
+	buf []byte
+	err error
+}
+
+func newIgnoreReader(r io.Reader, res []*regexp.Regexp) *ignoreReader {
+	return &ignoreReader{r: bufio.NewReader(r), res: res}
+}
+
+func (r *ignoreReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		line, err := r.r.ReadBytes('\n')
+		r.err = err
+		if len(line) > 0 && r.ignored(strings.TrimRight(string(line), "\r\n")) {
+			ignoredLines.Add(1)
+			continue
+		}
+		r.buf = line
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *ignoreReader) ignored(line string) bool {
+	for _, re := range r.res {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
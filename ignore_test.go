@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreReader(t *testing.T) {
+	ignoredLines.Store(0)
+	res, err := parseIgnores([]string{"healthz", `^\s*$`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := "GET /users 200\nGET /healthz 200\r\n\nPOST /users 201\nGET /healthz"
+	out, err := io.ReadAll(newIgnoreReader(strings.NewReader(in), res))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "GET /users 200\nPOST /users 201\n"; string(out) != want {
+		t.Errorf("read %q, want %q", out, want)
+	}
+	if n := ignoredLines.Load(); n != 3 {
+		t.Errorf("ignored %d lines, want 3", n)
+	}
+	if _, err := parseIgnores([]string{"("}); err == nil {
+		t.Error("parseIgnores accepted an invalid regex")
+	}
+}
+
+func TestIgnoreRecord(t *testing.T) {
+	ignoredLines.Store(0)
+	fields, err := parseIgnoreFields([]string{"level=^(DEBUG|TRACE)$", "path=/healthz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value   map[string]interface{}
+		ignored bool
+	}{
+		{map[string]interface{}{"level": "DEBUG", "message": "cache hit"}, true},
+		{map[string]interface{}{"level": "DEBUGGING"}, false},
+		{map[string]interface{}{"level": "INFO", "path": "/healthz"}, true},
+		{map[string]interface{}{"message": "no level"}, false},
+	}
+	for i, tt := range tests {
+		if got := ignoreRecord(fields, tt.value); got != tt.ignored {
+			t.Errorf("Test[%d]: ignoreRecord(%v) = %v, want %v", i, tt.value, got, tt.ignored)
+		}
+	}
+	if n := ignoredLines.Load(); n != 2 {
+		t.Errorf("ignored %d records, want 2", n)
+	}
+	for _, spec := range []string{"level", "=x", "level=("} {
+		if _, err := parseIgnoreField(spec); err == nil {
+			t.Errorf("parseIgnoreField(%q) succeeded", spec)
+		}
+	}
+}
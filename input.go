@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stringsFlag is a flag.Value collecting every occurrence of a repeated flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// consumeAll updates the store with the records of every input, each read
+// concurrently by a decoder of its own, and returns once all of them ended.
+// failed is called with the error of every input failing to decode.
+func consumeAll(inputs []io.ReadCloser, failed func(error)) {
+	var wg sync.WaitGroup
+	for _, in := range inputs {
+		wg.Add(1)
+		go func(in io.ReadCloser) {
+			defer wg.Done()
+			if err := consume(newSourceDecoder(in)); err != nil {
+				failed(err)
+			}
+		}(in)
+	}
+	wg.Wait()
+}
+
+// openStdin returns stdin, decompressed if gzipped.
+func openStdin(gzipped bool) (io.ReadCloser, error) {
+	if !gzipped {
+		return os.Stdin, nil
+	}
+	in, err := newGzipReader(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("stdin: %w", err)
+	}
+	return in, nil
+}
+
+// expandFiles expands shell-style glob patterns. Patterns matching nothing
+// are kept as is, so that opening them reports a meaningful error.
+func expandFiles(patterns []string) ([]string, error) {
+	var names []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			matches = []string{pattern}
+		}
+		names = append(names, matches...)
+	}
+	return names, nil
+}
+
+// openInputs opens the files given by -file, or returns stdin if there are none.
+// With follow set the files are tailed for appended data. Stdin is never
+// wrapped, a pipe blocks until the writer closes it anyway. Files ending in
+// .gz, and stdin if gzipped is set, are decompressed. With -tail only the
+// last lines of uncompressed files are read.
+func openInputs(patterns []string, follow, gzipped bool) ([]io.ReadCloser, error) {
+	if len(patterns) == 0 && tailLines >= 0 {
+		return nil, fmt.Errorf("-tail needs -file, stdin can't be read from the end")
+	}
+	if len(patterns) == 0 {
+		in, err := openStdin(gzipped)
+		if err != nil {
+			return nil, err
+		}
+		return []io.ReadCloser{namedInput{in, stdinName}}, nil
+	}
+
+	names, err := expandFiles(patterns)
+	if err != nil {
+		return nil, err
+	}
+	var inputs []io.ReadCloser
+	if mergeStdin {
+		in, err := openStdin(gzipped)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, namedInput{in, stdinName})
+	}
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			closeInputs(inputs)
+			return nil, err
+		}
+		if tailLines >= 0 {
+			err := fmt.Errorf("-tail can't read a compressed file from the end")
+			if !strings.HasSuffix(name, ".gz") {
+				err = seekTail(f, tailLines)
+			}
+			if err != nil {
+				f.Close()
+				closeInputs(inputs)
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		var in io.ReadCloser = f
+		if fi, err := f.Stat(); err == nil && !follow && fi.Mode().IsRegular() {
+			// Compressed files progress by the compressed bytes read.
+			offset, _ := f.Seek(0, io.SeekCurrent)
+			progress.size += fi.Size() - offset
+			in = countingReader{f}
+		}
+		if strings.HasSuffix(name, ".gz") {
+			in, err := newGzipReader(in)
+			if err != nil {
+				f.Close()
+				closeInputs(inputs)
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			inputs = append(inputs, namedInput{in, name})
+			continue
+		}
+		if follow {
+			inputs = append(inputs, namedInput{newFollowReader(f), name})
+			continue
+		}
+		inputs = append(inputs, namedInput{in, name})
+	}
+	return inputs, nil
+}
+
+// gzipReader decompresses a possibly multi-stream gzip input and closes the
+// underlying input on Close.
+type gzipReader struct {
+	*gzip.Reader
+	in io.ReadCloser
+}
+
+func newGzipReader(in io.ReadCloser) (*gzipReader, error) {
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip stream: %w", err)
+	}
+	return &gzipReader{Reader: zr, in: in}, nil
+}
+
+func (r *gzipReader) Close() error {
+	r.Reader.Close()
+	return r.in.Close()
+}
+
+func closeInputs(inputs []io.ReadCloser) {
+	for _, in := range inputs {
+		in.Close()
+	}
+}
+
+// formatMu guards the detection of the -format auto.
+var formatMu sync.Mutex
+
+// newDecoder creates a decoder for the -format flag, flattening nested
+// values if -flatten is set.
+func newDecoder(r io.Reader) Decoder {
+	// The first input decides the format of all of them. Connections to
+	// -listen create decoders concurrently.
+	if len(ignores) > 0 {
+		r = newIgnoreReader(r, ignores)
+	}
+	formatMu.Lock()
+	if format == autoFormat {
+		br := bufio.NewReader(r)
+		format = detectFormat(br)
+		log.Printf("info: detected %s format", format)
+		r = br
+	}
+	formatMu.Unlock()
+	dec := newFormatDecoder(format, r)
+	if dec != nil && flatten {
+		return flattenDecoder{dec}
+	}
+	return dec
+}
+
+// currentFormat returns the -format, the detected one once the first input
+// began with auto.
+func currentFormat() string {
+	formatMu.Lock()
+	defer formatMu.Unlock()
+	return format
+}
+
+// newFormatDecoder creates a decoder for the format name, or returns nil if
+// the format is unknown.
+func newFormatDecoder(name string, r io.Reader) Decoder {
+	switch name {
+	case "json":
+		if strict {
+			return newJsonLinesDecoder(r, rejects)
+		}
+		return newJsonDecoder(r)
+	case "zaplog":
+		d := newZaplogDecoder(r, timeLayout)
+		d.sep = zapSep
+		d.nest = nestFields
+		return d
+	case "logfmt":
+		return newLogfmtDecoder(r)
+	case "syslog":
+		return newSyslogDecoder(r)
+	case "nginx":
+		return newNginxDecoder(r, nginxLog)
+	case "combined":
+		return newCombinedDecoder(r)
+	case "gelf":
+		return newGelfDecoder(r)
+	case "klog":
+		return newKlogDecoder(r)
+	case "cri":
+		return newCriDecoder(r, criFormat)
+	case "journald":
+		return newJournaldDecoder(r)
+	case "csv":
+		return newCsvDecoder(r, csvDelim)
+	}
+	return nil
+}
+
+// mergeDecoder merges records of several decoders in datetime order. Every
+// input is expected to be sorted already, records without a parsable datetime
+// are emitted as soon as they are read.
+type mergeDecoder struct {
+	decs  []Decoder
+	heads []map[string]interface{}
+	raws  []string
+	times []time.Time
+	raw   string
+	err   error
+}
+
+func newMergeDecoder(decs []Decoder) *mergeDecoder {
+	return &mergeDecoder{
+		decs:  decs,
+		heads: make([]map[string]interface{}, len(decs)),
+		raws:  make([]string, len(decs)),
+		times: make([]time.Time, len(decs)),
+	}
+}
+
+// fill reads the next record of every input which has none buffered.
+func (d *mergeDecoder) fill() {
+	for i, dec := range d.decs {
+		for d.heads[i] == nil && d.err == nil && dec.More() {
+			m, err := dec.Decode()
+			if err == io.EOF {
+				continue
+			}
+			if err != nil {
+				d.err = err
+				return
+			}
+			d.heads[i] = m
+			d.raws[i] = rawText(dec)
+			d.times[i], _ = recordTime(m)
+		}
+	}
+}
+
+func (d *mergeDecoder) Decode() (map[string]interface{}, error) {
+	d.fill()
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	next := -1
+	for i, m := range d.heads {
+		if m != nil && (next < 0 || d.times[i].Before(d.times[next])) {
+			next = i
+		}
+	}
+	if next < 0 {
+		return nil, io.EOF
+	}
+
+	m := d.heads[next]
+	d.heads[next] = nil
+	d.raw = d.raws[next]
+	return m, nil
+}
+
+// Raw returns the text of the record decoded last, as its input has it.
+func (d *mergeDecoder) Raw() string {
+	return d.raw
+}
+
+func (d *mergeDecoder) More() bool {
+	d.fill()
+	if d.err != nil {
+		return true // let Decode report the error
+	}
+	for _, m := range d.heads {
+		if m != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeDecoderOrdersByDatetime(t *testing.T) {
+	a := strings.Join([]string{
+		`{"datetime": "2024-08-22 09:00:01", "message": "a1"}`,
+		`{"datetime": "2024-08-22 09:00:04", "message": "a2"}`,
+	}, "\n")
+	b := strings.Join([]string{
+		`{"datetime": "2024-08-22 09:00:02", "message": "b1"}`,
+		`{"datetime": "2024-08-22 09:00:03", "message": "b2"}`,
+		`{"datetime": "2024-08-22 09:00:05", "message": "b3"}`,
+	}, "\n")
+
+	dec := newMergeDecoder([]Decoder{
+		newJsonDecoder(strings.NewReader(a)),
+		newJsonDecoder(strings.NewReader(b)),
+	})
+	var got []string
+	for _, m := range decodeAll(t, dec) {
+		got = append(got, m["message"].(string))
+	}
+
+	want := "a1,b1,b2,a2,b3"
+	if strings.Join(got, ",") != want {
+		t.Errorf("merged messages %q, want %s", got, want)
+	}
+}
+
+func TestMergeDecoderConcatenatesWithoutDatetime(t *testing.T) {
+	dec := newMergeDecoder([]Decoder{
+		newLogfmtDecoder(strings.NewReader("msg=a1\nmsg=a2\n")),
+		newLogfmtDecoder(strings.NewReader("msg=b1\n")),
+	})
+	var got []string
+	for _, m := range decodeAll(t, dec) {
+		got = append(got, m["msg"].(string))
+	}
+
+	want := "a1,a2,b1"
+	if strings.Join(got, ",") != want {
+		t.Errorf("merged messages %q, want %s", got, want)
+	}
+}
+
+func TestOpenInputsGzip(t *testing.T) {
+	dir := t.TempDir()
+
+	// two concatenated gzip members, like `cat a.gz b.gz`
+	var buf bytes.Buffer
+	for _, part := range []string{"msg=a\n", "msg=b\n"} {
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(part))
+		zw.Close()
+	}
+	name := filepath.Join(dir, "app.log.gz")
+	if err := os.WriteFile(name, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	inputs, err := openInputs([]string{name}, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeInputs(inputs)
+	data, err := io.ReadAll(inputs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "msg=a\nmsg=b\n" {
+		t.Errorf("read %q, want both gzip members", data)
+	}
+
+	invalid := filepath.Join(dir, "plain.gz")
+	if err := os.WriteFile(invalid, []byte("msg=a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openInputs([]string{invalid}, false, false); err == nil || !strings.Contains(err.Error(), "invalid gzip") {
+		t.Errorf("openInputs(%s) error = %v, want invalid gzip stream", invalid, err)
+	}
+}
+
+func TestMergeStdinAndFiles(t *testing.T) {
+	format = "json"
+	keys = []string{"message"}
+	store = NewStore(time.Second, defaultTrendBuckets, 1, keys, nil)
+	mergeStdin = true
+	defer func() { format, keys, store, mergeStdin = autoFormat, nil, nil, false }()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+	go func() {
+		w.Write([]byte("{\"message\": \"live\"}\n{\"message\": \"live\"}\n"))
+		w.Close()
+	}()
+
+	name := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(name, []byte("{\"message\": \"history\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inputs, err := openInputs([]string{name}, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeInputs(inputs)
+	if len(inputs) != 2 {
+		t.Fatalf("opened %d inputs, want stdin and the file", len(inputs))
+	}
+
+	consumeAll(inputs, func(err error) { t.Error(err) })
+	if store.Total() != 3 || store.Len() != 2 {
+		t.Errorf("%d entries in %d groups, want 3 in 2", store.Total(), store.Len())
+	}
+}
@@ -45,6 +45,9 @@ type Formatter struct {
 
 	// Newline string. To print without new lines set it to empty string. Default is \n.
 	Newline string
+
+	// Keys printed first in objects, in this order. The other keys follow sorted. Default is none.
+	KeyOrder []string
 }
 
 // NewFormatter returns a new formatter with following default values.
@@ -153,7 +156,7 @@ func (f *Formatter) processMap(m map[string]interface{}, depth int) string {
 		keys = append(keys, key)
 	}
 
-	sort.Strings(keys)
+	OrderKeys(keys, f.KeyOrder)
 
 	for _, key := range keys {
 		val := m[key]
@@ -201,3 +204,25 @@ func Marshal(v interface{}) ([]byte, error) {
 func Format(data []byte) ([]byte, error) {
 	return NewFormatter().Format(data)
 }
+
+// OrderKeys sorts keys in place, those in order first and in that order, the
+// others alphabetically after them.
+func OrderKeys(keys []string, order []string) {
+	rank := make(map[string]int, len(order))
+	for i, key := range order {
+		if _, ok := rank[key]; !ok {
+			rank[key] = i
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ri, oki := rank[keys[i]]
+		rj, okj := rank[keys[j]]
+		switch {
+		case oki && okj:
+			return ri < rj
+		case oki != okj:
+			return oki
+		}
+		return keys[i] < keys[j]
+	})
+}
@@ -0,0 +1,38 @@
+package prettyjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrderKeys(t *testing.T) {
+	tests := []struct {
+		keys  []string
+		order []string
+		want  []string
+	}{
+		{[]string{"b", "a", "c"}, nil, []string{"a", "b", "c"}},
+		{[]string{"caller", "message", "level", "datetime", "app"}, []string{"datetime", "level", "message"}, []string{"datetime", "level", "message", "app", "caller"}},
+		{[]string{"message", "app"}, []string{"datetime", "message", "datetime"}, []string{"message", "app"}},
+	}
+	for i, tt := range tests {
+		OrderKeys(tt.keys, tt.order)
+		if !reflect.DeepEqual(tt.keys, tt.want) {
+			t.Errorf("Test[%d]: OrderKeys() = %v, want %v", i, tt.keys, tt.want)
+		}
+	}
+}
+
+func TestFormatterKeyOrder(t *testing.T) {
+	f := NewFormatter()
+	f.DisabledColor = true
+	f.KeyOrder = []string{"message"}
+	got, err := f.Marshal(map[string]interface{}{"a": 1, "message": "x", "b": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n  \"message\": \"x\",\n  \"a\": 1,\n  \"b\": 2\n}"
+	if string(got) != want {
+		t.Errorf("Marshal() = %q, want %q", got, want)
+	}
+}
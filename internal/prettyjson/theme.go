@@ -0,0 +1,77 @@
+package prettyjson
+
+import (
+	"sort"
+
+	"github.com/fatih/color"
+)
+
+// Theme holds the colors of the JSON tokens. A nil color prints the token
+// uncolored.
+type Theme struct {
+	Key    *color.Color
+	String *color.Color
+	Number *color.Color
+	Bool   *color.Color
+	Null   *color.Color
+}
+
+// rgb returns a 24-bit foreground color.
+func rgb(r, g, b int) *color.Color {
+	return color.New(38, 2, color.Attribute(r), color.Attribute(g), color.Attribute(b))
+}
+
+// Themes are the themes selectable by name. default has the colors of
+// NewFormatter, none disables colors.
+var Themes = map[string]Theme{
+	"default": {
+		Key:    color.New(color.FgBlue, color.Bold),
+		String: color.New(color.FgGreen, color.Bold),
+		Number: color.New(color.FgCyan, color.Bold),
+		Bool:   color.New(color.FgYellow, color.Bold),
+		Null:   color.New(color.FgBlack, color.Bold),
+	},
+	"monokai": {
+		Key:    rgb(0xf9, 0x26, 0x72),
+		String: rgb(0xe6, 0xdb, 0x74),
+		Number: rgb(0xae, 0x81, 0xff),
+		Bool:   rgb(0x66, 0xd9, 0xef),
+		Null:   rgb(0x75, 0x71, 0x5e),
+	},
+	"solarized": {
+		Key:    rgb(0x26, 0x8b, 0xd2),
+		String: rgb(0x2a, 0xa1, 0x98),
+		Number: rgb(0xd3, 0x36, 0x82),
+		Bool:   rgb(0xb5, 0x89, 0x00),
+		Null:   rgb(0x93, 0xa1, 0xa1),
+	},
+	"none": {},
+}
+
+// ThemeNames returns the sorted names of Themes.
+func ThemeNames() []string {
+	names := make([]string, 0, len(Themes))
+	for name := range Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetTheme sets the token colors of the formatter.
+func (f *Formatter) SetTheme(t Theme) {
+	f.KeyColor = t.Key
+	f.StringColor = t.String
+	f.NumberColor = t.Number
+	f.BoolColor = t.Bool
+	f.NullColor = t.Null
+}
+
+// MarshalWithTheme marshals and formats JSON data with the colors of theme.
+// Like the default colors, they are left out when color.NoColor is set,
+// which it is if stdout isn't a terminal.
+func MarshalWithTheme(v interface{}, theme Theme) ([]byte, error) {
+	f := NewFormatter()
+	f.SetTheme(theme)
+	return f.Marshal(v)
+}
@@ -0,0 +1,45 @@
+package prettyjson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestMarshalWithTheme(t *testing.T) {
+	defer func(v bool) { color.NoColor = v }(color.NoColor)
+	color.NoColor = false
+
+	v := map[string]interface{}{"a": "x", "b": json.Number("1"), "c": true, "d": nil}
+	tests := []struct {
+		theme string
+		want  string
+	}{
+		{"none", "{\n  \"a\": \"x\",\n  \"b\": 1,\n  \"c\": true,\n  \"d\": null\n}"},
+		{"solarized", "{\n" +
+			"  \x1b[38;2;38;139;210m\"a\"\x1b[0m: \x1b[38;2;42;161;152m\"x\"\x1b[0m,\n" +
+			"  \x1b[38;2;38;139;210m\"b\"\x1b[0m: \x1b[38;2;211;54;130m1\x1b[0m,\n" +
+			"  \x1b[38;2;38;139;210m\"c\"\x1b[0m: \x1b[38;2;181;137;0mtrue\x1b[0m,\n" +
+			"  \x1b[38;2;38;139;210m\"d\"\x1b[0m: \x1b[38;2;147;161;161mnull\x1b[0m\n" +
+			"}"},
+	}
+	for i, tt := range tests {
+		got, err := MarshalWithTheme(v, Themes[tt.theme])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Test[%d]: MarshalWithTheme(%s) = %q, want %q", i, tt.theme, got, tt.want)
+		}
+	}
+
+	color.NoColor = true
+	got, err := MarshalWithTheme(v, Themes["monokai"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := tests[0].want; string(got) != want {
+		t.Errorf("MarshalWithTheme(monokai) without a terminal = %q, want %q", got, want)
+	}
+}
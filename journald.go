@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxJournaldField bounds the length of binary fields, a corrupt length
+// shouldn't allocate gigabytes.
+const maxJournaldField = 64 << 20
+
+// journaldDecoder decodes the export format written by journalctl -o export,
+// records of KEY=value lines separated by blank lines, e.g.
+// __REALTIME_TIMESTAMP=1724317206956789
+// PRIORITY=3
+// MESSAGE=empty counter list
+//
+// Values holding newlines or binary data are written as the key on a line of
+// its own, followed by the value's little endian 64 bit length, the value
+// and a newline.
+type journaldDecoder struct {
+	r   *bufio.Reader
+	err error
+
+	// raw is the text of the record decoded last, with binary fields as
+	// KEY=value.
+	raw strings.Builder
+}
+
+func newJournaldDecoder(r io.Reader) *journaldDecoder {
+	return &journaldDecoder{
+		r: bufio.NewReader(r),
+	}
+}
+
+func (d *journaldDecoder) More() bool {
+	if d.err != nil {
+		return false
+	}
+	_, err := d.r.Peek(1)
+	return err == nil
+}
+
+func (d *journaldDecoder) Decode() (map[string]interface{}, error) {
+	fields := map[string]string{}
+	d.raw.Reset()
+	for {
+		line, err := d.r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			d.err = err
+			return nil, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(fields) > 0 || err == io.EOF {
+				break
+			}
+			continue
+		}
+
+		if d.raw.Len() > 0 {
+			d.raw.WriteByte('\n')
+		}
+		if k, v, ok := strings.Cut(line, "="); ok {
+			fields[k] = v
+			d.raw.WriteString(line)
+		} else if err == io.EOF {
+			invalidEntry(line)
+			break
+		} else {
+			v, err := d.readBinary()
+			if err != nil {
+				d.err = fmt.Errorf("journald field %s: %w", line, err)
+				return nil, d.err
+			}
+			fields[line] = v
+			d.raw.WriteString(line + "=" + v)
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if len(fields) == 0 {
+		return nil, io.EOF
+	}
+	return journaldRecord(fields), nil
+}
+
+// Raw returns the fields of the record decoded last.
+func (d *journaldDecoder) Raw() string {
+	return d.raw.String()
+}
+
+// readBinary reads the length prefixed value of a binary field.
+func (d *journaldDecoder) readBinary() (string, error) {
+	var n uint64
+	if err := binary.Read(d.r, binary.LittleEndian, &n); err != nil {
+		return "", noEOF(err)
+	}
+	if n > maxJournaldField {
+		return "", fmt.Errorf("length %d exceeds %d bytes", n, maxJournaldField)
+	}
+	b := make([]byte, n+1)
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		return "", noEOF(err)
+	}
+	if b[n] != '\n' {
+		return "", fmt.Errorf("missing newline after %d bytes", n)
+	}
+	return string(b[:n]), nil
+}
+
+// noEOF reports a record cut short as an unexpected EOF.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// journaldRecord maps journal fields to red's keys: MESSAGE becomes message,
+// PRIORITY a level name and __REALTIME_TIMESTAMP the datetime. The other
+// address fields starting with two underscores, like __CURSOR, are unique
+// per record and dropped.
+func journaldRecord(fields map[string]string) map[string]interface{} {
+	m := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		switch {
+		case k == "MESSAGE":
+			m["message"] = v
+		case k == "PRIORITY":
+			m[k] = toValue(v)
+			if i, err := strconv.Atoi(v); err == nil && i >= 0 && i < len(gelfLevels) {
+				m["level"] = gelfLevels[i]
+				m["severity"] = syslogSeverities[i]
+				delete(m, k)
+			}
+		case k == "__REALTIME_TIMESTAMP":
+			if us, err := strconv.ParseInt(v, 10, 64); err == nil {
+				m["datetime"] = time.UnixMicro(us)
+			}
+		case strings.HasPrefix(k, "__"):
+		default:
+			m[k] = toValue(v)
+		}
+	}
+	return m
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// journaldBinary encodes a binary journal field.
+func journaldBinary(key, value string) string {
+	n := make([]byte, 8)
+	binary.LittleEndian.PutUint64(n, uint64(len(value)))
+	return key + "\n" + string(n) + value + "\n"
+}
+
+func TestJournaldDecoder(t *testing.T) {
+	input := "__CURSOR=s=739ad463348b4ceca5a9e69c95a3c93f;i=4ece7\n" +
+		"__REALTIME_TIMESTAMP=1724317206956789\n" +
+		"__MONOTONIC_TIMESTAMP=2861846413\n" +
+		"_PID=8982\n" +
+		"PRIORITY=3\n" +
+		"MESSAGE=empty counter list\n" +
+		"\n" +
+		"\n" +
+		journaldBinary("MESSAGE", "connection refused\nat dial") +
+		"PRIORITY=4\n" +
+		"SYSLOG_IDENTIFIER=dbsvr\n" +
+		"\n" +
+		"MESSAGE=no priority"
+
+	got := decodeAll(t, newJournaldDecoder(strings.NewReader(input)))
+	want := []map[string]interface{}{
+		{
+			"datetime": time.UnixMicro(1724317206956789),
+			"_PID":     json.Number("8982"),
+			"level":    "ERROR",
+			"severity": "err",
+			"message":  "empty counter list",
+		},
+		{
+			"message":           "connection refused\nat dial",
+			"level":             "WARN",
+			"severity":          "warning",
+			"SYSLOG_IDENTIFIER": "dbsvr",
+		},
+		{"message": "no priority"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestJournaldDecoderTruncated(t *testing.T) {
+	tests := []string{
+		"MESSAGE\n\x05\x00",
+		journaldBinary("MESSAGE", "cut")[:12],
+		"MESSAGE\n\x01\x00\x00\x00\x00\x00\x00\x00ab\n",
+		"MESSAGE\n\xff\xff\xff\xff\xff\xff\xff\xff",
+	}
+	for i, input := range tests {
+		dec := newJournaldDecoder(strings.NewReader(input))
+		if _, err := dec.Decode(); err == nil || errors.Is(err, io.EOF) {
+			t.Errorf("Test[%d]: Decode() error = %v, want a decoding error", i, err)
+		}
+		if dec.More() {
+			t.Errorf("Test[%d]: More() = true after an error", i)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Rejected line counters of the strict JSON-lines decoder, by category.
+var (
+	jsonSyntaxErrors atomic.Int64
+	jsonNotObjects   atomic.Int64
+)
+
+// jsonLinesDecoder decodes one JSON object per line. Unlike jsonDecoder it
+// survives malformed input: lines with a syntax error, or holding a JSON
+// value which is not an object, are counted and written to rejects.
+type jsonLinesDecoder struct {
+	*lineScanner
+	rejects io.Writer
+}
+
+func newJsonLinesDecoder(r io.Reader, rejects io.Writer) *jsonLinesDecoder {
+	return &jsonLinesDecoder{
+		lineScanner: newLineScanner(r),
+		rejects:     rejects,
+	}
+}
+
+func (d *jsonLinesDecoder) Decode() (map[string]interface{}, error) {
+	for {
+		d.Begin()
+		line, ok := d.Next()
+		if !ok {
+			if err := d.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var v interface{}
+		dec := json.NewDecoder(bytes.NewBufferString(line))
+		dec.UseNumber()
+		err := dec.Decode(&v)
+		if err == nil && dec.More() {
+			err = fmt.Errorf("unexpected data after JSON value")
+		}
+		if err != nil {
+			jsonSyntaxErrors.Add(1)
+			d.reject("syntax error", line, err)
+			continue
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			jsonNotObjects.Add(1)
+			d.reject("not an object", line, fmt.Errorf("got %T", v))
+			continue
+		}
+		keepDuplicates([]byte(line), m)
+		return m, nil
+	}
+}
+
+func (d *jsonLinesDecoder) reject(category, line string, err error) {
+	invalidLines.Add(1)
+	if !quiet {
+		log.Printf("warn: rejected json line, %s: %v - %s", category, err, line)
+	}
+	if d.rejects != nil {
+		fmt.Fprintln(d.rejects, line)
+	}
+}
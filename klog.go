@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var klogLevels = map[string]string{
+	"I": "INFO",
+	"W": "WARN",
+	"E": "ERROR",
+	"F": "FATAL",
+}
+
+// klogDecoder decodes the glog format of Kubernetes components, e.g.
+// I0822 09:00:06.956789    8982 counter.go:202] empty counter list
+type klogDecoder struct {
+	*lineScanner
+	// now is the reference for the year missing from the timestamps.
+	now func() time.Time
+}
+
+func newKlogDecoder(r io.Reader) *klogDecoder {
+	return &klogDecoder{
+		lineScanner: newLineScanner(r),
+		now:         time.Now,
+	}
+}
+
+var klogRE = regexp.MustCompile(`^([IWEF])(\d{4} \d{2}:\d{2}:\d{2}(?:\.\d+)?)\s+(\d+) ([^\s:\]]+:\d+)\] ?(.*)$`)
+
+func (d *klogDecoder) Decode() (map[string]interface{}, error) {
+	for {
+		d.Begin()
+		line, ok := d.Next()
+		if !ok {
+			if err := d.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		m, ok := d.parse(line)
+		if !ok {
+			invalidEntry(line)
+			continue
+		}
+		return m, nil
+	}
+}
+
+func (d *klogDecoder) parse(line string) (map[string]interface{}, bool) {
+	matches := klogRE.FindStringSubmatch(line)
+	if matches == nil {
+		return nil, false
+	}
+	datetime, ok := klogTime(matches[2], d.now())
+	if !ok {
+		return nil, false
+	}
+	return map[string]interface{}{
+		"level":    klogLevels[matches[1]],
+		"datetime": datetime,
+		"thread":   toValue(matches[3]),
+		"position": matches[4],
+		"message":  matches[5],
+	}, true
+}
+
+// klogTime parses a timestamp like 0822 09:00:06.956789 in the year of now,
+// or the year before if it would be in the future, e.g. December logs read
+// in January.
+func klogTime(s string, now time.Time) (time.Time, bool) {
+	t, err := time.ParseInLocation("0102 15:04:05", s, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	t = t.AddDate(now.Year()-t.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t, true
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKlogDecoder(t *testing.T) {
+	input := strings.Join([]string{
+		"I0822 09:00:06.956789    8982 counter.go:202] empty counter list",
+		"E0822 09:00:07.000001 12 server.go:42] \"Failed to serve\" err=\"connection refused\"",
+		"W1231 23:59:59.5 1 main.go:1]",
+		"F0822 09:00:06.956789 x main.go:1] bad thread",
+		"not klog",
+	}, "\n")
+
+	dec := newKlogDecoder(strings.NewReader(input))
+	dec.now = func() time.Time { return time.Date(2025, 1, 2, 0, 0, 0, 0, time.Local) }
+	invalid := invalidLines.Load()
+	got := decodeAll(t, dec)
+	want := []map[string]interface{}{
+		{
+			"level":    "INFO",
+			"datetime": time.Date(2025, 8, 22, 9, 0, 6, 956789000, time.Local).AddDate(-1, 0, 0),
+			"thread":   json.Number("8982"),
+			"position": "counter.go:202",
+			"message":  "empty counter list",
+		},
+		{
+			"level":    "ERROR",
+			"datetime": time.Date(2024, 8, 22, 9, 0, 7, 1000, time.Local),
+			"thread":   json.Number("12"),
+			"position": "server.go:42",
+			"message":  `"Failed to serve" err="connection refused"`,
+		},
+		{
+			"level":    "WARN",
+			"datetime": time.Date(2024, 12, 31, 23, 59, 59, 500000000, time.Local),
+			"thread":   json.Number("1"),
+			"position": "main.go:1",
+			"message":  "",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded\n%v\nwant\n%v", got, want)
+	}
+	if n := invalidLines.Load() - invalid; n != 2 {
+		t.Errorf("%d invalid lines, want 2", n)
+	}
+}
+
+func TestKlogTimeCurrentYear(t *testing.T) {
+	now := time.Date(2024, 8, 22, 12, 0, 0, 0, time.Local)
+	got, ok := klogTime("0822 09:00:06.956789", now)
+	if want := time.Date(2024, 8, 22, 9, 0, 6, 956789000, time.Local); !ok || !got.Equal(want) {
+		t.Errorf("klogTime() = %v, %v, want %v", got, ok, want)
+	}
+}
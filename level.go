@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// levels in ascending order of severity.
+var levels = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR"}
+
+var levelAliases = map[string]string{
+	"WARNING": "WARN",
+	"ERR":     "ERROR",
+	"DPANIC":  "ERROR",
+	"PANIC":   "ERROR",
+	"FATAL":   "ERROR",
+}
+
+// levelRank returns the position of level in levels, or -1 if level is
+// unknown. Matching is case-insensitive.
+func levelRank(level string) int {
+	level = strings.ToUpper(level)
+	if alias, ok := levelAliases[level]; ok {
+		level = alias
+	}
+	for i, l := range levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// minLevel is the rank of the lowest level shown, 0 shows all rows including
+// those without a known level.
+var minLevel = 0
+
+var levelKeys = map[rune]string{
+	'e': "ERROR",
+	'w': "WARN",
+	'i': "INFO",
+	'a': "",
+}
+
+// setLevelFilter handles the level filter hotkeys and reports whether r is one.
+func setLevelFilter(r rune) bool {
+	level, ok := levelKeys[r]
+	if !ok {
+		return false
+	}
+	minLevel = 0
+	if level != "" {
+		minLevel = levelRank(level)
+	}
+	storeFilter()
+	return true
+}
+
+func matchLevel(data RowData) bool {
+	return minLevel == 0 || levelRank(data.GetLevel()) >= minLevel
+}
+
+func levelFilterText() string {
+	if minLevel == 0 {
+		return "level: all"
+	}
+	if minLevel == len(levels)-1 {
+		return "level: " + levels[minLevel]
+	}
+	return "level: " + levels[minLevel] + "+"
+}
+
+// levelColors maps levels to tcell color names, "dim" dims the default color.
+var levelColors = map[string]string{
+	"ERROR": "red",
+	"WARN":  "yellow",
+	"DEBUG": "dim",
+	"TRACE": "dim",
+}
+
+// setLevelColors overrides levelColors by a spec like "ERROR=red,INFO=green".
+func setLevelColors(spec string) error {
+	if spec == "" {
+		return nil
+	}
+	for _, item := range strings.Split(spec, ",") {
+		level, color, ok := strings.Cut(strings.TrimSpace(item), "=")
+		rank := levelRank(level)
+		if !ok || rank < 0 {
+			return fmt.Errorf("invalid level color %q", item)
+		}
+		color = strings.ToLower(color)
+		if _, ok := tcell.ColorNames[color]; !ok && color != "dim" && color != "default" {
+			return fmt.Errorf("unknown color %q", color)
+		}
+		levelColors[levels[rank]] = color
+	}
+	return nil
+}
+
+// levelStyle returns the text color and attributes of rows with level.
+func levelStyle(level string) (tcell.Color, tcell.AttrMask) {
+	color := tview.Styles.PrimaryTextColor
+	if noColor {
+		return color, 0
+	}
+	rank := levelRank(level)
+	if rank < 0 {
+		return color, 0
+	}
+	switch name := levelColors[levels[rank]]; name {
+	case "", "default":
+		return color, 0
+	case "dim":
+		return color, tcell.AttrDim
+	default:
+		return tcell.ColorNames[name], 0
+	}
+}
+
+// levelTagColor returns the color of level for a color tag, or "" if the
+// level isn't colored.
+func levelTagColor(level string) string {
+	switch name := levelColors[level]; name {
+	case "", "default", "dim":
+		return ""
+	default:
+		return name
+	}
+}
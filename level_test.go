@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestMatchLevel(t *testing.T) {
+	defer setLevelFilter('a')
+
+	row := func(level string) RowData {
+		return RowData{data: map[string]interface{}{"level": level}}
+	}
+	tests := []struct {
+		key   rune
+		level string
+		want  bool
+	}{
+		{'a', "DEBUG", true},
+		{'a', "", true},
+		{'i', "DEBUG", false},
+		{'i', "info", true},
+		{'w', "INFO", false},
+		{'w', "warning", true},
+		{'w', "ERROR", true},
+		{'e', "WARN", false},
+		{'e', "fatal", true},
+		{'e', "", false},
+	}
+	for _, tt := range tests {
+		setLevelFilter(tt.key)
+		if got := matchLevel(row(tt.level)); got != tt.want {
+			t.Errorf("filter %q: matchLevel(%q) = %v, want %v", tt.key, tt.level, got, tt.want)
+		}
+	}
+}
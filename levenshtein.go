@@ -43,3 +43,45 @@ func ComputeDistance(a, b []string) int {
 	}
 	return x[len(a)]
 }
+
+// WithinDistance reports whether ComputeDistance(a, b) < limit. It stops as
+// soon as a row of the table has no value below limit, as the distance can
+// only grow from there, so unrelated sequences are rejected after a few
+// tokens.
+func WithinDistance(a, b []string, limit int) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(b)-len(a) >= limit {
+		return false
+	}
+	if equals(a, b) {
+		return true
+	}
+
+	x := make([]int, len(a)+1)
+	for i := 0; i <= len(a); i++ {
+		x[i] = i
+	}
+	for i := 1; i <= len(b); i++ {
+		prev := i
+		lowest := prev
+		var current int
+
+		for j := 1; j <= len(a); j++ {
+			if b[i-1] == a[j-1] {
+				current = x[j-1]
+			} else {
+				current = min(min(x[j-1]+1, prev+1), x[j]+1)
+			}
+			x[j-1] = prev
+			prev = current
+			lowest = min(lowest, current)
+		}
+		x[len(a)] = prev
+		if lowest >= limit {
+			return false
+		}
+	}
+	return x[len(a)] < limit
+}
@@ -31,5 +31,11 @@ func TestComputeDistance(t *testing.T) {
 			t.Errorf("Test[%d]: ComputeDistance(%q,%q) returned %v, want %v",
 				i, d.a, d.b, n, d.want)
 		}
+		a, b := strings.Split(d.a, ""), strings.Split(d.b, "")
+		for limit := 0; limit <= d.want+1; limit++ {
+			if got := WithinDistance(a, b, limit); got != (d.want < limit) {
+				t.Errorf("Test[%d]: WithinDistance(%q,%q,%d) returned %v", i, d.a, d.b, limit, got)
+			}
+		}
 	}
 }
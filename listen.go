@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// listeners accept connections streaming logs into the store, see -listen.
+var listeners []net.Listener
+
+// listen opens a listener for an address like unix:///run/red.sock or
+// tcp://:5140.
+func listen(addr string) (net.Listener, error) {
+	network, address, ok := strings.Cut(addr, "://")
+	if !ok || address == "" {
+		return nil, fmt.Errorf("invalid listen address %q, want unix:///path or tcp://host:port", addr)
+	}
+	switch network {
+	case "unix":
+		if err := removeStaleSocket(address); err != nil {
+			return nil, err
+		}
+		return net.Listen(network, address)
+	case "tcp", "tcp4", "tcp6":
+		return net.Listen(network, address)
+	}
+	return nil, fmt.Errorf("invalid listen address %q, unsupported network %s", addr, network)
+}
+
+// removeStaleSocket removes a socket file left by a process that didn't exit
+// cleanly, unless it still accepts connections.
+func removeStaleSocket(name string) error {
+	fi, err := os.Stat(name)
+	if err != nil || fi.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+	if conn, err := net.Dial("unix", name); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use", name)
+	}
+	return os.Remove(name)
+}
+
+// conns limits the connections served at once over all listeners, nil if
+// unlimited.
+var conns chan struct{}
+
+// serve decodes every connection accepted by l concurrently, until l is
+// closed and the open connections are done. Connections beyond -max-conns are
+// closed right away.
+func serve(l net.Listener) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		if conns != nil {
+			select {
+			case conns <- struct{}{}:
+			default:
+				log.Printf("warn: rejected connection from %s, %d connections open", conn.RemoteAddr(), cap(conns))
+				conn.Close()
+				continue
+			}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveConn(l, conn)
+		}()
+	}
+}
+
+// serveConn decodes a connection, a decoding error or a read timeout only
+// drops the connection.
+func serveConn(l net.Listener, conn net.Conn) {
+	defer func() {
+		// Free the slot first, a client seeing the close may reconnect.
+		if conns != nil {
+			<-conns
+		}
+		conn.Close()
+	}()
+	var r io.Reader = conn
+	if readTimeout > 0 {
+		r = timeoutReader{conn, readTimeout}
+	}
+	if err := consume(labelDecoder(newDecoder(r), connLabel(conn))); err != nil {
+		log.Printf("warn: dropped connection from %s to %s: %v", conn.RemoteAddr(), l.Addr(), err)
+	}
+}
+
+// timeoutReader fails a read that waits for data longer than timeout.
+type timeoutReader struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (r timeoutReader) Read(p []byte) (int, error) {
+	if err := r.conn.SetReadDeadline(time.Now().Add(r.timeout)); err != nil {
+		return 0, err
+	}
+	return r.conn.Read(p)
+}
+
+// closeListeners stops accepting connections, unix sockets are removed.
+func closeListeners() {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListenUnix(t *testing.T) {
+	format = "json"
+	keys = []string{"message"}
+	store = NewStore(time.Second, defaultTrendBuckets, 3, keys, nil)
+	defer func() { format, keys, store = autoFormat, nil, nil }()
+
+	name := filepath.Join(t.TempDir(), "red.sock")
+	l, err := listen("unix://" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() { serve(l); close(done) }()
+
+	for _, input := range []string{
+		"{\"message\": \"a\"}\n{\"message\": \"a\"}\n",
+		"{\"message\": \"disk full\"}\n{\"mess",
+	} {
+		conn, err := net.Dial("unix", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write([]byte(input))
+		conn.Close()
+	}
+
+	waitTotal(t, 3)
+
+	// a live socket isn't replaced
+	if _, err := listen("unix://" + name); err == nil {
+		t.Error("listen() replaced a socket in use")
+	}
+	l.Close()
+	<-done
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("socket file left after close: %v", err)
+	}
+}
+
+func TestListenTCP(t *testing.T) {
+	format = "logfmt"
+	keys = []string{"msg"}
+	store = NewStore(time.Second, defaultTrendBuckets, 3, keys, nil)
+	conns = make(chan struct{}, 1)
+	defer func() {
+		format, keys, store, conns, readTimeout = autoFormat, nil, nil, nil, 10*time.Minute
+	}()
+	readTimeout = 500 * time.Millisecond
+
+	l, err := listen("tcp://127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() { serve(l); close(done) }()
+	defer func() { l.Close(); <-done }()
+
+	first, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	first.Write([]byte("level=info msg=started\n"))
+	waitTotal(t, 1)
+
+	// the only slot is taken, a second connection is closed
+	second, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := second.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("read from rejected connection returned %v, want EOF", err)
+	}
+	second.Close()
+
+	// the idle first connection times out and frees the slot
+	first.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := first.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("read from idle connection returned %v, want EOF", err)
+	}
+	third, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	third.Write([]byte("level=info msg=started\n"))
+	third.Close()
+	waitTotal(t, 2)
+}
+
+// waitTotal waits for the store to count n events.
+func waitTotal(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		store.RLock()
+		total := store.Total()
+		store.RUnlock()
+		if total == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("store has %d events, want %d", total, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestListenInvalid(t *testing.T) {
+	for _, addr := range []string{"", "/tmp/red.sock", "unix://", "udp://:5140"} {
+		if _, err := listen(addr); err == nil {
+			t.Errorf("listen(%q) accepted an invalid address", addr)
+		}
+	}
+}
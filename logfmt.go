@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// logfmtDecoder decodes logfmt lines, e.g.
+// ts=2024-08-22T09:00:06Z level=error msg="empty counter" traceID=16029
+type logfmtDecoder struct {
+	*lineScanner
+}
+
+func newLogfmtDecoder(r io.Reader) *logfmtDecoder {
+	return &logfmtDecoder{
+		lineScanner: newLineScanner(r),
+	}
+}
+
+func (d *logfmtDecoder) Decode() (map[string]interface{}, error) {
+	for {
+		d.Begin()
+		line, ok := d.Next()
+		if !ok {
+			if err := d.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		m := parseLogfmt(line)
+		if len(m) == 0 {
+			invalidEntry(line)
+			continue
+		}
+		return m, nil
+	}
+}
+
+// parseLogfmt splits a line into key=value pairs. Values may be double quoted
+// with backslash escapes, a key without value is treated as boolean true.
+func parseLogfmt(line string) map[string]interface{} {
+	m := map[string]interface{}{}
+	i := 0
+	for i < len(line) {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' {
+			i++
+		}
+		key := line[start:i]
+		if i >= len(line) || line[i] == ' ' {
+			if key != "" {
+				m[key] = true
+			}
+			continue
+		}
+		i++ // skip '='
+
+		if i < len(line) && line[i] == '"' {
+			start = i
+			i++
+			for i < len(line) && line[i] != '"' {
+				if line[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i < len(line) {
+				i++ // closing quote
+			}
+			raw := line[start:min(i, len(line))]
+			value, err := strconv.Unquote(raw)
+			if err != nil {
+				value = strings.Trim(raw, `"`)
+			}
+			if key != "" {
+				m[key] = value
+			}
+			continue
+		}
+
+		start = i
+		for i < len(line) && line[i] != ' ' {
+			i++
+		}
+		if key != "" {
+			m[key] = toValue(line[start:i])
+		}
+	}
+	return m
+}
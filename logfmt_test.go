@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]interface{}
+	}{
+		{
+			`ts=2024-08-22T09:00:06Z level=error msg="empty counter" traceID=16029`,
+			map[string]interface{}{
+				"ts":      "2024-08-22T09:00:06Z",
+				"level":   "error",
+				"msg":     "empty counter",
+				"traceID": json.Number("16029"),
+			},
+		},
+		{
+			`msg="say \"hi\" now" ratio=0.5 debug`,
+			map[string]interface{}{
+				"msg":   `say "hi" now`,
+				"ratio": json.Number("0.5"),
+				"debug": true,
+			},
+		},
+		{
+			`empty= version=v1.2`,
+			map[string]interface{}{
+				"empty":   "",
+				"version": "v1.2",
+			},
+		},
+	}
+	for i, tt := range tests {
+		got := parseLogfmt(tt.line)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Test[%d]: parseLogfmt(%q) = %v, want %v", i, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestLogfmtDecoder(t *testing.T) {
+	input := "level=info msg=a\n\nlevel=warn msg=b\n"
+	got := decodeAll(t, newLogfmtDecoder(strings.NewReader(input)))
+	if len(got) != 2 || got[0]["msg"] != "a" || got[1]["msg"] != "b" {
+		t.Errorf("decoded %v, want messages a and b", got)
+	}
+}
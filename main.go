@@ -1,77 +1,311 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/signal"
-	"syscall"
+	"regexp"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gdamore/tcell"
 	"github.com/rivo/tview"
-	"github.com/satyrius/gonx"
 
 	"github.com/antonmedv/red/internal/prettyjson"
 )
 
 var (
 	// options
-	duration    time.Duration
-	distance    int
-	format      string
-	nginxConfig string
-	nginxFormat string
-	showHelp    bool
+	duration       time.Duration
+	window         time.Duration
+	trendBuckets   int
+	distance       float64
+	similarityName string
+	groupBy        string
+	masks          stringsFlag
+	format         string
+	timeLayout     string
+	nginxConfig    string
+	nginxFormat    string
+	showHelp       bool
+	noColor        bool
+	levelColorSpec string
+	files          stringsFlag
+	sourceSpecs    stringsFlag
+	mergeStdin     bool
+	follow         bool
+	gzipped        bool
+	strict         bool
+	flatten        bool
+	rejectsFile    string
+	saveFile       string
+	loadFile       string
+	since          string
+	tzName         string
+	until          string
+	requireTime    bool
+	every          int
+	maxGroups      int
+	noCombine      bool
+	dumpFile       string
+	mouse          bool
+	splitTrend     bool
+	sparkStyle     string
+	statSpecs      stringsFlag
+	listenAddrs    stringsFlag
+	criFormat      string
+	maxConns       int
+	readTimeout    time.Duration
+	batchInterval  time.Duration
+	extractSpecs   stringsFlag
+	quiet          bool
+	logFile        string
+	maxLines       int64
+	headless       bool
+	output         string
+	topN           int
+	zapSep         string
+	nestFields     bool
+	fieldsSpec     string
+	showSeen       bool
+	keepSamples    int
+	theme          string
+	keyOrder       string
+	configFile     string
+	highlightSpecs stringsFlag
+	alertSpecs     stringsFlag
+	alertCmd       string
+	outMatchesFile string
+	dismissSticky  bool
+	ignoreSpecs    stringsFlag
+	ignoreKeySpecs stringsFlag
+	metricsAddr    string
+	replayMode     bool
+	replaySpeed    string
+	refresh        time.Duration
+	filterSpecs    stringsFlag
+	csvDelimSpec   string
+	csvDelim       rune
 
 	// args
 	keys []string
 
+	// rejects receives the lines rejected in -strict mode.
+	rejects io.Writer
+
 	app   *tview.Application
+	root  *tview.Flex
 	table *tview.Table
 	store *Store
+
+	// extracts add the fields captured from messages by -extract.
+	extracts []Extract
+
+	// highlights color parts of messages, see -highlight.
+	highlights []Highlight
+
+	// consumed counts the decoded records, shown in the footer and limited
+	// by -max-lines, and limited is set once a record past the limit was
+	// dropped.
+	consumed atomic.Int64
+	limited  atomic.Bool
+
+	// sampled counts the entries offered to the store with -every.
+	sampled atomic.Int64
+
+	// rows maps table rows to store indices, table row i+1 renders
+	// store.Get(rows[i]).
+	rows []int
+
+	// rendered holds the version of the store row each table row i+1 was
+	// rendered from, and renderedSearch the search it was highlighted with.
+	rendered       []uint64
+	renderedSearch *regexp.Regexp
+
+	// paused freezes the table and the trend, the store still ingests.
+	paused atomic.Bool
+
+	sortMode SortMode
+
+	// stats are the -stat columns rendered after the keys.
+	stats []Stat
+
+	// sparkRamp is the -spark-style the trends are drawn with.
+	sparkRamp = SparkBlock
 )
 
 const (
 	trendColumn int = iota
 	countColumn
+	rateColumn
 	firstDataColumn
 )
 
+// stopTimeout is how long a signal waits for the app to stop before red
+// exits without restoring the terminal.
+const stopTimeout = 3 * time.Second
+
 const (
 	helpMsg = `
 "red" is an utility to visualize log events from reading or tailing log files,
 this repo is forked from https://github.com/hokaccha/red, which inspires me
 to improve "red" to support more formats, including zaplog.
 
-red support 2 formats:
-- json, 
+red support 11 formats:
+- json, objects may span several lines or be elements of a top-level array
   {"datetime": "2024-08-22 09:00:06.956", "level": "ERROR", "pos": "dbsvr/counter.go:202" "func": "[GetCounterBatch]", "msg": "empty counter list", "process": 8982, "traceID": 16029078675928157035, "meta.PlayerID": 0}
 - zaplog,
-  2024-08-22 09:00:06.956 ERROR dbsvr/counter.go:202 [GetCounterBatch] empty counter list {"process": 8982, "traceID": 16029078675928157035, "meta.PlayerID": 0}`
+  2024-08-22 09:00:06.956 ERROR dbsvr/counter.go:202 [GetCounterBatch] empty counter list {"process": 8982, "traceID": 16029078675928157035, "meta.PlayerID": 0}
+- logfmt,
+  ts=2024-08-22T09:00:06Z level=error msg="empty counter list" process=8982 traceID=16029078675928157035
+- syslog, RFC5424 with a loose RFC3164 fallback
+  <165>1 2024-08-22T09:00:06.956Z host dbsvr 8982 - [meta PlayerID="0"] empty counter list
+- nginx, access log lines of the -nginx-format log_format found in -nginx-config
+  127.0.0.1 - - [22/Aug/2024:09:00:06 +0000] "GET /api/counter HTTP/1.1" 502 157 "-" "curl/8.0"
+- combined, the Apache/nginx combined access log format, no config needed;
+  fields are remote_addr, remote_user, datetime, request, method, path,
+  protocol, status, bytes, referer and user_agent
+- gelf, Graylog messages delimited by newlines or null bytes
+  {"version": "1.1", "host": "dbsvr", "short_message": "empty counter list", "timestamp": 1724317206.956, "level": 3, "_traceID": "16029078675928157035"}
+- klog, the glog format of Kubernetes components, timestamps are in the current year
+  E0822 09:00:06.956789    8982 counter.go:202] empty counter list
+- cri, container runtime logs, partial lines are joined and the output is
+  decoded with -cri-format, adding stream and the runtime's datetime
+  2024-08-22T09:00:06.956Z stdout F {"level": "ERROR", "msg": "empty counter list"}
+- journald, the export format of journalctl -o export, MESSAGE becomes message,
+  PRIORITY level and __REALTIME_TIMESTAMP datetime
+  __REALTIME_TIMESTAMP=1724317206956789
+  PRIORITY=3
+  MESSAGE=empty counter list
+- csv, rows keyed by the columns of the header row, -csv-delim for TSV or ;
+  datetime,level,msg
+  2024-08-22 09:00:06.956,ERROR,"empty counter list, retrying"
+
+By default the format is detected from the first non-empty line, falling back
+to zaplog if it is ambiguous; nginx and csv need an explicit -format.
+
+nginx and combined entries get a derived status_class field, 2xx to 5xx,
+so -group-by status_class trends the rate of each class, e.g. 5xx errors.`
 )
 
 func init() {
 	flag.DurationVar(&duration, "trend", 10*time.Second, "duration of trend")
-	flag.IntVar(&distance, "distance", 3, "levenshtein distance for combining similar log entities")
+	flag.DurationVar(&window, "window", 0, "span the rate column, footer rate and -alert counts cover, independent of the sparkline's -trend (default -trend)")
+	flag.IntVar(&trendBuckets, "trend-buckets", defaultTrendBuckets, "number of trend buckets, at least 2")
+	flag.StringVar(&sparkStyle, "spark-style", "block", "trend sparkline glyphs, block, braille, ascii or dots for fonts with poor unicode coverage")
+	flag.BoolVar(&splitTrend, "split-trend", false, "add a sparkline of the WARN and ERROR entries of each row next to the trend, ERROR only with -no-color")
+	flag.Float64Var(&distance, "distance", 3, "distance below which similar log entities combine: a number of edits for levenshtein, a fraction of tokens between 0 and 1 for jaccard (default 0.5); -1 combines none, see -no-combine")
+	flag.StringVar(&similarityName, "similarity", "levenshtein", "metric for combining: levenshtein over the tokens in order or jaccard over the sets of tokens")
+	flag.IntVar(&maxGroups, "max-groups", 0, "maximum number of groups, the least recently updated group is evicted beyond it (default unbounded)")
+	flag.BoolVar(&noCombine, "no-combine", false, "don't combine entries, every record is a row of its own and the table a scrollback of the latest -max-groups records (default 1000), like -distance -1")
+	flag.Var(&masks, "mask", "regex=>replacement applied to the message before grouping, e.g. '\\d+=>N', can be repeated; masked messages combine by exact match")
+	flag.Var(&statSpecs, "stat", "field:aggregate column of a numeric field per group, aggregates are count, sum, avg, min, max and percentiles like p95, can be repeated")
+	flag.Var(&extractSpecs, "extract", "name=regex adding the value captured from the message as field name, usable as a column or in -group-by, e.g. 'userid=user (\\d+)', can be repeated")
+	flag.StringVar(&groupBy, "group-by", "", "comma separated fields compared for combining, e.g. message or position,level; entries only combine if every field is within -distance (default all displayed keys together); template combines by the template learned from the message")
 
-	// red support 2 formats:
+	// red support 11 formats:
 	// - json: {"datetime": "2024-08-22 09:00:06.956", "level": "ERROR", "pos": "dbsvr/counter.go:202" "func": "[GetCounterBatch]", "msg": "empty counter list", "process": 8982, "traceID": 16029078675928157035, "meta.PlayerID": 0}
 	// - zaplog: 2024-08-22 09:00:06.956 ERROR dbsvr/counter.go:202 [GetCounterBatch] empty counter list {"process": 8982, "traceID": 16029078675928157035, "meta.PlayerID": 0}
-	flag.StringVar(&format, "format", "zaplog", "stdin format, json or zaplog")
+	// - logfmt: ts=2024-08-22T09:00:06Z level=error msg="empty counter list" process=8982 traceID=16029078675928157035
+	// - syslog: <165>1 2024-08-22T09:00:06.956Z host dbsvr 8982 - [meta PlayerID="0"] empty counter list
+	// - nginx: 127.0.0.1 - - [22/Aug/2024:09:00:06 +0000] "GET /api/counter HTTP/1.1" 502 157 "-" "curl/8.0"
+	// - combined: same as nginx with the default combined log_format, without reading a config
+	// - gelf: {"version": "1.1", "host": "dbsvr", "short_message": "empty counter list", "timestamp": 1724317206.956, "level": 3}
+	// - klog: E0822 09:00:06.956789    8982 counter.go:202] empty counter list
+	// - cri: 2024-08-22T09:00:06.956Z stdout F {"level": "ERROR", "msg": "empty counter list"}
+	// - journald: __REALTIME_TIMESTAMP=1724317206956789\nPRIORITY=3\nMESSAGE=empty counter list\n\n
+	// - csv: datetime,level,msg\n2024-08-22 09:00:06.956,ERROR,"empty counter list, retrying"
+	// - auto: one of the above except nginx and csv, detected from the first line
+	flag.StringVar(&format, "format", autoFormat, "stdin format, json, zaplog, logfmt, syslog, nginx, combined, gelf, klog, cri, journald, csv or auto to detect it from the first line")
+	flag.IntVar(&tailLines, "tail", -1, "only read the last N lines of every -file before following it, like tail -n N -f (default the whole file)")
+	flag.IntVar(&maxLineSize, "max-line-size", defaultMaxLineSize, "longest line in bytes read by the line based formats, longer lines are dropped and counted in the footer")
+	flag.StringVar(&csvDelimSpec, "csv-delim", ",", "csv: cell delimiter, a single character like ; or tab")
+	flag.StringVar(&criFormat, "cri-format", autoFormat, "cri: format of the container output, any -format but journald, text to keep lines as message, or auto")
+	flag.BoolVar(&nestFields, "nest-fields", false, "keep the zaplog {...} fields under a fields. prefix, like fields.process, instead of merging them into the record, where fields named like datetime, level, position, func, message or stacktrace are kept as <key>_orig")
+	flag.StringVar(&zapSep, "zap-sep", "auto", "zaplog separator between datetime, level, caller and message: auto to detect tab, pipe or space per line, space, tab, pipe or any string")
+	flag.StringVar(&timeLayout, "time-layout", zaplogTimeLayout, "zaplog timestamp layout, \"epoch\" for unix time, empty to try common layouts")
 
-	// don't need this
 	flag.StringVar(&nginxConfig, "nginx-config", "/etc/nginx/nginx.conf", "nginx config file")
 	flag.StringVar(&nginxFormat, "nginx-format", "main", "nginx log_format name")
 
+	flag.Var(&files, "file", "log file or glob pattern to read instead of stdin, can be repeated")
+	flag.Var(&sourceSpecs, "source-label", "rule like logs/api.log=api naming the source field of the records of a file, by path or base name, or of stdin; with several inputs or -listen records get a source field, the file's base name, stdin or the remote address by default; can be repeated")
+	flag.BoolVar(&mergeStdin, "merge-stdin-and-files", false, "read piped stdin as well as the -file inputs, every input concurrently, e.g. a live stream next to yesterday's log")
+	flag.BoolVar(&follow, "follow", false, "keep reading files as they grow, like tail -f; stdin is always read until closed")
+	flag.BoolVar(&follow, "f", false, "shorthand for -follow")
+	flag.BoolVar(&replayMode, "replay", false, "ingest the records of finite inputs with the gaps between their datetimes, so the trend animates as it did live")
+	flag.StringVar(&replaySpeed, "replay-speed", "1x", "with -replay, how many times faster than logged the records are ingested, e.g. 10x")
+	flag.StringVar(&metricsAddr, "metrics", "", "serve Prometheus metrics of the ingested lines and groups at http://addr/metrics, e.g. :9090")
+	flag.Var(&listenAddrs, "listen", "accept newline delimited logs streamed to unix:///path/to.sock or tcp://host:port instead of stdin, can be repeated")
+	flag.IntVar(&maxConns, "max-conns", 64, "with -listen, maximum number of connections served at once, 0 for unlimited")
+	flag.DurationVar(&readTimeout, "read-timeout", 10*time.Minute, "with -listen, drop connections idle for longer, 0 to wait forever")
+	flag.DurationVar(&refresh, "refresh", 100*time.Millisecond, "interval between redraws of the table, higher for slow terminals or SSH, at least 10ms")
+	flag.DurationVar(&batchInterval, "batch", 20*time.Millisecond, "apply entries to the table in batches collected for this long, cheaper at high rates; 0 to apply every entry at once")
+	flag.IntVar(&keepSamples, "keep-samples", 1, "number of latest records kept per group, v cycles through them and the first record in the viewer")
+	flag.Var(&highlightSpecs, "highlight", "regex=color coloring the matches in messages and the viewer, e.g. 'E\\d+=red', can be repeated, the first rule wins where matches overlap")
+	flag.Var(&alertSpecs, "alert", "rule like 'level=ERROR count>100' ringing the bell and highlighting a matching group whose count within -window exceeds the threshold, can be repeated")
+	flag.StringVar(&alertCmd, "alert-cmd", "", "shell command run when an alert fires, with the alert and the group as JSON on stdin")
+	flag.StringVar(&outMatchesFile, "out-matches", "", "file the original lines of the records passing the time window, level filter and search are appended to")
+	flag.Var(&filterSpecs, "filter", "only ingest records whose field compares to a value with > < >= <= == or !=, e.g. latency>500, numerically if both are numbers; can be repeated, all must hold")
+	flag.Var(&ignoreSpecs, "ignore", "drop input lines matching regex before decoding, can be repeated")
+	flag.Var(&ignoreKeySpecs, "ignore-field", "drop records whose field matches, as key=regex, can be repeated")
+	flag.BoolVar(&dismissSticky, "dismiss-sticky", false, "drop the entries of groups dismissed with d instead of starting them again")
+	flag.StringVar(&fieldsSpec, "fields", "", "comma separated fields kept of every record after filtering, the others are dropped to save memory on wide records; datetime, level, position, func, message and stacktrace are always kept")
+	flag.StringVar(&keyOrder, "key-order", "datetime,level,message", "comma separated fields shown first in the viewer, the others follow sorted")
+	flag.StringVar(&theme, "theme", "default", "colors of the viewer: "+strings.Join(prettyjson.ThemeNames(), ", "))
+	flag.BoolVar(&showSeen, "seen", false, "add first seen and last seen columns, the earliest and latest datetime of each group")
+	flag.IntVar(&maxColWidth, "max-col-width", 0, "truncate cells to N characters with an ellipsis, the viewer shows them in full; t toggles truncation (default 0 shows cells in full, t truncates to 60)")
+	flag.StringVar(&truncateMode, "truncate", "right", "where -max-col-width cuts cells: right keeps the start, middle the start and the end")
+	flag.IntVar(&topN, "top", 0, "only show the N groups with the highest counts, the others are summed up in one row; 0 shows all")
+	flag.BoolVar(&headless, "headless", false, "read the whole input and print the groups by count to stdout instead of showing the table")
+	flag.StringVar(&output, "output", "text", "-headless output, text for an aligned table or json")
+	flag.Int64Var(&maxLines, "max-lines", 0, "stop reading after this many records, the table stays interactive; 0 reads everything")
+	flag.BoolVar(&quiet, "quiet", false, "don't log a warning per invalid line, they are still counted in the footer")
+	flag.StringVar(&logFile, "log-file", "red.log", "file warnings and errors are appended to, - or empty to discard them")
+	flag.BoolVar(&gzipped, "gzip", false, "stdin is gzip compressed, files ending in .gz are always decompressed")
+
+	flag.BoolVar(&flatten, "flatten", true, "flatten nested objects and arrays into dotted keys like meta.PlayerID and tags.0")
+	flag.BoolVar(&strict, "strict", false, "json: decode one object per line, counting malformed lines instead of stopping")
+	flag.StringVar(&rejectsFile, "rejects", "", "json: with -strict, append rejected lines to file")
+
+	flag.StringVar(&since, "since", "", "drop entries before a timestamp or a duration ago, e.g. 2024-08-22 09:00:00 or 10m")
+	flag.StringVar(&until, "until", "", "drop entries after a timestamp or a duration ago")
+	flag.StringVar(&tzName, "tz", "", "normalize datetimes to local, utc or an IANA zone like Europe/Berlin, also the zone of -since and -until timestamps without one (default as logged)")
+	flag.BoolVar(&requireTime, "require-time", false, "with -since or -until, also drop entries without a datetime")
+
+	flag.IntVar(&every, "every", 1, "only group every Nth entry, counting it N times; counts and trends are approximate and rare entries may be missed")
+
+	flag.StringVar(&saveFile, "save", "", "save the session to file on exit")
+	flag.StringVar(&loadFile, "load", "", "restore a session saved with -save on startup")
+	flag.StringVar(&dumpFile, "dump-on-exit", "", "write every group with its count, trend and latest record to file as a JSON array on exit")
+
+	flag.BoolVar(&mouse, "mouse", true, "click rows to open them and scroll with the wheel; hold Shift to select text in most terminals, or disable with -mouse=false")
+	flag.BoolVar(&noColor, "no-color", false, "disable colors")
+	flag.StringVar(&levelColorSpec, "level-colors", "", "override row colors by level, e.g. ERROR=red,WARN=orange,INFO=green; \"dim\" dims the row")
+
 	flag.BoolVar(&showHelp, "help", false, "show help")
+	flag.StringVar(&configFile, "config", "", "YAML file with flags by name, columns and keybindings, flags on the command line override it")
 }
 
 func main() {
 	flag.Parse()
 	keys = flag.Args()
+	if configFile != "" {
+		conf, err := readConfig(configFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if len(keys) == 0 {
+			keys = conf.columns
+		}
+		keyAliases = conf.keyBindings
+	}
 
 	if showHelp {
 		fmt.Println(helpMsg)
@@ -80,22 +314,287 @@ func main() {
 		os.Exit(2)
 	}
 
-	fout, err := os.OpenFile("red.log", os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
+	if format == "nginx" || format == "cri" && criFormat == "nginx" {
+		f, err := loadNginxConfig(nginxConfig, nginxFormat)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		nginxLog = f
+	}
+	if format != autoFormat && newFormatDecoder(format, strings.NewReader("")) == nil {
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", format)
+		os.Exit(2)
+	}
+	if criFormat == "cri" || criFormat != autoFormat && newOutputParser(criFormat) == nil {
+		fmt.Fprintf(os.Stderr, "unknown -cri-format %q\n", criFormat)
+		os.Exit(2)
+	}
+	if window < 0 {
+		fmt.Fprintln(os.Stderr, "-window must not be negative")
+		os.Exit(2)
+	}
+	if trendBuckets < 2 {
+		fmt.Fprintln(os.Stderr, "-trend-buckets must be at least 2")
+		os.Exit(2)
+	}
+	if style, ok := sparkStyles[sparkStyle]; ok {
+		sparkRamp = style
+	} else {
+		fmt.Fprintf(os.Stderr, "unknown -spark-style %q\n", sparkStyle)
+		os.Exit(2)
+	}
+	delim, err := parseDelim(csvDelimSpec)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	csvDelim = delim
+	if maxLineSize < 1 {
+		fmt.Fprintln(os.Stderr, "-max-line-size must be positive")
+		os.Exit(2)
+	}
+	if refresh < minRefresh {
+		fmt.Fprintf(os.Stderr, "-refresh must be at least %v\n", minRefresh)
+		os.Exit(2)
+	}
+	if every < 1 {
+		fmt.Fprintln(os.Stderr, "-every must be at least 1")
+		os.Exit(2)
+	}
+	if headless && (follow || len(listenAddrs) > 0) {
+		fmt.Fprintln(os.Stderr, "-headless reads until the input ends, it can't -follow or -listen")
+		os.Exit(2)
+	}
+	if sourceLabels, err = parseSourceLabels(sourceSpecs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if mergeStdin {
+		if len(files) == 0 {
+			fmt.Fprintln(os.Stderr, "-merge-stdin-and-files needs -file")
+			os.Exit(2)
+		}
+		if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice != 0 {
+			fmt.Fprintln(os.Stderr, "-merge-stdin-and-files needs piped stdin, not a terminal")
+			os.Exit(2)
+		}
+	}
+	if replayMode {
+		if follow || mergeStdin || len(listenAddrs) > 0 {
+			fmt.Fprintln(os.Stderr, "-replay paces finite inputs in order, it can't -follow, -listen or -merge-stdin-and-files")
+			os.Exit(2)
+		}
+		speed, err := parseSpeed(replaySpeed)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		replay = newReplayer(speed)
+	}
+	if output != "text" && output != "json" {
+		fmt.Fprintf(os.Stderr, "unknown -output %q, want text or json\n", output)
+		os.Exit(2)
+	}
+	if sep, ok := zapSeparators[zapSep]; ok {
+		zapSep = sep
+	} else if zapSep == "" {
+		fmt.Fprintln(os.Stderr, "-zap-sep must not be empty, use auto to detect it")
+		os.Exit(2)
+	}
+	if _, ok := prettyjson.Themes[theme]; !ok {
+		fmt.Fprintf(os.Stderr, "unknown -theme %q, want one of %s\n", theme, strings.Join(prettyjson.ThemeNames(), ", "))
+		os.Exit(2)
+	}
+	if noColor {
+		theme = "none"
+	}
+	if topN < 0 {
+		fmt.Fprintln(os.Stderr, "-top must not be negative")
+		os.Exit(2)
+	}
+	if maxLines < 0 {
+		fmt.Fprintln(os.Stderr, "-max-lines must not be negative")
+		os.Exit(2)
+	}
+	if err := setLevelColors(levelColorSpec); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	similarity, err := parseSimilarity(similarityName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if distance == -1 {
+		// Any valid distance, it is never compared.
+		noCombine, distance = true, 0
+	}
+	if similarity == Jaccard && !isFlagSet("distance") && !noCombine {
+		distance = defaultJaccardDistance
+	}
+	if noCombine && maxGroups == 0 {
+		maxGroups = defaultScrollback
+	}
+	if err := similarity.checkDistance(distance); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	storeMasks, err := parseMasks(masks)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	keptFields = parseKeptFields(fieldsSpec)
+	if extracts, err = parseExtracts(extractSpecs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if predicates, err = parsePredicates(filterSpecs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if ignores, err = parseIgnores(ignoreSpecs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if ignoreFields, err = parseIgnoreFields(ignoreKeySpecs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := checkTruncateMode(truncateMode); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if maxColWidth < 0 {
+		fmt.Fprintln(os.Stderr, "-max-col-width must not be negative")
+		os.Exit(2)
+	}
+	if highlights, err = parseHighlights(highlightSpecs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	alerts, err := parseAlerts(alertSpecs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if stats, err = parseStats(statSpecs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if timeZone, err = parseTimeZone(tzName); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	now := time.Now()
+	if sinceTime, err = parseTimeBound(since, now); err != nil {
+		fmt.Fprintln(os.Stderr, "-since:", err)
+		os.Exit(2)
+	}
+	if untilTime, err = parseTimeBound(until, now); err != nil {
+		fmt.Fprintln(os.Stderr, "-until:", err)
+		os.Exit(2)
+	}
+
+	fout, err := openLogFile(logFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 	defer fout.Close()
-	log.SetOutput(fout)
 
-	ch := make(chan os.Signal, 1)
-	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
-	go func() {
-		<-ch
-		fout.Close()
-		os.Exit(0)
-	}()
+	if rejectsFile != "" {
+		f, err := os.OpenFile(rejectsFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		rejects = f
+	}
+	if outMatchesFile != "" {
+		f, err := os.OpenFile(outMatchesFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		outMatches = f
+	}
+	storeFilter()
+
+	if maxConns > 0 {
+		conns = make(chan struct{}, maxConns)
+	}
+	for _, addr := range listenAddrs {
+		l, err := listen(addr)
+		if err != nil {
+			closeListeners()
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		listeners = append(listeners, l)
+	}
+	defer closeListeners()
+
+	// With -listen only -file inputs are read besides the connections.
+	var inputs []io.ReadCloser
+	if len(files) > 0 || len(listeners) == 0 {
+		inputs, err = openInputs(files, follow, gzipped)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer closeInputs(inputs)
+	}
+	labelSources = len(inputs) > 1 || len(listeners) > 0 || len(sourceSpecs) > 0
+
+	store = NewStore(duration, trendBuckets, distance, keys, splitFields(groupBy))
+	store.SetSimilarity(similarity)
+	store.SetWindow(window)
+	store.SetMasks(storeMasks)
+	store.SetWeight(every)
+	store.SetMaxGroups(maxGroups)
+	store.SetCombine(!noCombine)
+	store.SetSplitTrend(splitTrend)
+	store.SetStats(stats)
+	store.SetKeepSamples(keepSamples)
+	store.SetAlerts(alerts)
+	store.SetDismissSticky(dismissSticky)
+	if loadFile != "" {
+		if err := loadStore(loadFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if metricsAddr != "" {
+		l, err := startMetrics(metricsAddr, store)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer l.Close()
+	}
+
+	if headless {
+		failed := func(err error) {
+			log.Println(err)
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if mergeStdin {
+			consumeAll(inputs, failed)
+		} else if err := consume(inputDecoder(inputs)); err != nil {
+			failed(err)
+		}
+		if err := printSummary(os.Stdout, output == "json"); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		saveOnExit()
+		return
+	}
 
-	store = NewStore(duration, distance, keys)
 	app = tview.NewApplication()
 
 	viewerOpen := false
@@ -105,7 +604,7 @@ func main() {
 	viewer.SetBorder(true)
 
 	table = tview.NewTable().
-		SetFixed(1, 2).
+		SetFixed(1, 3).
 		SetDoneFunc(func(key tcell.Key) {
 			if key == tcell.KeyEscape {
 				table.SetSelectable(false, false)
@@ -118,61 +617,560 @@ func main() {
 
 	flex := tview.NewFlex()
 	flex.AddItem(table, 0, 1, true)
-	app.SetRoot(flex, true)
+	searchInput = newSearchInput()
+	status = newStatus()
+	table.SetSelectionChangedFunc(func(row, column int) {
+		renderStatus()
+	})
+	footer = newStatus()
+	root = tview.NewFlex().SetDirection(tview.FlexRow)
+	root.AddItem(flex, 0, 1, true)
+	root.AddItem(footer, 1, 0, false)
+	root.AddItem(status, 1, 0, false)
+	helpView = newHelpView()
+	pages = tview.NewPages().AddPage("main", root, true, true)
+	app.SetRoot(pages, true)
 
-	showRowData := func() {
-		store.RLock()
-		row, _ := table.GetSelection()
-		if row == 0 {
-			row = 1
+	// viewerSample is the record of the viewed group shown, see viewerRecord.
+	// It starts at the latest whenever another group is viewed.
+	viewerIndex, viewerSample := -1, 0
+	// marked is the record of the group marked with m, the viewer shows how
+	// the record of any other group differs from it.
+	var marked map[string]interface{}
+	markedIndex := -1
+	// pinned are the viewers of the groups pinned with p, left to right.
+	var pinned []*pinnedViewer
+	// showRecord shows the sample-th record of row, the index-th group, in
+	// target. Row must be a copy from copyRow, it is read
+	// without the lock.
+	showRecord := func(target *tview.TextView, row RowData, index, sample int) {
+		data, label := viewerRecord(row, sample)
+		buckets := row.GetTrendBuckets()
+		firstSeen, lastSeen := formatSeen(row.GetFirstSeen()), formatSeen(row.GetLastSeen())
+
+		raw, _ := data[rawKey].(string)
+		data = withoutRaw(data)
+		if marked != nil && index != markedIndex {
+			text, differ := diffRecords(withoutRaw(marked), data)
+			header := fmt.Sprintf("diff: %d fields differ, - marked group, + this group's %s record, m clears the mark\n\n", differ, label)
+			target.SetText(header + text)
+			target.ScrollToBeginning()
+			return
 		}
-		data := store.Get(row - 1).GetData()
-		store.RUnlock()
 
-		text, err := prettyjson.Marshal(data)
+		var text []byte
+		var err error
+		if !recovered("viewer", func() {
+			formatter := prettyjson.NewFormatter()
+			formatter.SetTheme(prettyjson.Themes[theme])
+			formatter.KeyOrder = splitFields(keyOrder)
+			text, err = formatter.Marshal(data)
+		}) {
+			err = fmt.Errorf("formatting failed")
+		}
 		if err != nil {
-			panic(err)
+			log.Println(err)
+			showMessage("can't format the entry: %v", err)
+			text = []byte(escape(fmt.Sprintf("%v", data)))
 		}
 		log.Println("data after jsonmarshal", string(text))
 
-		viewer.SetText(tview.TranslateANSI(string(text)))
-		viewer.ScrollToBeginning()
+		header := fmt.Sprintf("trend: %v\nfirst seen: %s\nlast seen: %s\nrecord: %s\n%s\n", buckets, firstSeen, lastSeen, label, projectionNote(keptFields))
+		if raw != "" {
+			text = append(text, "\n\nraw:\n"+escape(raw)...)
+		}
+		target.SetText(header + highlightViewer(tview.TranslateANSI(string(text))))
+		target.ScrollToBeginning()
+	}
+	showRowData := func() {
+		store.RLock()
+		index := selectedIndex()
+		row := copyRow(store.Get(index))
+		store.RUnlock()
+		if index != viewerIndex {
+			viewerIndex, viewerSample = index, 0
+		}
+		showRecord(viewer, row, index, viewerSample)
+	}
+	// showPinned renders the pinned viewers again, like after the mark
+	// changed.
+	showPinned := func() {
+		for _, v := range pinned {
+			showRecord(v.TextView, v.row, v.index, v.sample)
+		}
+	}
+
+	openViewer := func() {
+		viewerOpen = true
+		flex.AddItem(viewer, 0, 1, false)
+		showRowData()
+	}
+	closeViewer := func() {
+		viewerOpen = false
+		flex.RemoveItem(viewer)
+		app.SetFocus(table)
+	}
+	pin := func() {
+		store.RLock()
+		index := selectedIndex()
+		var row RowData
+		if index >= 0 {
+			row = copyRow(store.Get(index))
+		}
+		store.RUnlock()
+		if index < 0 {
+			showMessage("nothing to pin")
+			return
+		}
+		v := newPinnedViewer(row, index)
+		pinned = append(pinned, v)
+		flex.AddItem(v, 0, 1, false)
+		showRecord(v.TextView, v.row, v.index, v.sample)
+	}
+	unpin := func(i int) {
+		flex.RemoveItem(pinned[i])
+		pinned = append(pinned[:i], pinned[i+1:]...)
+		app.SetFocus(table)
+	}
+	// panes are the table and the open viewers in the order Tab moves the
+	// focus through them.
+	panes := func() []tview.Primitive {
+		panes := []tview.Primitive{table}
+		if viewerOpen {
+			panes = append(panes, viewer)
+		}
+		for _, v := range pinned {
+			panes = append(panes, v)
+		}
+		return panes
 	}
 
 	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyDown || event.Key() == tcell.KeyUp {
-			table.SetSelectable(true, false)
+		if searching {
+			return event
+		}
+		if r, ok := keyAliases[event.Rune()]; ok && event.Key() == tcell.KeyRune {
+			event = tcell.NewEventKey(tcell.KeyRune, r, event.Modifiers())
+		}
+		if helpOpen {
+			if event.Key() == tcell.KeyEsc || event.Key() == tcell.KeyRune && event.Rune() == '?' {
+				closeHelp()
+				return nil
+			}
+			return event
+		}
+		if columnsOpen {
+			if event.Key() == tcell.KeyEsc || event.Key() == tcell.KeyRune && event.Rune() == 'c' {
+				closeColumns()
+				return nil
+			}
+			return event
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == '?' {
+			openHelp()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'c' {
+			openColumns()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == '/' {
+			openSearch()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && setLevelFilter(event.Rune()) {
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == ' ' {
+			togglePause()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 's' {
+			sortMode = (sortMode + 1) % sortModes
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && (event.Rune() == '+' || event.Rune() == '-') && noCombine {
+			showMessage("-no-combine keeps every record apart")
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && (event.Rune() == '+' || event.Rune() == '-') {
+			step := 1
+			if event.Rune() == '-' {
+				step = -1
+			}
+			store.Lock()
+			distance = store.similarity.adjust(distance, step)
+			store.SetDistance(distance)
+			store.Unlock()
+			// Regrouping moves rows, the marked index may point elsewhere.
+			marked, markedIndex = nil, -1
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'x' {
+			name, err := exportCSVFile()
+			if err != nil {
+				log.Println(err)
+				showMessage("export failed: %v", err)
+			} else {
+				showMessage("exported to %s", name)
+			}
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'J' {
+			name, err := dumpJSONFile("")
+			if err != nil {
+				log.Println(err)
+				showMessage("dump failed: %v", err)
+			} else {
+				showMessage("dumped to %s", name)
+			}
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'y' {
+			copyRowData()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'm' {
+			store.RLock()
+			index := selectedIndex()
+			if index >= 0 && index == viewerIndex {
+				marked, _ = viewerRecord(store.Get(index), viewerSample)
+			} else if index >= 0 {
+				marked = store.Get(index).GetData()
+			}
+			store.RUnlock()
+			switch {
+			case index < 0:
+			case index == markedIndex:
+				marked, markedIndex = nil, -1
+				showMessage("mark cleared")
+			default:
+				markedIndex = index
+				showMessage("marked group, the viewer shows how other groups differ from it")
+			}
 			if viewerOpen {
 				showRowData()
 			}
+			showPinned()
+			return nil
 		}
-		if event.Key() == tcell.KeyEnter && !viewerOpen {
-			viewerOpen = true
-			flex.AddItem(viewer, 0, 1, false)
+		if event.Key() == tcell.KeyRune && event.Rune() == 'd' {
+			store.Lock()
+			index := selectedIndex()
+			store.Remove(index)
+			store.Unlock()
+			if index < 0 {
+				showMessage("nothing to dismiss")
+				return nil
+			}
+			// Render now, even if paused, so the table doesn't show the
+			// indices of before the removal.
+			renderRows()
+			// Later groups moved up, the viewer and the mark follow them.
+			switch {
+			case index == markedIndex:
+				marked, markedIndex = nil, -1
+			case index < markedIndex:
+				markedIndex--
+			}
+			viewerIndex = -1
+			showMessage("dismissed group")
+			if viewerOpen {
+				showRowData()
+			}
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 't' {
+			if width := toggleTruncate(); width > 0 {
+				showMessage("truncating cells to %d characters", width)
+			} else {
+				showMessage("showing cells in full")
+			}
+			// Cells are cached by row version, render them all again.
+			rendered = rendered[:0]
+			renderRows()
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'p' {
+			pin()
+			return nil
+		}
+		focused := focusedPinned(pinned)
+		if event.Key() == tcell.KeyRune && event.Rune() == 'v' && focused >= 0 {
+			v := pinned[focused]
+			v.sample++
+			showRecord(v.TextView, v.row, v.index, v.sample)
+			return nil
+		}
+		if event.Key() == tcell.KeyRune && event.Rune() == 'v' && viewerOpen {
+			viewerSample++
 			showRowData()
+			return nil
+		}
+		if event.Key() == tcell.KeyTab {
+			app.SetFocus(nextFocus(panes()))
+			return nil
+		}
+		if focused >= 0 {
+			if event.Key() == tcell.KeyEsc {
+				unpin(focused)
+				return nil
+			}
+			if navigateViewer(pinned[focused].TextView, event) {
+				return nil
+			}
+		} else if viewer.HasFocus() {
+			if navigateViewer(viewer, event) {
+				return nil
+			}
+		} else if navigateTable(event) {
+			if viewerOpen {
+				showRowData()
+			}
+			return nil
+		} else if scrollTable(event) {
+			return nil
+		}
+		if event.Key() == tcell.KeyEnter && !viewerOpen {
+			openViewer()
 		}
 		if event.Key() == tcell.KeyEsc && viewerOpen {
-			viewerOpen = false
-			flex.RemoveItem(viewer)
+			closeViewer()
 		}
 		return event
 	})
 
-	switch format {
-	case "json", "zaplog":
-		go read()
-	case "nginx":
-		go readNginx()
+	if mouse {
+		screen, err := newMouseScreen(func(event *tcell.EventMouse) {
+			if searching || helpOpen || columnsOpen {
+				return
+			}
+			x, y := event.Position()
+			// target is the viewer under the pointer, pinned ones included.
+			target := viewer
+			inViewer := viewerOpen && inRect(viewer, x, y)
+			for _, v := range pinned {
+				if inRect(v, x, y) {
+					target, inViewer = v.TextView, true
+				}
+			}
+			switch {
+			case event.Buttons()&tcell.WheelUp != 0 && inViewer:
+				row, column := target.GetScrollOffset()
+				target.ScrollTo(max(row-wheelLines, 0), column)
+			case event.Buttons()&tcell.WheelDown != 0 && inViewer:
+				row, column := target.GetScrollOffset()
+				target.ScrollTo(row+wheelLines, column)
+			case event.Buttons()&(tcell.WheelUp|tcell.WheelDown) != 0:
+				key := tcell.KeyDown
+				if event.Buttons()&tcell.WheelUp != 0 {
+					key = tcell.KeyUp
+				}
+				navigateTable(tcell.NewEventKey(key, 0, tcell.ModNone))
+				if viewerOpen {
+					showRowData()
+				}
+			case event.Buttons()&tcell.Button1 == 0:
+			case inViewer:
+				app.SetFocus(target)
+			case inRect(table, x, y):
+				if row, ok := tableRowAt(y); ok && row <= len(rows) {
+					table.SetSelectable(true, false)
+					table.Select(row, 0)
+					app.SetFocus(table)
+					if viewerOpen {
+						showRowData()
+					} else {
+						openViewer()
+					}
+				}
+			case viewerOpen:
+				closeViewer()
+			}
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		app.SetScreen(screen)
 	}
 
-	go draw()
+	// A signal stops the app like q, restoring the terminal and saving the
+	// table. If that hangs, or on a second signal, red exits right away.
+	stopped := make(chan struct{})
+	ch := make(chan os.Signal, 2)
+	signal.Notify(ch, stopSignals...)
+	go func() {
+		if !stopOnSignal(ch, app.Stop, stopped, stopTimeout) {
+			return
+		}
+		log.Println("warn: forced exit, the terminal may need a reset")
+		closeListeners()
+		fout.Close()
+		os.Exit(1)
+	}()
+
+	if batchInterval > 0 {
+		updates = newBatcher(batchInterval)
+		go updates.run()
+	}
+	go read(inputs)
+	for _, l := range listeners {
+		go serve(l)
+	}
+	go draw(refresh, func(f func()) { app.QueueUpdateDraw(f) }, stopped)
 	go shift(duration)
+	go shiftWindow(store.Window())
 
 	if err := app.Run(); err != nil {
 		panic(err)
 	}
+	close(stopped)
+	flushOutput()
+}
+
+// flushOutput applies the entries still batched with -batch and writes the
+// -save and -dump-json files, once the app stopped.
+func flushOutput() {
+	if updates != nil {
+		updates.close()
+	}
+	saveOnExit()
+}
+
+// saveOnExit writes the table to the -save and -dump-on-exit files.
+func saveOnExit() {
+	if saveFile != "" {
+		if err := saveStore(saveFile); err != nil {
+			log.Println(err)
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if dumpFile != "" {
+		if _, err := dumpJSONFile(dumpFile); err != nil {
+			log.Println(err)
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+// openLogFile directs the log to name, appending to it, or discards it if
+// name is - or empty.
+func openLogFile(name string) (io.Closer, error) {
+	if name == "" || name == "-" {
+		log.SetOutput(io.Discard)
+		return io.NopCloser(nil), nil
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	log.SetOutput(f)
+	return f, nil
+}
+
+func loadStore(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := store.Load(f); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if len(keys) == 0 {
+		keys = store.keys
+	} else {
+		store.SetKeys(keys)
+	}
+	return nil
+}
+
+func saveStore(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	store.RLock()
+	err = store.Save(f)
+	store.RUnlock()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// copyRowData copies the latest record of the selected row to the clipboard
+// as JSON.
+func copyRowData() {
+	store.RLock()
+	index := selectedIndex()
+	data := store.Get(index).GetData()
+	store.RUnlock()
+	if index < 0 {
+		showMessage("nothing to copy")
+		return
+	}
+
+	text, err := json.MarshalIndent(withoutRaw(data), "", "  ")
+	if err == nil {
+		err = copyToClipboard(string(text))
+	}
+	if err != nil {
+		log.Println("copy:", err)
+		showMessage("copy failed: %v", err)
+		return
+	}
+	showMessage("copied row to clipboard")
+}
+
+// selectedIndex returns the store index of the selected table row, or of
+// the first row if there is no selection.
+func selectedIndex() int {
+	row, _ := table.GetSelection()
+	if row == 0 {
+		row = 1
+	}
+	if row > len(rows) {
+		return -1
+	}
+	return rows[row-1]
+}
+
+// viewerRecord returns the i-th record of row the viewer cycles through,
+// the kept samples from the latest back and then the first, and its label.
+func viewerRecord(row RowData, i int) (map[string]interface{}, string) {
+	samples := row.GetSamples()
+	i %= len(samples) + 1
+	switch {
+	case i == len(samples):
+		return row.GetFirst(), "first"
+	case len(samples) == 1:
+		return samples[0], "latest, v shows the first"
+	case i == 0:
+		return samples[len(samples)-1], fmt.Sprintf("latest of %d kept, v shows older ones", len(samples))
+	}
+	return samples[len(samples)-1-i], fmt.Sprintf("%d before the latest", i)
 }
 
+// setCell updates the text of a table cell, creating the cell if it doesn't
+// exist yet.
+func setCell(row, column int, text string, selectable bool) *tview.TableCell {
+	// GetCell returns a new, detached cell each time for a missing cell.
+	if cell := table.GetCell(row, column); cell == table.GetCell(row, column) {
+		return cell.SetText(text).SetSelectable(selectable)
+	}
+	cell := tview.NewTableCell(text).SetSelectable(selectable)
+	table.SetCell(row, column, cell)
+	return cell
+}
+
+// renderColumns rebuilds the table header for the current keys. The data
+// rows are cleared as well, since the table can't drop columns, and are
+// filled again by renderRows.
 func renderColumns() {
 	headerCell := func(s string) *tview.TableCell {
 		return tview.NewTableCell(s).
@@ -182,124 +1180,304 @@ func renderColumns() {
 			SetSelectable(false)
 	}
 
+	table.Clear()
+	rendered = rendered[:0]
 	table.SetCell(0, trendColumn, headerCell("trend"))
 	table.SetCell(0, countColumn, headerCell("count"))
+	table.SetCell(0, rateColumn, headerCell("rate"))
 	for i, key := range keys {
 		table.SetCell(0, firstDataColumn+i, headerCell(key))
 	}
+	for i, st := range stats {
+		table.SetCell(0, firstDataColumn+len(keys)+i, headerCell(st.String()))
+	}
+	if showSeen {
+		table.SetCell(0, seenColumn(), headerCell("first seen"))
+		table.SetCell(0, seenColumn()+1, headerCell("last seen"))
+	}
 }
 
-func update(value map[string]interface{}) {
-	if len(keys) == 0 {
-		keys = mapKeys(value)
-		store.SetKeys(keys)
-		renderColumns()
+// seenColumn returns the first seen column, the last seen one follows it.
+func seenColumn() int {
+	return firstDataColumn + len(keys) + len(stats)
+}
+
+// update filters value, writes it to -out-matches and pushes it to the
+// store, reporting whether it passed.
+func update(value map[string]interface{}) bool {
+	// update is called by concurrent readers in follow mode, so infer keys
+	// while holding the lock.
+	if ignoreRecord(ignoreFields, value) {
+		return false
+	}
+	normalizeTime(value)
+	if !inTimeBounds(value) {
+		return false
+	}
+	if len(extracts) > 0 {
+		applyExtracts(extracts, value)
+	}
+	// Filter after extracting, so -filter can compare extracted fields.
+	if !matchPredicates(predicates, value) {
+		return false
 	}
+	// Sample after filtering, so every Nth matching entry counts.
+	if every > 1 && (sampled.Add(1)-1)%int64(every) != 0 {
+		return false
+	}
+	if accessLogFormat(currentFormat()) {
+		addStatusClass(value)
+	}
+	// Project last, the filters and extracts above see every field.
+	project(keptFields, value)
+	// Once queued, value may be read by the batcher pushing it.
+	writeMatch(value)
+
+	if updates != nil {
+		updates.add(value)
+		return true
+	}
+	apply([]map[string]interface{}{value})
+	return true
+}
 
+// apply pushes entries to the store, inferring the keys from the first one
+// if none were given, and notifies of the alerts they fire.
+func apply(values []map[string]interface{}) {
 	store.Lock()
-	store.Push(value)
+	for _, value := range values {
+		// Batches are applied by the batcher, recover here so one bad entry
+		// doesn't lose the rest of its batch.
+		recovered("grouping", func() {
+			if len(keys) == 0 {
+				keys = mapKeys(value)
+				if store.drain != nil {
+					keys = templateColumns(keys)
+				}
+				store.SetKeys(keys)
+				if table != nil {
+					renderColumns()
+				}
+			}
+			store.Push(value)
+		})
+	}
+	fired := store.TakeAlerts()
 	store.Unlock()
+	notifyAlerts(fired)
 }
 
-func read() {
-	var dec Decoder
-	switch format {
-	case "json":
-		dec = newJsonDecoder(os.Stdin)
-	case "zaplog":
-		dec = newZaplogDecoder(os.Stdin)
+// read decodes all inputs, merging them in datetime order if there are
+// several. Followed inputs never end, so they are read concurrently instead.
+func read(inputs []io.ReadCloser) {
+	if len(inputs) == 0 {
+		return
 	}
+	if follow && len(inputs) > 1 || mergeStdin {
+		// Followed files and stdin never end, so the others aren't merged
+		// in datetime order but read as they come.
+		consumeAll(inputs, func(err error) {
+			log.Println(err)
+			app.Stop()
+		})
+		progress.done.Store(true)
+		return
+	}
+	decode(inputDecoder(inputs))
+	progress.done.Store(true)
+}
+
+// inputDecoder decodes inputs one after another, merged in datetime order if
+// there are several.
+func inputDecoder(inputs []io.ReadCloser) Decoder {
+	if len(inputs) == 1 {
+		return newSourceDecoder(inputs[0])
+	}
+	decs := make([]Decoder, len(inputs))
+	for i, in := range inputs {
+		decs[i] = newSourceDecoder(in)
+	}
+	return newMergeDecoder(decs)
+}
+
+// decode updates the store with every record of dec, a decoding error
+// stops red.
+func decode(dec Decoder) {
+	if err := consume(dec); err != nil {
+		log.Println(err)
+		app.Stop()
+	}
+}
 
+// consume updates the store with the records of dec until the input ends,
+// fails to decode or -max-lines records were read from all inputs.
+func consume(dec Decoder) error {
 	for dec.More() {
-		value, err := dec.Decode()
+		var value map[string]interface{}
+		var err error
+		if !recovered("decoding", func() { value, err = dec.Decode() }) {
+			continue
+		}
 		if err != nil {
 			if err == io.EOF {
 				continue
 			}
-
-			log.Println(err)
-			app.Stop()
+			return err
+		}
+		if n := consumed.Add(1); maxLines > 0 && n > maxLines {
+			limited.Store(true)
+			return nil
+		}
+		if raw := rawText(dec); raw != "" {
+			value[rawKey] = raw
+		}
+		if replay != nil {
+			replay.wait(value)
 		}
 
-		update(value)
+		recovered("grouping", func() {
+			update(value)
+		})
 	}
+	return nil
 }
 
-func readNginx() {
-	config, err := os.Open(nginxConfig)
-	if err != nil {
-		panic(err)
+func shift(duration time.Duration) {
+	for {
+		shiftTrends()
+		time.Sleep(duration / time.Duration(trendBuckets))
 	}
-	defer config.Close()
+}
 
-	reader, err := gonx.NewNginxReader(os.Stdin, config, nginxFormat)
-	if err != nil {
-		panic(err)
-	}
-	for {
-		rec, err := reader.Read()
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			panic(err)
-		}
-		// Process the record... e.g.
-		fmt.Printf("Parsed entry: %+v\n", rec)
+// shiftTrends moves the trends of the store on by a bucket, unless paused.
+func shiftTrends() {
+	if paused.Load() {
+		return
 	}
+	store.Lock()
+	store.Shift()
+	store.Unlock()
+}
+
+// togglePause freezes the table and the trends, or resumes them. The store
+// keeps ingesting while paused, resuming shows what arrived meanwhile.
+func togglePause() {
+	paused.Store(!paused.Load())
 }
 
-func readCommon(format string) {
-	reader := gonx.NewReader(os.Stdin, format)
+// minRefresh is the lowest -refresh, redrawing more often only burns CPU.
+const minRefresh = 10 * time.Millisecond
+
+// draw queues a redraw with queue every refresh, until stop is closed.
+func draw(refresh time.Duration, queue func(func()), stop <-chan struct{}) {
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
 	for {
-		rec, err := reader.Read()
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			panic(err)
+		select {
+		case <-ticker.C:
+			queue(redraw)
+		case <-stop:
+			return
 		}
-		// Process the record... e.g.
-		fmt.Printf("Parsed entry: %+v\n", rec)
 	}
 }
 
-func shift(duration time.Duration) {
-	for {
-		store.Lock()
-		store.Shift()
-		store.Unlock()
-		time.Sleep(duration / trendSize)
+// redraw renders the table, unless paused, and the footer and status line,
+// which keep counting the entries a paused table doesn't show yet.
+func redraw() {
+	if !paused.Load() {
+		renderRows()
 	}
+	renderFooter()
+	renderStatus()
 }
 
-func draw() {
-	for {
-		app.QueueUpdateDraw(func() {
-			store.RLock()
-			defer store.RUnlock()
-
-			row := 1
-			for ; row < table.GetRowCount(); row++ {
-				data := store.Get(row - 1)
-				table.GetCell(row, trendColumn).SetText(Spark(data.GetTrend()))
-				table.GetCell(row, countColumn).SetText(data.GetCount())
-				for j := 0; j < len(keys); j++ {
-					text := fmt.Sprintf("%v", data.Get(keys[j]))
-					table.GetCell(row, firstDataColumn+j).SetText(text)
-				}
-			}
+// renderRows renders the store entries passing the level and search filters
+// in sortMode order. The selection follows the selected entry as it moves.
+// Only table rows showing another store row, or a changed one, are rewritten.
+func renderRows() {
+	store.RLock()
+	defer store.RUnlock()
 
-			for ; row <= store.Len(); row++ {
-				data := store.Get(row - 1)
-				table.SetCell(row, trendColumn, tview.NewTableCell(Spark(data.GetTrend())).
-					SetSelectable(false))
-				table.SetCell(row, countColumn, tview.NewTableCell(data.GetCount()).
-					SetSelectable(false))
-				for j := 0; j < len(keys); j++ {
-					text := fmt.Sprintf("%v", data.Get(keys[j]))
-					table.SetCellSimple(row, firstDataColumn+j, text)
-				}
-			}
-		})
-		time.Sleep(100 * time.Millisecond)
+	selected := -1
+	if selectable, _ := table.GetSelectable(); selectable {
+		selected = selectedIndex()
+	}
+
+	rows = rows[:0]
+	for _, i := range store.Sorted(sortMode) {
+		if data := store.Get(i); matchLevel(data) && matchSearch(data) {
+			rows = append(rows, i)
+		}
+	}
+	var rest []int
+	rows, rest = store.Top(rows, topN)
+
+	if searchRegexp != renderedSearch {
+		rendered = rendered[:0]
+		renderedSearch = searchRegexp
+	}
+
+	for i, index := range rows {
+		row := i + 1
+		data := store.Get(index)
+		if i < len(rendered) && rendered[i] == data.GetVersion() {
+			continue
+		}
+		if i < len(rendered) {
+			rendered[i] = data.GetVersion()
+		} else {
+			rendered = append(rendered, data.GetVersion())
+		}
+		color, attr := levelStyle(data.GetLevel())
+		if data.GetAlerting() {
+			attr |= tcell.AttrReverse
+		}
+		spark := Spark(data.GetTrend(), sparkRamp)
+		if splitTrend {
+			spark += " " + SplitSpark(data, sparkRamp)
+		}
+		setCell(row, trendColumn, spark, false).
+			SetTextColor(color).SetAttributes(attr)
+		setCell(row, countColumn, data.GetCount(), false).
+			SetTextColor(color).SetAttributes(attr)
+		setCell(row, rateColumn, formatRate(store.GroupRate(index)), false).
+			SetTextColor(color).SetAttributes(attr).SetAlign(tview.AlignRight)
+		for j := 0; j < len(keys); j++ {
+			text := truncateCell(formatValue(data.Get(keys[j])))
+			setCell(row, firstDataColumn+j, highlightCell(keys[j], text), true).
+				SetTextColor(color).SetAttributes(attr)
+		}
+		for j, st := range stats {
+			setCell(row, firstDataColumn+len(keys)+j, data.GetStat(j, st), true).
+				SetTextColor(color).SetAttributes(attr).SetAlign(tview.AlignRight)
+		}
+		if showSeen {
+			setCell(row, seenColumn(), formatSeen(data.GetFirstSeen()), true).
+				SetTextColor(color).SetAttributes(attr)
+			setCell(row, seenColumn()+1, formatSeen(data.GetLastSeen()), true).
+				SetTextColor(color).SetAttributes(attr)
+		}
+	}
+
+	// The others row isn't tracked in rendered, so a group taking its place
+	// is rendered in full.
+	count := len(rows) + 1
+	if len(rest) > 0 {
+		renderOthers(count, rest)
+		count++
+	}
+	for table.GetRowCount() > count {
+		table.RemoveRow(table.GetRowCount() - 1)
+	}
+	if len(rendered) > len(rows) {
+		rendered = rendered[:len(rows)]
+	}
+
+	for i, index := range rows {
+		if index == selected {
+			table.Select(i+1, 0)
+			break
+		}
 	}
 }
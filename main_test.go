@@ -0,0 +1,243 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+func TestSetCell(t *testing.T) {
+	table = tview.NewTable()
+	defer func() { table = nil }()
+
+	setCell(1, 0, "a", false)
+	setCell(1, 1, "b", true)
+	setCell(1, 0, "c", false)
+	if got := table.GetCell(1, 0).Text; got != "c" {
+		t.Errorf("cell 1,0 is %q, want c", got)
+	}
+	if got := table.GetCell(1, 1); got.Text != "b" || table.GetColumnCount() != 2 {
+		t.Errorf("cell 1,1 is %q in %d columns, want b, added after the first column", got.Text, table.GetColumnCount())
+	}
+}
+
+func TestRedrawPaused(t *testing.T) {
+	keys = []string{"message"}
+	table = tview.NewTable().SetFixed(1, 2)
+	footer, status = newStatus(), newStatus()
+	store = NewStore(time.Second, defaultTrendBuckets, 3, keys, nil)
+	defer func() {
+		keys, table, footer, status, store, rows = nil, nil, nil, nil, nil, nil
+		paused.Store(false)
+	}()
+
+	renderColumns()
+	store.Push(map[string]interface{}{"message": "a"})
+	redraw()
+	togglePause()
+	store.Push(map[string]interface{}{"message": "disk full"})
+	trend := store.Get(0).GetTrend()
+	shiftTrends()
+	redraw()
+
+	// the table and trends freeze, the footer keeps counting
+	if n := table.GetRowCount(); n != 2 {
+		t.Errorf("%d table rows while paused, want the header and the first entry", n)
+	}
+	if got := store.Get(0).GetTrend(); got[len(got)-1] != trend[len(trend)-1] {
+		t.Errorf("trend shifted while paused: %v, was %v", got, trend)
+	}
+	if text := footer.GetText(true); !strings.Contains(text, "events: 2") {
+		t.Errorf("footer %q while paused, want it counting 2 events", text)
+	}
+	if text := status.GetText(true); !strings.Contains(text, "PAUSED") {
+		t.Errorf("status %q while paused", text)
+	}
+
+	togglePause()
+	redraw()
+	if n := table.GetRowCount(); n != 3 {
+		t.Errorf("%d table rows after resuming, want the entries buffered meanwhile", n)
+	}
+}
+
+func TestDraw(t *testing.T) {
+	var draws atomic.Int32
+	third, stop, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	queue := func(func()) {
+		if draws.Add(1) == 3 {
+			close(third)
+		}
+	}
+	start := time.Now()
+	go func() {
+		draw(10*time.Millisecond, queue, stop)
+		close(done)
+	}()
+
+	select {
+	case <-third:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%d redraws queued, want 3", draws.Load())
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("3 redraws queued in %v, want one per 10ms -refresh", elapsed)
+	}
+	close(stop)
+	<-done
+}
+
+func TestOpenLogFile(t *testing.T) {
+	defer log.SetOutput(os.Stderr)
+
+	name := filepath.Join(t.TempDir(), "red.log")
+	for _, msg := range []string{"first", "second"} {
+		f, err := openLogFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		log.Print(msg)
+		f.Close()
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); !strings.Contains(s, "first") || !strings.Contains(s, "second") {
+		t.Errorf("log file holds %q, want both messages appended", s)
+	}
+
+	for _, name := range []string{"", "-"} {
+		f, err := openLogFile(name)
+		if err != nil {
+			t.Errorf("openLogFile(%q) error = %v", name, err)
+			continue
+		}
+		log.Print("discarded")
+		if err := f.Close(); err != nil {
+			t.Errorf("openLogFile(%q) Close() error = %v", name, err)
+		}
+	}
+	if _, err := os.Stat("-"); !os.IsNotExist(err) {
+		t.Errorf("openLogFile(\"-\") created a file: %v", err)
+	}
+
+	if _, err := openLogFile(filepath.Join(t.TempDir(), "missing", "red.log")); err == nil {
+		t.Error("openLogFile() in a missing directory succeeded")
+	}
+}
+
+func TestConsumeMaxLines(t *testing.T) {
+	keys = []string{"message"}
+	store = NewStore(time.Second, defaultTrendBuckets, 3, keys, nil)
+	maxLines = 3
+	consumed.Store(0)
+	defer func() {
+		keys, store, maxLines = nil, nil, 0
+		consumed.Store(0)
+		limited.Store(false)
+	}()
+
+	input := strings.Repeat(`{"message": "a"}`+"\n", 2)
+	if err := consume(newJsonDecoder(strings.NewReader(input))); err != nil {
+		t.Fatal(err)
+	}
+	if limited.Load() {
+		t.Error("limited after 2 of 3 records")
+	}
+
+	// the limit is shared by all inputs
+	dec := newJsonDecoder(strings.NewReader(strings.Repeat(`{"message": "disk full"}`+"\n", 3)))
+	if err := consume(dec); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.Total(); got != 3 {
+		t.Errorf("%d entries grouped, want 3", got)
+	}
+	if !limited.Load() {
+		t.Error("not limited after dropping a record")
+	}
+	if !dec.More() {
+		t.Error("input read to the end past the limit")
+	}
+}
+
+func TestConsumeKeepsRaw(t *testing.T) {
+	store = NewStore(time.Second, defaultTrendBuckets, 3, nil, nil)
+	defer func() {
+		keys, store = nil, nil
+		consumed.Store(0)
+	}()
+
+	input := "level=error msg=\"disk full\" host=db1\n"
+	if err := consume(newLogfmtDecoder(strings.NewReader(input))); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.Get(0).GetData()[rawKey]; got != strings.TrimSpace(input) {
+		t.Errorf("raw = %q, want the input line", got)
+	}
+	if want := []string{"host", "level", "msg"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("inferred keys %v, want %v", keys, want)
+	}
+	if fields := store.Fields(); !reflect.DeepEqual(fields, keys) {
+		t.Errorf("fields %v, want %v", fields, keys)
+	}
+}
+
+func TestConsumeBatched(t *testing.T) {
+	keys = []string{"msg"}
+	store = NewStore(time.Second, defaultTrendBuckets, 3, keys, []string{templateKey})
+	updates = newBatcher(time.Microsecond)
+	var out strings.Builder
+	outMatches = &out
+	defer func() {
+		keys, store, updates, outMatches = nil, nil, nil, nil
+		consumed.Store(0)
+	}()
+	go updates.run()
+
+	// The batcher pushes records while consume still reads them.
+	input := strings.Repeat("level=error msg=\"request 1f3a took 12ms\"\n", 1000)
+	if err := consume(newLogfmtDecoder(strings.NewReader(input))); err != nil {
+		t.Fatal(err)
+	}
+	updates.close()
+	if got := strings.Count(out.String(), "\n"); got != 1000 {
+		t.Errorf("%d lines written to -out-matches, want 1000", got)
+	}
+	if got := store.Get(0); got.count != 1000 || got.Get(templateKey) == nil {
+		t.Errorf("group counts %d with template %v, want 1000 and a template", got.count, got.Get(templateKey))
+	}
+}
+
+func TestViewerRecord(t *testing.T) {
+	s := NewStore(time.Second, defaultTrendBuckets, 3, []string{"message"}, nil)
+	s.SetKeepSamples(2)
+	for i := 1; i <= 3; i++ {
+		s.Push(map[string]interface{}{"message": "a", "id": i})
+	}
+
+	tests := []struct {
+		i     int
+		id    int
+		label string
+	}{
+		{0, 3, "latest of 2 kept, v shows older ones"},
+		{1, 2, "1 before the latest"},
+		{2, 1, "first"},
+		{3, 3, "latest of 2 kept, v shows older ones"},
+	}
+	for _, tt := range tests {
+		record, label := viewerRecord(s.Get(0), tt.i)
+		if record["id"] != tt.id || label != tt.label {
+			t.Errorf("viewerRecord(%d) = id %v, %q, want id %d, %q", tt.i, record["id"], label, tt.id, tt.label)
+		}
+	}
+}
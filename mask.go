@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces variable parts of messages, like ids, before grouping.
+type Mask struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// parseMask parses a spec like `\d+=>N`. The replacement defaults to "*".
+func parseMask(spec string) (Mask, error) {
+	expr, repl, ok := strings.Cut(spec, "=>")
+	if !ok {
+		repl = "*"
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return Mask{}, fmt.Errorf("invalid mask %q: %w", spec, err)
+	}
+	return Mask{re: re, repl: repl}, nil
+}
+
+func parseMasks(specs []string) ([]Mask, error) {
+	masks := make([]Mask, 0, len(specs))
+	for _, spec := range specs {
+		m, err := parseMask(spec)
+		if err != nil {
+			return nil, err
+		}
+		masks = append(masks, m)
+	}
+	return masks, nil
+}
+
+// applyMasks applies all masks in order and reports whether any matched.
+func applyMasks(masks []Mask, s string) (string, bool) {
+	matched := false
+	for _, m := range masks {
+		if m.re.MatchString(s) {
+			s = m.re.ReplaceAllString(s, m.repl)
+			matched = true
+		}
+	}
+	return s, matched
+}
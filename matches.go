@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"sync/atomic"
+)
+
+// matchFilter is the level filter and search of the table. Readers check
+// records against it concurrently with the UI changing them, so it is
+// replaced rather than modified.
+type matchFilter struct {
+	minLevel int
+	search   *regexp.Regexp
+}
+
+var (
+	// outMatches receives the raw text of the records passing the filters,
+	// see -out-matches.
+	outMatches   io.Writer
+	outMatchesMu sync.Mutex
+
+	filter atomic.Pointer[matchFilter]
+)
+
+// storeFilter publishes the current level filter and search to the readers.
+func storeFilter() {
+	filter.Store(&matchFilter{minLevel: minLevel, search: searchRegexp})
+}
+
+// writeMatch appends the original text of value to -out-matches if value
+// passes the level filter and its text contains the search query.
+func writeMatch(value map[string]interface{}) {
+	if outMatches == nil {
+		return
+	}
+	raw, _ := value[rawKey].(string)
+	if raw == "" {
+		return
+	}
+	if f := filter.Load(); f != nil {
+		if f.minLevel != 0 && levelRank(fmt.Sprintf("%v", value["level"])) < f.minLevel {
+			return
+		}
+		if f.search != nil && !f.search.MatchString(raw) {
+			return
+		}
+	}
+	outMatchesMu.Lock()
+	defer outMatchesMu.Unlock()
+	fmt.Fprintln(outMatches, raw)
+}
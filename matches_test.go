@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteMatch(t *testing.T) {
+	var out strings.Builder
+	outMatches = &out
+	defer func() { outMatches = nil }()
+	defer func() {
+		setLevelFilter('a')
+		setSearch("")
+	}()
+
+	records := []struct {
+		level, raw string
+	}{
+		{"ERROR", "ERROR user 1 not found"},
+		{"INFO", "INFO user 2 not found"},
+		{"ERROR", "ERROR disk full"},
+		{"ERROR", ""},
+	}
+	write := func() string {
+		out.Reset()
+		for _, r := range records {
+			writeMatch(map[string]interface{}{"level": r.level, rawKey: r.raw})
+		}
+		return out.String()
+	}
+
+	setLevelFilter('a')
+	if got, want := write(), "ERROR user 1 not found\nINFO user 2 not found\nERROR disk full\n"; got != want {
+		t.Errorf("without filters wrote %q, want %q", got, want)
+	}
+	setLevelFilter('e')
+	if got, want := write(), "ERROR user 1 not found\nERROR disk full\n"; got != want {
+		t.Errorf("with the ERROR filter wrote %q, want %q", got, want)
+	}
+	setSearch("USER")
+	if got, want := write(), "ERROR user 1 not found\n"; got != want {
+		t.Errorf("with the ERROR filter and a search wrote %q, want %q", got, want)
+	}
+}
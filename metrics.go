@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsCollector exports the counters of the store and the readers to
+// Prometheus, read when scraped.
+type metricsCollector struct {
+	store *Store
+
+	lines, invalid, ignored, groups, levels *prometheus.Desc
+}
+
+func newMetricsCollector(store *Store) *metricsCollector {
+	return &metricsCollector{
+		store:   store,
+		lines:   prometheus.NewDesc("red_lines_total", "Entries ingested into the table.", nil, nil),
+		invalid: prometheus.NewDesc("red_invalid_lines_total", "Input lines no format could parse.", nil, nil),
+		ignored: prometheus.NewDesc("red_ignored_lines_total", "Lines and records dropped by -ignore and -ignore-field.", nil, nil),
+		groups:  prometheus.NewDesc("red_groups", "Groups of similar entries in the table.", nil, nil),
+		levels:  prometheus.NewDesc("red_level_lines_total", "Entries ingested by level, none for entries without a known level.", []string{"level"}, nil),
+	}
+}
+
+func (c *metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.lines
+	ch <- c.invalid
+	ch <- c.ignored
+	ch <- c.groups
+	ch <- c.levels
+}
+
+func (c *metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.store.RLock()
+	total, groups, levels := c.store.Total(), c.store.Len(), c.store.LevelTotals()
+	c.store.RUnlock()
+
+	ch <- prometheus.MustNewConstMetric(c.lines, prometheus.CounterValue, float64(total))
+	ch <- prometheus.MustNewConstMetric(c.invalid, prometheus.CounterValue, float64(invalidLines.Load()))
+	ch <- prometheus.MustNewConstMetric(c.ignored, prometheus.CounterValue, float64(ignoredLines.Load()))
+	ch <- prometheus.MustNewConstMetric(c.groups, prometheus.GaugeValue, float64(groups))
+	for level, n := range levels {
+		ch <- prometheus.MustNewConstMetric(c.levels, prometheus.CounterValue, float64(n), level)
+	}
+}
+
+// metricsHandler serves the metrics of store.
+func metricsHandler(store *Store) http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(newMetricsCollector(store))
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// startMetrics serves the metrics of store at /metrics on addr, see -metrics,
+// until the returned listener is closed.
+func startMetrics(addr string, store *Store) (net.Listener, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsHandler(store))
+	go func() {
+		if err := http.Serve(l, mux); err != nil && !errors.Is(err, net.ErrClosed) {
+			log.Println("metrics:", err)
+		}
+	}()
+	return l, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	s := NewStore(time.Second, defaultTrendBuckets, 1, []string{"message"}, nil)
+	s.Push(map[string]interface{}{"level": "error", "message": "disk full"})
+	s.Push(map[string]interface{}{"level": "ERROR", "message": "disk full"})
+	s.Push(map[string]interface{}{"level": "INFO", "message": "started"})
+	s.Push(map[string]interface{}{"message": "no level"})
+
+	w := httptest.NewRecorder()
+	metricsHandler(s).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Result().Body)
+	for _, want := range []string{
+		"red_lines_total 4\n",
+		"red_groups 3\n",
+		`red_level_lines_total{level="ERROR"} 2` + "\n",
+		`red_level_lines_total{level="INFO"} 1` + "\n",
+		`red_level_lines_total{level="none"} 1` + "\n",
+		"red_invalid_lines_total ",
+		"red_ignored_lines_total ",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics lack %q:\n%s", want, body)
+		}
+	}
+}
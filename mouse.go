@@ -0,0 +1,74 @@
+package main
+
+import (
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// wheelLines is the number of lines the viewer scrolls per wheel step.
+const wheelLines = 3
+
+// mouseScreen enables the mouse on a tcell screen. The tview version red
+// uses drops mouse events, so they are taken out of PollEvent and handled on
+// the application goroutine instead.
+type mouseScreen struct {
+	tcell.Screen
+	handler func(event *tcell.EventMouse)
+	buttons tcell.ButtonMask
+}
+
+func newMouseScreen(handler func(event *tcell.EventMouse)) (*mouseScreen, error) {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return nil, err
+	}
+	// tview only initializes the screens it creates.
+	if err := screen.Init(); err != nil {
+		return nil, err
+	}
+	screen.EnableMouse()
+	return &mouseScreen{Screen: screen, handler: handler}, nil
+}
+
+func (s *mouseScreen) PollEvent() tcell.Event {
+	for {
+		event := s.Screen.PollEvent()
+		mouse, ok := event.(*tcell.EventMouse)
+		if !ok {
+			return event
+		}
+		// Motion with a button held repeats its mask, only handle presses.
+		pressed := mouse.Buttons() &^ s.buttons
+		s.buttons = mouse.Buttons() &^ (tcell.WheelUp | tcell.WheelDown)
+		if pressed == 0 {
+			continue
+		}
+		x, y := mouse.Position()
+		event = tcell.NewEventMouse(x, y, pressed, mouse.Modifiers())
+		app.QueueUpdateDraw(func() {
+			s.handler(event.(*tcell.EventMouse))
+		})
+	}
+}
+
+// inRect reports whether the screen position is inside the primitive.
+func inRect(p tview.Primitive, x, y int) bool {
+	rx, ry, width, height := p.GetRect()
+	return x >= rx && x < rx+width && y >= ry && y < ry+height
+}
+
+// tableRowAt returns the data row of the table at screen line y.
+func tableRowAt(y int) (int, bool) {
+	_, top, _, height := table.GetInnerRect()
+	line := y - top
+	// line 0 is the fixed header, the data rows below it are scrolled
+	if line < 1 || line >= height {
+		return 0, false
+	}
+	offset, _ := table.GetOffset()
+	row := line + offset
+	if row >= table.GetRowCount() {
+		return 0, false
+	}
+	return row, true
+}
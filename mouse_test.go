@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+func TestTableRowAt(t *testing.T) {
+	table = tview.NewTable().SetFixed(1, 2)
+	defer func() { table = nil }()
+	for row := 0; row < 20; row++ {
+		table.SetCellSimple(row, 0, "x")
+	}
+	table.SetRect(0, 2, 80, 10)
+
+	tests := []struct {
+		y    int
+		want int
+		ok   bool
+	}{
+		{1, 0, false},  // above the table
+		{2, 0, false},  // header
+		{3, 1, true},   // first data row
+		{11, 9, true},  // last visible row
+		{12, 0, false}, // below the table
+	}
+	for i, tt := range tests {
+		if got, ok := tableRowAt(tt.y); got != tt.want || ok != tt.ok {
+			t.Errorf("Test[%d]: tableRowAt(%d) = %d, %v, want %d, %v", i, tt.y, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	table.SetOffset(5, 0)
+	if got, ok := tableRowAt(3); got != 6 || !ok {
+		t.Errorf("scrolled tableRowAt(3) = %d, %v, want 6, true", got, ok)
+	}
+
+	if !inRect(table, 79, 11) || inRect(table, 80, 11) || inRect(table, 0, 1) {
+		t.Error("inRect() is off the table bounds")
+	}
+}
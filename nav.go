@@ -0,0 +1,158 @@
+package main
+
+import (
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+// selectionMove returns the row offset of a navigation key, relative to the
+// current row, and whether the key navigates at all. Jumps to the top and
+// bottom are returned as the minimum and maximum int offsets.
+//
+// Besides the arrow keys vim motions are supported: j/k, g/G and
+// Ctrl-d/Ctrl-u for half a page of height rows.
+func selectionMove(event *tcell.EventKey, height int) (int, bool) {
+	half := height / 2
+	if half < 1 {
+		half = 1
+	}
+	switch event.Key() {
+	case tcell.KeyDown:
+		return 1, true
+	case tcell.KeyUp:
+		return -1, true
+	case tcell.KeyHome:
+		return minInt, true
+	case tcell.KeyEnd:
+		return maxInt, true
+	case tcell.KeyCtrlD:
+		return half, true
+	case tcell.KeyCtrlU:
+		return -half, true
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case 'j':
+			return 1, true
+		case 'k':
+			return -1, true
+		case 'g':
+			return minInt, true
+		case 'G':
+			return maxInt, true
+		}
+	}
+	return 0, false
+}
+
+const (
+	maxInt = int(^uint(0) >> 1)
+	minInt = -maxInt - 1
+)
+
+// moveRow applies a selectionMove to row, keeping it within the data rows
+// 1 to count-1 below the table header.
+func moveRow(row, move, count int) int {
+	switch {
+	case move == minInt:
+		row = 1
+	case move == maxInt:
+		row = count - 1
+	default:
+		row += move
+	}
+	if row > count-1 {
+		row = count - 1
+	}
+	if row < 1 {
+		row = 1
+	}
+	return row
+}
+
+// navigateTable moves the table selection, the first navigation key only
+// makes the table selectable.
+func navigateTable(event *tcell.EventKey) bool {
+	_, _, _, height := table.GetInnerRect()
+	move, ok := selectionMove(event, height-1)
+	if !ok {
+		return false
+	}
+	row, _ := table.GetSelection()
+	if selectable, _ := table.GetSelectable(); !selectable {
+		table.SetSelectable(true, false)
+		move = 0
+	}
+	// The rows of groups end at len(rows), below is at most the others row.
+	table.Select(moveRow(row, move, len(rows)+1), 0)
+	return true
+}
+
+// columnMove returns the number of columns a key scrolls the table by, h/l
+// and the arrows with or without Shift, and whether the key scrolls at all.
+func columnMove(event *tcell.EventKey) (int, bool) {
+	switch event.Key() {
+	case tcell.KeyLeft:
+		return -1, true
+	case tcell.KeyRight:
+		return 1, true
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case 'h':
+			return -1, true
+		case 'l':
+			return 1, true
+		}
+	}
+	return 0, false
+}
+
+// moveColumn applies a columnMove to the offset of the scrolled columns,
+// keeping at least the last of columns visible.
+func moveColumn(offset, move, columns int) int {
+	offset += move
+	if offset > columns-1 {
+		offset = columns - 1
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return offset
+}
+
+// scrollTable scrolls the key and stat columns, the trend, count and rate
+// stay in place.
+func scrollTable(event *tcell.EventKey) bool {
+	move, ok := columnMove(event)
+	if !ok {
+		return false
+	}
+	row, column := table.GetOffset()
+	table.SetOffset(row, moveColumn(column, move, table.GetColumnCount()-firstDataColumn))
+	return true
+}
+
+// scrolledColumn returns the header of the first column shown after the
+// fixed ones if the table is scrolled, or "".
+func scrolledColumn() string {
+	if _, column := table.GetOffset(); column > 0 {
+		return table.GetCell(0, firstDataColumn+column).Text
+	}
+	return ""
+}
+
+// navigateViewer scrolls the viewer by half a page on Ctrl-d/Ctrl-u, the
+// other navigation keys are handled by the viewer itself.
+func navigateViewer(viewer *tview.TextView, event *tcell.EventKey) bool {
+	_, _, _, height := viewer.GetInnerRect()
+	move, ok := selectionMove(event, height)
+	if !ok || (event.Key() != tcell.KeyCtrlD && event.Key() != tcell.KeyCtrlU) {
+		return false
+	}
+	row, column := viewer.GetScrollOffset()
+	row += move
+	if row < 0 {
+		row = 0
+	}
+	viewer.ScrollTo(row, column)
+	return true
+}
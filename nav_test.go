@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell"
+)
+
+func TestMoveRow(t *testing.T) {
+	char := func(r rune) *tcell.EventKey { return tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone) }
+	key := func(k tcell.Key) *tcell.EventKey { return tcell.NewEventKey(k, 0, tcell.ModNone) }
+
+	// 1 header row and 20 data rows, 10 rows visible
+	const count, height = 21, 10
+	tests := []struct {
+		event *tcell.EventKey
+		row   int
+		want  int
+	}{
+		{char('j'), 1, 2},
+		{key(tcell.KeyDown), 20, 20},
+		{char('k'), 2, 1},
+		{key(tcell.KeyUp), 1, 1},
+		{char('g'), 15, 1},
+		{char('G'), 3, 20},
+		{key(tcell.KeyCtrlD), 1, 6},
+		{key(tcell.KeyCtrlD), 18, 20},
+		{key(tcell.KeyCtrlU), 10, 5},
+		{key(tcell.KeyCtrlU), 3, 1},
+	}
+	for i, tt := range tests {
+		move, ok := selectionMove(tt.event, height)
+		if !ok {
+			t.Errorf("Test[%d]: %v is not a navigation key", i, tt.event.Name())
+			continue
+		}
+		if got := moveRow(tt.row, move, count); got != tt.want {
+			t.Errorf("Test[%d]: %v from row %d moves to %d, want %d", i, tt.event.Name(), tt.row, got, tt.want)
+		}
+	}
+
+	for _, event := range []*tcell.EventKey{char('/'), char('s'), key(tcell.KeyEnter)} {
+		if _, ok := selectionMove(event, height); ok {
+			t.Errorf("%v is a navigation key", event.Name())
+		}
+	}
+}
+
+func TestMoveColumn(t *testing.T) {
+	char := func(r rune) *tcell.EventKey { return tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone) }
+	key := func(k tcell.Key, mod tcell.ModMask) *tcell.EventKey { return tcell.NewEventKey(k, 0, mod) }
+
+	// 5 key and stat columns right of the rate
+	const columns = 5
+	tests := []struct {
+		event  *tcell.EventKey
+		offset int
+		want   int
+	}{
+		{char('l'), 0, 1},
+		{char('h'), 1, 0},
+		{char('h'), 0, 0},
+		{key(tcell.KeyRight, tcell.ModShift), 3, 4},
+		{key(tcell.KeyRight, tcell.ModNone), 4, 4},
+		{key(tcell.KeyLeft, tcell.ModShift), 2, 1},
+	}
+	for i, tt := range tests {
+		move, ok := columnMove(tt.event)
+		if !ok {
+			t.Errorf("Test[%d]: key doesn't scroll", i)
+			continue
+		}
+		if got := moveColumn(tt.offset, move, columns); got != tt.want {
+			t.Errorf("Test[%d]: moveColumn(%d, %d) = %d, want %d", i, tt.offset, move, got, tt.want)
+		}
+	}
+	if _, ok := columnMove(char('j')); ok {
+		t.Error("j scrolls the columns")
+	}
+}
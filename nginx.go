@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/satyrius/gonx"
+)
+
+// nginxLogFormat is a log_format read from an nginx config.
+type nginxLogFormat struct {
+	parser *gonx.Parser
+	fields []string
+}
+
+// nginxLog is loaded from -nginx-config for the nginx format.
+var nginxLog *nginxLogFormat
+
+var nginxVarRE = regexp.MustCompile(`\$([a-z_]+)`)
+
+// loadNginxFormat reads the log_format called name from an nginx config.
+func loadNginxFormat(conf []byte, name string) (*nginxLogFormat, error) {
+	parser, err := gonx.NewNginxParser(bytes.NewReader(conf), name)
+	if err != nil {
+		return nil, err
+	}
+
+	// gonx doesn't expose the field names of an entry, collect the variables
+	// of the log_format definition instead.
+	var fields []string
+	def := regexp.MustCompile(`(?s)log_format\s+` + regexp.QuoteMeta(name) + `\s+([^;]*);?`).FindSubmatch(conf)
+	if def != nil {
+		for _, m := range nginxVarRE.FindAllSubmatch(def[1], -1) {
+			fields = append(fields, string(m[1]))
+		}
+	}
+	return &nginxLogFormat{parser: parser, fields: fields}, nil
+}
+
+func loadNginxConfig(name, format string) (*nginxLogFormat, error) {
+	conf, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := loadNginxFormat(conf, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return f, nil
+}
+
+// nginxDecoder decodes access log lines written with an nginx log_format.
+type nginxDecoder struct {
+	*lineScanner
+	format *nginxLogFormat
+}
+
+func newNginxDecoder(r io.Reader, format *nginxLogFormat) *nginxDecoder {
+	return &nginxDecoder{
+		lineScanner: newLineScanner(r),
+		format:      format,
+	}
+}
+
+func (d *nginxDecoder) Decode() (map[string]interface{}, error) {
+	for {
+		d.Begin()
+		line, ok := d.Next()
+		if !ok {
+			if err := d.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		m, ok := d.parse(line)
+		if !ok {
+			invalidEntry(line)
+			continue
+		}
+		return m, nil
+	}
+}
+
+func (d *nginxDecoder) parse(line string) (map[string]interface{}, bool) {
+	entry, err := d.format.parser.ParseString(line)
+	if err != nil {
+		return nil, false
+	}
+	m := make(map[string]interface{}, len(d.format.fields))
+	for _, name := range d.format.fields {
+		if value, err := entry.Field(name); err == nil {
+			m[name] = toValue(value)
+		}
+	}
+	return m, true
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const nginxTestConf = `
+http {
+    log_format  main  '$remote_addr - $remote_user [$time_local] "$request" '
+                      '$status $body_bytes_sent "$http_referer" '
+                      '"$http_user_agent"';
+}
+`
+
+func TestNginxDecoder(t *testing.T) {
+	format, err := loadNginxFormat([]byte(nginxTestConf), "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `127.0.0.1 - frank [22/Aug/2024:09:00:06 +0000] "GET /api/counter HTTP/1.1" 502 157 "-" "curl/8.0"` + "\n" +
+		"not an access log line\n"
+	got := decodeAll(t, newNginxDecoder(strings.NewReader(input), format))
+
+	want := []map[string]interface{}{{
+		"remote_addr":     "127.0.0.1",
+		"remote_user":     "frank",
+		"time_local":      "22/Aug/2024:09:00:06 +0000",
+		"request":         "GET /api/counter HTTP/1.1",
+		"status":          json.Number("502"),
+		"body_bytes_sent": json.Number("157"),
+		"http_referer":    "-",
+		"http_user_agent": "curl/8.0",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// lookup returns the value of key in m. A key naming no field of m is read
+// as a path into the nested objects and arrays of the record, so columns and
+// -group-by fields reach them without -flatten:
+//
+//	meta.PlayerID   field PlayerID of the object meta
+//	tags[0]         first element of the array tags
+//	tags.0          the same, like -flatten names it
+//	a.b[1][0].c     any mix of the above
+//
+// Indices are zero-based and non-negative. Segments are taken literally,
+// there are no wildcards, filters, quoting or $ root, and a key read as
+// a path only splits at dots and brackets.
+func lookup(m map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	segments := parsePath(key)
+	if len(segments) < 2 {
+		return nil, false
+	}
+	var v interface{} = m
+	for _, segment := range segments {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = c[segment]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, false
+			}
+			v = c[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// lookupValue returns the value of key in m like lookup, nil if it has none.
+func lookupValue(m map[string]interface{}, key string) interface{} {
+	v, _ := lookup(m, key)
+	return v
+}
+
+// paths caches the segments of the keys parsed by parsePath.
+var paths sync.Map
+
+// parsePath splits key into the segments of a path, like meta, tags and 0
+// for meta.tags[0]. Keys which aren't a valid path, like a[x], a..b or
+// [0], give nil.
+func parsePath(key string) []string {
+	if segments, ok := paths.Load(key); ok {
+		return segments.([]string)
+	}
+	var segments []string
+	for _, part := range strings.Split(key, ".") {
+		name, indices, bracketed := strings.Cut(part, "[")
+		if name == "" {
+			segments = nil
+			break
+		}
+		segments = append(segments, name)
+		if !bracketed {
+			continue
+		}
+		for _, index := range strings.Split(indices, "[") {
+			n, closed := strings.CutSuffix(index, "]")
+			if !closed || !isIndex(n) {
+				segments = nil
+				break
+			}
+			segments = append(segments, n)
+		}
+		if segments == nil {
+			break
+		}
+	}
+	paths.Store(key, segments)
+	return segments
+}
+
+// isIndex reports whether s is a non-negative decimal array index.
+func isIndex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{"message", []string{"message"}},
+		{"meta.PlayerID", []string{"meta", "PlayerID"}},
+		{"tags[0]", []string{"tags", "0"}},
+		{"tags.0", []string{"tags", "0"}},
+		{"a.b[1][0].c", []string{"a", "b", "1", "0", "c"}},
+		{"a..b", nil},
+		{"[0]", nil},
+		{"a.[0]", nil},
+		{"a[x]", nil},
+		{"a[-1]", nil},
+		{"a[1", nil},
+	}
+	for i, tt := range tests {
+		if got := parsePath(tt.key); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Test[%d]: parsePath(%q) = %q, want %q", i, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"message": "m",
+		"meta": {"PlayerID": 7, "tags": ["a", {"id": "b"}]},
+		"tags": ["x", ["y", "z"]],
+		"meta.PlayerID": "flattened"
+	}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key  string
+		want interface{}
+		ok   bool
+	}{
+		{"message", "m", true},
+		{"meta.PlayerID", "flattened", true},
+		{"meta.tags[1].id", "b", true},
+		{"meta.tags.0", "a", true},
+		{"tags[1][1]", "z", true},
+		{"tags[2]", nil, false},
+		{"message.length", nil, false},
+		{"meta.missing", nil, false},
+		{"meta.tags.id", nil, false},
+		{"missing", nil, false},
+	}
+	for i, tt := range tests {
+		got, ok := lookup(m, tt.key)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Test[%d]: lookup(%q) = %v, %v, want %v, %v", i, tt.key, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestStoreGroupByPath(t *testing.T) {
+	s := NewStore(time.Second, defaultTrendBuckets, 1, []string{"user.name", "message"}, []string{"user.tags[0]"})
+	push := func(name, tag string) {
+		s.Push(map[string]interface{}{
+			"message": "login",
+			"user":    map[string]interface{}{"name": name, "tags": []interface{}{tag}},
+		})
+	}
+	push("ann", "admin")
+	push("bob", "guest")
+	push("cid", "admin")
+	if s.Len() != 2 {
+		t.Fatalf("%d groups by user.tags[0], want 2", s.Len())
+	}
+	if got := s.Get(0).Get("user.name"); got != "cid" || s.Get(0).count != 2 {
+		t.Errorf("admin group shows user.name %v with count %d, want cid and 2", got, s.Get(0).count)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// predicates must all hold for a record to be ingested, see -filter.
+var predicates []Predicate
+
+// predicateOps are tried in order, so the two character operators are found
+// before their prefixes.
+var predicateOps = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// Predicate compares a field of a record with a value, as numbers if both
+// are numeric and as strings otherwise.
+type Predicate struct {
+	key, op, value string
+}
+
+// parsePredicate parses a spec like `latency>500` or `status==500`. The
+// value may be quoted.
+func parsePredicate(spec string) (Predicate, error) {
+	for i := 0; i < len(spec); i++ {
+		for _, op := range predicateOps {
+			if !strings.HasPrefix(spec[i:], op) {
+				continue
+			}
+			key := strings.TrimSpace(spec[:i])
+			value := strings.TrimSpace(spec[i+len(op):])
+			if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+				value = value[1 : len(value)-1]
+			}
+			if key == "" {
+				return Predicate{}, fmt.Errorf("invalid filter %q, missing the field", spec)
+			}
+			return Predicate{key: key, op: op, value: value}, nil
+		}
+	}
+	return Predicate{}, fmt.Errorf("invalid filter %q, want field, one of %s and a value", spec, strings.Join(predicateOps, " "))
+}
+
+func parsePredicates(specs []string) ([]Predicate, error) {
+	preds := make([]Predicate, 0, len(specs))
+	for _, spec := range specs {
+		p, err := parsePredicate(spec)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, p)
+	}
+	return preds, nil
+}
+
+// match reports whether the field of value satisfies p. Records without the
+// field never do.
+func (p Predicate) match(value map[string]interface{}) bool {
+	v, ok := lookup(value, p.key)
+	if !ok || v == nil {
+		return false
+	}
+	var c int
+	x, xok := numeric(v)
+	y, yok := numeric(p.value)
+	if xok && yok {
+		switch {
+		case x < y:
+			c = -1
+		case x > y:
+			c = 1
+		}
+	} else {
+		c = strings.Compare(fmt.Sprintf("%v", v), p.value)
+	}
+	switch p.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	}
+	return c <= 0
+}
+
+// matchPredicates reports whether value satisfies all preds.
+func matchPredicates(preds []Predicate, value map[string]interface{}) bool {
+	for _, p := range preds {
+		if !p.match(value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParsePredicate(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Predicate
+		err  bool
+	}{
+		{"latency>500", Predicate{"latency", ">", "500"}, false},
+		{"status == 500", Predicate{"status", "==", "500"}, false},
+		{"latency>=1.5", Predicate{"latency", ">=", "1.5"}, false},
+		{`path!="/healthz"`, Predicate{"path", "!=", "/healthz"}, false},
+		{"level<=WARN", Predicate{"level", "<=", "WARN"}, false},
+		{"meta.retries<3", Predicate{"meta.retries", "<", "3"}, false},
+		{"==500", Predicate{}, true},
+		{"latency", Predicate{}, true},
+	}
+	for i, tt := range tests {
+		got, err := parsePredicate(tt.spec)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("Test[%d]: parsePredicate(%q) = %v, %v, want %v", i, tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestMatchPredicates(t *testing.T) {
+	preds, err := parsePredicates([]string{"latency>500", "status!=200"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value map[string]interface{}
+		want  bool
+	}{
+		{map[string]interface{}{"latency": json.Number("900"), "status": json.Number("500")}, true},
+		// numeric, not string order
+		{map[string]interface{}{"latency": json.Number("1000.5"), "status": "503"}, true},
+		{map[string]interface{}{"latency": json.Number("90"), "status": json.Number("500")}, false},
+		{map[string]interface{}{"latency": 501.0, "status": json.Number("200")}, false},
+		{map[string]interface{}{"status": json.Number("500")}, false},
+		{map[string]interface{}{"latency": "slow", "status": json.Number("500")}, true},
+	}
+	for i, tt := range tests {
+		if got := matchPredicates(preds, tt.value); got != tt.want {
+			t.Errorf("Test[%d]: matchPredicates(%v) = %v, want %v", i, tt.value, got, tt.want)
+		}
+	}
+	if !matchPredicates(nil, map[string]interface{}{}) {
+		t.Error("no filters drop a record")
+	}
+}
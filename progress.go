@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync/atomic"
+)
+
+// progress tracks how far the inputs were read, for the footer.
+var progress struct {
+	// read counts the bytes read from files of size bytes in total. size is
+	// 0 for stdin and followed files, whose end isn't known.
+	read atomic.Int64
+	size int64
+
+	// done is set once every input ended.
+	done atomic.Bool
+
+	// spin advances the spinner shown while reading inputs of unknown size.
+	spin int
+}
+
+var spinner = []string{"|", "/", "-", `\`}
+
+// countingReader counts the bytes read from an input in progress.read.
+type countingReader struct {
+	io.ReadCloser
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	progress.read.Add(int64(n))
+	return n, err
+}
+
+// progressText shows the percentage of the files read, or a spinner and the
+// number of records read from inputs of unknown size.
+func progressText() string {
+	records := consumed.Load()
+	switch {
+	case progress.done.Load():
+		return fmt.Sprintf("read: %d records, done", records)
+	case progress.size > 0:
+		percent := float64(progress.read.Load()) * 100 / float64(progress.size)
+		return fmt.Sprintf("read: %.0f%%", math.Min(percent, 100))
+	}
+	progress.spin = (progress.spin + 1) % len(spinner)
+	return fmt.Sprintf("read: %s %d records", spinner[progress.spin], records)
+}
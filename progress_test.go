@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func resetProgress() {
+	progress.size, progress.spin = 0, 0
+	progress.read.Store(0)
+	progress.done.Store(false)
+	consumed.Store(0)
+}
+
+func TestProgressText(t *testing.T) {
+	resetProgress()
+	defer resetProgress()
+
+	name := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(name, []byte("msg=a\nmsg=b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inputs, err := openInputs([]string{name}, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeInputs(inputs)
+
+	if got := progressText(); got != "read: 0%" {
+		t.Errorf("progressText() = %q before reading, want 0%%", got)
+	}
+	io.ReadFull(inputs[0], make([]byte, 6))
+	if got := progressText(); got != "read: 50%" {
+		t.Errorf("progressText() = %q after half the file, want 50%%", got)
+	}
+	io.ReadAll(inputs[0])
+	consumed.Store(2)
+	progress.done.Store(true)
+	if got := progressText(); got != "read: 2 records, done" {
+		t.Errorf("progressText() = %q after the end, want done", got)
+	}
+}
+
+func TestProgressTextUnknownSize(t *testing.T) {
+	resetProgress()
+	defer resetProgress()
+
+	// followed files have no known end
+	name := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(name, []byte("msg=a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inputs, err := openInputs([]string{name}, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeInputs(inputs)
+
+	consumed.Store(7)
+	first, second := progressText(), progressText()
+	if first != "read: / 7 records" || second != "read: - 7 records" {
+		t.Errorf("progressText() = %q, %q, want a turning spinner and 7 records", first, second)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// keptFields are the keys kept of every record with -fields, besides the
+// reserved ones, nil to keep all.
+var keptFields map[string]bool
+
+// parseKeptFields parses the comma separated -fields, nil if empty.
+func parseKeptFields(spec string) map[string]bool {
+	fields := splitFields(spec)
+	if len(fields) == 0 {
+		return nil
+	}
+	kept := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		kept[field] = true
+	}
+	return kept
+}
+
+// project deletes the fields of value not in kept, except the reserved keys
+// and the raw text, so wide records don't hold on to fields nobody looks at.
+func project(kept map[string]bool, value map[string]interface{}) {
+	if kept == nil {
+		return
+	}
+	for k := range value {
+		if !kept[k] && !reservedKeys[k] && k != rawKey {
+			delete(value, k)
+		}
+	}
+}
+
+// projectionNote tells the viewer which fields records keep with -fields,
+// "" without it.
+func projectionNote(kept map[string]bool) string {
+	if kept == nil {
+		return ""
+	}
+	fields := make([]string, 0, len(kept))
+	for k := range kept {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	return "dropped: the fields other than " + strings.Join(fields, ", ") + ", see -fields\n"
+}
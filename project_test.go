@@ -0,0 +1,36 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	value := map[string]interface{}{
+		"level":   "ERROR",
+		"message": "save failed",
+		"user":    "1",
+		"trace":   "abc",
+		"host":    "db1",
+		rawKey:    `{"level": "ERROR"}`,
+	}
+	project(nil, value)
+	if len(value) != 6 {
+		t.Errorf("projected %v without -fields, want every field", value)
+	}
+
+	project(parseKeptFields(" user, host "), value)
+	want := map[string]interface{}{
+		"level":   "ERROR",
+		"message": "save failed",
+		"user":    "1",
+		"host":    "db1",
+		rawKey:    `{"level": "ERROR"}`,
+	}
+	if !reflect.DeepEqual(value, want) {
+		t.Errorf("projected %v, want %v", value, want)
+	}
+	if parseKeptFields(" , ") != nil {
+		t.Error("parsed kept fields from a blank -fields")
+	}
+}
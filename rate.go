@@ -0,0 +1,87 @@
+package main
+
+import "time"
+
+// rateBuckets is the resolution of the counting window when it differs
+// from the trend, its counts age out a tenth of the window at a time.
+const rateBuckets = 10
+
+// SetWindow sets the span rates and alerts count entries over, the trend
+// duration if 0. A window other than the trend keeps its own counts per
+// row, aged by ShiftWindow, while the trend only drives the sparkline.
+func (s *Store) SetWindow(window time.Duration) {
+	if window == s.duration {
+		window = 0
+	}
+	s.window = window
+	for i := range s.rows {
+		s.rows[i].window = nil
+	}
+}
+
+// Window returns the span rates and alerts count entries over.
+func (s *Store) Window() time.Duration {
+	if s.window == 0 {
+		return s.duration
+	}
+	return s.window
+}
+
+// windowCounts returns the counts of row within the window, oldest first.
+func (s *Store) windowCounts(row *RowData) []float64 {
+	if s.window == 0 {
+		return row.trend
+	}
+	return row.window
+}
+
+// windowCount returns the number of entries of row within the window.
+func (s *Store) windowCount(row *RowData) float64 {
+	var n float64
+	for _, x := range s.windowCounts(row) {
+		n += x
+	}
+	return n
+}
+
+// pushWindow counts an entry of row in its window.
+func (s *Store) pushWindow(row *RowData) {
+	if s.window == 0 {
+		return
+	}
+	if row.window == nil {
+		row.window = make([]float64, rateBuckets)
+	}
+	row.window[rateBuckets-1] += float64(s.weight)
+}
+
+// ShiftWindow ages the counts of the window by a bucket, to be called every
+// Window() / rateBuckets. It does nothing while the window is the trend,
+// which Shift ages.
+func (s *Store) ShiftWindow() {
+	if s.window == 0 {
+		return
+	}
+	now := time.Now()
+	for i := range s.rows {
+		shiftTrend(s.rows[i].window)
+		s.checkAlerts(&s.rows[i], now)
+		s.touch(&s.rows[i])
+	}
+}
+
+// shiftWindow ages the window of the store like shift does the trend, if it
+// has a window of its own.
+func shiftWindow(window time.Duration) {
+	if window == duration {
+		return
+	}
+	for {
+		if !paused.Load() {
+			store.Lock()
+			store.ShiftWindow()
+			store.Unlock()
+		}
+		time.Sleep(window / rateBuckets)
+	}
+}
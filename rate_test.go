@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStoreWindow(t *testing.T) {
+	alerts, err := parseAlerts([]string{"count>3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(10*time.Second, 2, 1, []string{"message"}, nil)
+	s.SetWindow(2 * time.Second)
+	s.SetAlerts(alerts)
+	for i := 0; i < 4; i++ {
+		s.Push(map[string]interface{}{"message": "a"})
+	}
+	if got := s.GroupRate(0); got != 2 {
+		t.Errorf("GroupRate(0) = %v over the window, want 2", got)
+	}
+	if !s.Get(0).GetAlerting() {
+		t.Error("the row isn't alerting above the threshold within the window")
+	}
+
+	s.Shift()
+	s.Shift()
+	if got := s.GroupRate(0); got != 2 {
+		t.Errorf("GroupRate(0) = %v after the trend moved on, want 2", got)
+	}
+	for i := 0; i < rateBuckets; i++ {
+		s.ShiftWindow()
+	}
+	if got := s.GroupRate(0); got != 0 {
+		t.Errorf("GroupRate(0) = %v after the window moved on, want 0", got)
+	}
+	if s.Get(0).GetAlerting() {
+		t.Error("the row still alerts after its entries left the window")
+	}
+
+	s.Push(map[string]interface{}{"message": "a"})
+	if got := s.Get(0).GetTrendBuckets(); got[0] != 0 || got[1] != 1 {
+		t.Errorf("trend %v, want [0 1] independent of the window", got)
+	}
+
+	s.SetWindow(10 * time.Second)
+	if s.Window() != 10*time.Second || s.GroupRate(0) != 0.1 {
+		t.Errorf("window %v with rate %v, want the trend's 10s and 0.1", s.Window(), s.GroupRate(0))
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// panics counts the panics recovered while reading and rendering records.
+var panics atomic.Int64
+
+// recovered runs fn, turning a panic into a logged error and a status line
+// message about what, and reports whether fn returned normally. A bad record
+// then only loses itself instead of the UI and the terminal.
+func recovered(what string, fn func()) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panics.Add(1)
+			log.Printf("error: %s failed: %v\n%s", what, r, debug.Stack())
+			msg := fmt.Sprintf("%s failed: %v, see the log", what, r)
+			if app != nil {
+				// The status is rendered by the UI goroutine.
+				go app.QueueUpdate(func() { showMessage("%s", msg) })
+			}
+			ok = false
+		}
+	}()
+	fn()
+	return true
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+// panicDecoder yields its records in order, panicking on nil ones.
+type panicDecoder struct {
+	records []map[string]interface{}
+}
+
+func (d *panicDecoder) More() bool {
+	return len(d.records) > 0
+}
+
+func (d *panicDecoder) Decode() (map[string]interface{}, error) {
+	if len(d.records) == 0 {
+		return nil, io.EOF
+	}
+	m := d.records[0]
+	d.records = d.records[1:]
+	if m == nil {
+		panic("bad record")
+	}
+	return m, nil
+}
+
+func TestConsumeRecovers(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	keys = []string{"message"}
+	store = NewStore(time.Second, defaultTrendBuckets, 3, keys, nil)
+	defer func() { keys, store = nil, nil }()
+
+	before := panics.Load()
+	dec := &panicDecoder{records: []map[string]interface{}{
+		{"message": "a"},
+		nil,
+		{"message": "disk full"},
+	}}
+	if err := consume(dec); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.Total(); got != 2 {
+		t.Errorf("%d entries grouped, want 2", got)
+	}
+	if n := panics.Load() - before; n != 1 {
+		t.Errorf("%d panics recovered, want 1", n)
+	}
+}
+
+func TestRecovered(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	if !recovered("test", func() {}) {
+		t.Error("recovered() = false without a panic")
+	}
+	if recovered("test", func() { panic("boom") }) {
+		t.Error("recovered() = true after a panic")
+	}
+}
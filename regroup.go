@@ -0,0 +1,88 @@
+package main
+
+import "time"
+
+// SetDistance changes the distance within which entries combine and groups
+// the rows again with it. Rows only merge: the store doesn't keep every
+// entry, so a lower distance can't split the groups combined already and
+// applies to new entries. Masked rows combine by exact match regardless.
+// Rows stay apart without SetCombine.
+func (s *Store) SetDistance(distance float64) {
+	s.distance = distance
+	if s.separate {
+		return
+	}
+	now := time.Now()
+	rows := s.rows
+	s.rows = make([]RowData, 0, len(rows))
+	s.exact = make(map[string]int, len(s.exact))
+	s.byLen = make(map[int][]int, len(s.byLen))
+	for _, row := range rows {
+		if j := s.find(row.key, row.masked); j >= 0 {
+			s.merge(&s.rows[j], row)
+			s.checkAlerts(&s.rows[j], now)
+			s.touch(&s.rows[j])
+			continue
+		}
+		if row.masked {
+			s.exact[exactKey(row.key)] = len(s.rows)
+		}
+		s.touch(&row)
+		s.rows = append(s.rows, row)
+		s.index(len(s.rows) - 1)
+	}
+}
+
+// GetDistance returns the distance within which entries combine.
+func (s *Store) GetDistance() float64 {
+	return s.distance
+}
+
+// merge adds row b, which was seen after a first, to row a.
+func (s *Store) merge(a *RowData, b RowData) {
+	a.count += b.count
+	addTrend(a.trend, b.trend)
+	if b.window != nil && a.window == nil {
+		a.window = make([]float64, len(b.window))
+	}
+	addTrend(a.window, b.window)
+	if b.levelTrend != nil && a.levelTrend == nil {
+		a.levelTrend = make([][]float64, len(b.levelTrend))
+	}
+	for rank, trend := range b.levelTrend {
+		if trend == nil {
+			continue
+		}
+		if a.levelTrend[rank] == nil {
+			a.levelTrend[rank] = make([]float64, len(trend))
+		}
+		addTrend(a.levelTrend[rank], trend)
+	}
+	if len(a.stats) < len(b.stats) {
+		a.stats = append(a.stats, make([]aggregate, len(b.stats)-len(a.stats))...)
+	}
+	for i := range b.stats {
+		a.stats[i].merge(b.stats[i])
+	}
+
+	samples := append(append([]map[string]interface{}{}, a.samples...), b.samples...)
+	if b.updated.After(a.updated) {
+		a.data = b.data
+		a.updated = b.updated
+	} else {
+		samples = append(append([]map[string]interface{}{}, b.samples...), a.samples...)
+	}
+	a.samples = samples[max(len(samples)-s.keepSamples, 0):]
+	if !b.firstSeen.IsZero() {
+		a.seen(b.firstSeen)
+		a.seen(b.lastSeen)
+	}
+}
+
+func addTrend(a, b []float64) {
+	for i := range a {
+		if i < len(b) {
+			a[i] += b[i]
+		}
+	}
+}
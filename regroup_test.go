@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestStoreSetDistance(t *testing.T) {
+	s := NewStore(time.Second, defaultTrendBuckets, 1, []string{"message"}, nil)
+	stats, err := parseStats([]string{"ms:max"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetStats(stats)
+	push := func(message string, ms int) {
+		s.Push(map[string]interface{}{"message": message, "ms": json.Number(strconv.Itoa(ms))})
+	}
+	push("user 1 not found", 10)
+	push("disk full", 5)
+	push("user 2 not found", 30)
+	push("user 1 not found", 20)
+	if s.Len() != 3 {
+		t.Fatalf("%d groups at distance 1, want 3", s.Len())
+	}
+
+	s.SetDistance(2)
+	if s.Len() != 2 {
+		t.Fatalf("%d groups at distance 2, want 2", s.Len())
+	}
+	users, disk := s.Get(0), s.Get(1)
+	if users.count != 3 || users.GetTrendBuckets()[defaultTrendBuckets-1] != 3 {
+		t.Errorf("merged group counts %d with trend %v, want 3", users.count, users.GetTrendBuckets())
+	}
+	if got := users.GetStat(0, s.stats[0]); got != "30" {
+		t.Errorf("merged max ms = %s, want 30", got)
+	}
+	if users.GetFirst()["message"] != "user 1 not found" || users.Get("message") != "user 1 not found" {
+		t.Errorf("merged group first %v, latest %v", users.GetFirst(), users.GetData())
+	}
+	if disk.count != 1 {
+		t.Errorf("disk full counts %d, want 1", disk.count)
+	}
+	if users.GetVersion() == disk.GetVersion() {
+		t.Error("regrouped rows share a version")
+	}
+
+	// lower distances keep the groups and apply to new entries
+	s.SetDistance(1)
+	push("user 3 not found", 1)
+	if s.Len() != 3 || s.Get(0).count != 3 {
+		t.Errorf("%d groups after lowering the distance, want 3 with the merged one kept", s.Len())
+	}
+}
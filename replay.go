@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// replay paces the records of -replay, nil reads them as fast as possible.
+var replay *replayer
+
+// replayer delays records so they are ingested with the gaps between their
+// datetimes, divided by speed.
+type replayer struct {
+	speed float64
+
+	// first is the datetime of the first record, replayed at start.
+	first, start time.Time
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func newReplayer(speed float64) *replayer {
+	return &replayer{speed: speed, now: time.Now, sleep: time.Sleep}
+}
+
+// parseSpeed parses a -replay-speed like 2x, 0.5x or 10.
+func parseSpeed(s string) (float64, error) {
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+	if err != nil || speed <= 0 {
+		return 0, fmt.Errorf("invalid -replay-speed %q, want a positive factor like 2x", s)
+	}
+	return speed, nil
+}
+
+// wait sleeps until value is due. Records without a parsable datetime, or
+// older than the ones already replayed, aren't delayed.
+func (r *replayer) wait(value map[string]interface{}) {
+	t, ok := recordTime(value)
+	if !ok {
+		return
+	}
+	if r.first.IsZero() {
+		r.first, r.start = t, r.now()
+		return
+	}
+	due := r.start.Add(time.Duration(float64(t.Sub(r.first)) / r.speed))
+	if d := due.Sub(r.now()); d > 0 {
+		r.sleep(d)
+	}
+}
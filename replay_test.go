@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSpeed(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+		err  bool
+	}{
+		{"1x", 1, false},
+		{"2x", 2, false},
+		{"0.5x", 0.5, false},
+		{"10", 10, false},
+		{"0x", 0, true},
+		{"fast", 0, true},
+	}
+	for i, tt := range tests {
+		got, err := parseSpeed(tt.s)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("Test[%d]: parseSpeed(%q) = %g, %v, want %g", i, tt.s, got, err, tt.want)
+		}
+	}
+}
+
+func TestReplayerWait(t *testing.T) {
+	now := time.Date(2024, 8, 22, 12, 0, 0, 0, time.UTC)
+	var slept []time.Duration
+	r := newReplayer(2)
+	r.now = func() time.Time { return now }
+	r.sleep = func(d time.Duration) {
+		slept = append(slept, d)
+		now = now.Add(d)
+	}
+
+	for _, datetime := range []string{
+		"2024-08-22T09:00:00Z",
+		"2024-08-22T09:00:04Z",
+		"not a time",
+		"2024-08-22T09:00:03Z", // out of order, due already
+		"2024-08-22T09:00:20Z",
+	} {
+		r.wait(map[string]interface{}{"datetime": datetime})
+		// ingesting takes a second
+		now = now.Add(time.Second)
+	}
+	want := []time.Duration{time.Second, 5 * time.Second}
+	if len(slept) != len(want) || slept[0] != want[0] || slept[1] != want[1] {
+		t.Errorf("slept %v, want %v at double speed", slept, want)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// reservedKeys are the keys decoders fill from the structure of a record,
+// the segments of a zaplog line or the fields red relies on, like level.
+var reservedKeys = map[string]bool{
+	"datetime":   true,
+	"level":      true,
+	"position":   true,
+	"func":       true,
+	"message":    true,
+	"stacktrace": true,
+}
+
+// origSuffix marks the value a record carried itself under a reserved key
+// when the decoder fills the key, e.g. message_orig.
+const origSuffix = "_orig"
+
+// collisions holds the reserved keys whose collisions were logged, so
+// each is logged once.
+var collisions sync.Map
+
+// origKey returns the key a record's own value of the reserved key is kept
+// under, logging the first collision of every key.
+func origKey(key string) string {
+	if _, logged := collisions.LoadOrStore(key, true); !logged {
+		log.Printf("warn: a record has its own %s field, it is kept as %s", key, key+origSuffix)
+	}
+	return key + origSuffix
+}
+
+// keepDuplicates restores the first value of every reserved key which occurs
+// more than once in the JSON object raw, decoded into m. encoding/json keeps
+// the last one, which for a logger appending fields after its own level or
+// message is the field rather than the logger's. The last value is kept
+// under origKey.
+func keepDuplicates(raw []byte, m map[string]interface{}) {
+	duplicated := false
+	for key := range reservedKeys {
+		if bytes.Count(raw, []byte(`"`+key+`"`)) > 1 {
+			duplicated = true
+			break
+		}
+	}
+	if !duplicated {
+		return
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if _, err := dec.Token(); err != nil {
+		return
+	}
+	first := map[string]interface{}{}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return
+		}
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return
+		}
+		key, _ := t.(string)
+		if !reservedKeys[key] {
+			continue
+		}
+		if _, ok := first[key]; !ok {
+			first[key] = v
+			continue
+		}
+		m[key] = first[key]
+		m[origKey(key)] = v
+	}
+}
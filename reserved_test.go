@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeepDuplicates(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want map[string]interface{}
+	}{
+		{
+			`{"level": "ERROR", "message": "save failed", "level": "custom", "n": 1}`,
+			map[string]interface{}{"level": "ERROR", "level_orig": "custom", "message": "save failed", "n": json.Number("1")},
+		},
+		{
+			`{"message": "a \"level\" in the \"level\" text", "level": "INFO"}`,
+			map[string]interface{}{"message": `a "level" in the "level" text`, "level": "INFO"},
+		},
+		{
+			`{"meta": {"level": 1}, "level": "WARN"}`,
+			map[string]interface{}{"meta": map[string]interface{}{"level": json.Number("1")}, "level": "WARN"},
+		},
+	}
+	for i, tt := range tests {
+		got := decodeAll(t, newJsonDecoder(strings.NewReader(tt.raw)))
+		if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+			t.Errorf("Test[%d]: decoded %v, want %v", i, got, tt.want)
+		}
+		got = decodeAll(t, newJsonLinesDecoder(strings.NewReader(tt.raw), nil))
+		if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+			t.Errorf("Test[%d]: decoded json lines %v, want %v", i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+)
+
+var (
+	searchInput  *tview.InputField
+	searching    bool
+	searchQuery  string
+	searchRegexp *regexp.Regexp
+
+	// lastSearch is the query the search input starts with, the last one
+	// even if Esc cleared it.
+	lastSearch string
+)
+
+// Prefixes of a search query switching its mode, in any order, e.g.
+// case:re:^user \d+.
+const (
+	regexPrefix = "re:"
+	casePrefix  = "case:"
+)
+
+func newSearchInput() *tview.InputField {
+	return tview.NewInputField().
+		SetLabel("/").
+		SetFieldBackgroundColor(tcell.ColorDefault).
+		SetChangedFunc(setSearch).
+		SetDoneFunc(func(key tcell.Key) {
+			lastSearch = searchInput.GetText()
+			if key == tcell.KeyEscape {
+				setSearch("")
+			}
+			closeSearch()
+		})
+}
+
+// openSearch shows the search input below the table and focuses it, with the
+// last query to edit.
+func openSearch() {
+	searching = true
+	searchInput.SetText(lastSearch)
+	root.AddItem(searchInput, 1, 0, true)
+	app.SetFocus(searchInput)
+}
+
+func closeSearch() {
+	searching = false
+	root.RemoveItem(searchInput)
+	app.SetFocus(table)
+}
+
+// setSearch filters the table by query. An invalid regular expression
+// keeps the previous filter and shows the error next to the input.
+func setSearch(query string) {
+	re, err := parseSearch(query)
+	if searchInput != nil {
+		label := "/"
+		if err != nil {
+			label = "/ (" + err.Error() + ") "
+		}
+		searchInput.SetLabel(label)
+	}
+	if err != nil {
+		return
+	}
+	searchQuery = query
+	searchRegexp = re
+	storeFilter()
+}
+
+// parseSearch compiles query, nil if empty. The query is a literal text
+// matched case-insensitively, unless prefixed by re: for a regular
+// expression or case: to match case-sensitively.
+func parseSearch(query string) (*regexp.Regexp, error) {
+	regex, caseSensitive := false, false
+	for {
+		if rest, ok := strings.CutPrefix(query, regexPrefix); ok && !regex {
+			query, regex = rest, true
+		} else if rest, ok := strings.CutPrefix(query, casePrefix); ok && !caseSensitive {
+			query, caseSensitive = rest, true
+		} else {
+			break
+		}
+	}
+	if query == "" {
+		return nil, nil
+	}
+	if !regex {
+		query = regexp.QuoteMeta(query)
+	}
+	if !caseSensitive {
+		query = "(?i)" + query
+	}
+	re, err := regexp.Compile(query)
+	if err != nil {
+		return nil, errors.New("invalid regex")
+	}
+	return re, nil
+}
+
+// matchSearch reports whether the message or any key column of the row
+// matches the search query.
+func matchSearch(data RowData) bool {
+	if searchRegexp == nil {
+		return true
+	}
+	if searchRegexp.MatchString(formatValue(data.Get("message"))) {
+		return true
+	}
+	for _, key := range keys {
+		if searchRegexp.MatchString(formatValue(data.Get(key))) {
+			return true
+		}
+	}
+	return false
+}
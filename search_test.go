@@ -0,0 +1,83 @@
+package main
+
+import "testing"
+
+func TestHighlightCellSearch(t *testing.T) {
+	defer setSearch("")
+
+	tests := []struct {
+		query, text, want string
+	}{
+		{"", "[GetCounterBatch] done", "[GetCounterBatch[] done"},
+		{"counter", "empty Counter list", "empty [black:yellow]Counter[-:-] list"},
+		{"o", "foo", "f[black:yellow]o[-:-][black:yellow]o[-:-]"},
+		{"x", "foo", "foo"},
+	}
+	for i, tt := range tests {
+		setSearch(tt.query)
+		if got := highlightCell("level", tt.text); got != tt.want {
+			t.Errorf("Test[%d]: highlightCell(%q) with query %q = %q, want %q", i, tt.text, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMatchSearch(t *testing.T) {
+	defer setSearch("")
+	keys = []string{"level"}
+	defer func() { keys = nil }()
+
+	data := RowData{data: map[string]interface{}{"level": "ERROR", "message": "empty counter list"}}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"COUNTER", true},
+		{"error", true},
+		{"warn", false},
+	}
+	for _, tt := range tests {
+		setSearch(tt.query)
+		if got := matchSearch(data); got != tt.want {
+			t.Errorf("matchSearch with query %q = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseSearch(t *testing.T) {
+	tests := []struct {
+		query string
+		text  string
+		match bool
+		err   bool
+	}{
+		{"counter", "empty Counter list", true, false},
+		{"c.unter", "empty counter list", false, false},
+		{"re:c.unter", "empty Counter list", true, false},
+		{"case:counter", "empty Counter list", false, false},
+		{"case:Counter", "empty Counter list", true, false},
+		{"re:case:^empty \\w+", "empty counter list", true, false},
+		{"case:re:^Empty", "empty counter list", false, false},
+		{"re:re:", "re:", true, false},
+		{"re:(", "", false, true},
+	}
+	for i, tt := range tests {
+		re, err := parseSearch(tt.query)
+		if (err != nil) != tt.err {
+			t.Errorf("Test[%d]: parseSearch(%q) error = %v, want error %v", i, tt.query, err, tt.err)
+			continue
+		}
+		if err == nil && re.MatchString(tt.text) != tt.match {
+			t.Errorf("Test[%d]: parseSearch(%q) matches %q = %v, want %v", i, tt.query, tt.text, !tt.match, tt.match)
+		}
+	}
+}
+
+func TestSetSearchInvalidRegex(t *testing.T) {
+	defer setSearch("")
+	setSearch("re:count(er)?")
+	setSearch("re:count(er")
+	if searchQuery != "re:count(er)?" || searchRegexp == nil || !searchRegexp.MatchString("count") {
+		t.Errorf("invalid regex replaced the search, query %q", searchQuery)
+	}
+}
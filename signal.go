@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// stopSignals stop the app like q, restoring the terminal and saving the
+// table.
+var stopSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT}
+
+// stopOnSignal calls stop on the first signal received from signals, then
+// waits for stopped to be closed once the app returned. It reports whether
+// red has to exit right away instead, on a second signal or if stopped isn't
+// closed within timeout.
+func stopOnSignal(signals <-chan os.Signal, stop func(), stopped <-chan struct{}, timeout time.Duration) bool {
+	<-signals
+	stop()
+	select {
+	case <-stopped:
+		return false
+	case <-signals:
+	case <-time.After(timeout):
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStopOnSignal(t *testing.T) {
+	tests := []struct {
+		second bool
+		stops  bool
+		want   bool
+	}{
+		{false, true, false},
+		{true, false, true},
+		{false, false, true},
+	}
+	for i, tt := range tests {
+		signals := make(chan os.Signal, 2)
+		stopped := make(chan struct{})
+		stops := 0
+		stop := func() {
+			stops++
+			if tt.stops {
+				close(stopped)
+			}
+		}
+		signals <- syscall.SIGTERM
+		if tt.second {
+			signals <- syscall.SIGINT
+		}
+		if got := stopOnSignal(signals, stop, stopped, 50*time.Millisecond); got != tt.want || stops != 1 {
+			t.Errorf("Test[%d]: stopOnSignal() = %v with %d stops, want %v with 1", i, got, stops, tt.want)
+		}
+	}
+}
+
+func TestSignalStopsAndFlushes(t *testing.T) {
+	keys = []string{"message"}
+	store = NewStore(time.Second, defaultTrendBuckets, 3, keys, nil)
+	dumpFile = filepath.Join(t.TempDir(), "red.json")
+	updates = newBatcher(time.Hour)
+	defer func() { keys, store, dumpFile, updates = nil, nil, "", nil }()
+	go updates.run()
+	updates.add(map[string]interface{}{"message": "pending"})
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, stopSignals...)
+	defer signal.Stop(signals)
+
+	// running stands in for app.Run, which returns once stopped.
+	running, stopped := make(chan struct{}), make(chan struct{})
+	forced := make(chan bool, 1)
+	go func() { forced <- stopOnSignal(signals, func() { close(running) }, stopped, 5*time.Second) }()
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGTERM); err != nil {
+		t.Skip("can't signal the test process:", err)
+	}
+	select {
+	case <-running:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGTERM didn't stop the app")
+	}
+	close(stopped)
+	flushOutput()
+
+	if <-forced {
+		t.Error("stopping in time forced an exit")
+	}
+	b, err := os.ReadFile(dumpFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "pending") {
+		t.Errorf("-dump-json file lacks the batched entry:\n%s", b)
+	}
+}
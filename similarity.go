@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Similarity is the metric deciding whether the keys of two entries are close
+// enough to combine, see -similarity.
+type Similarity int
+
+const (
+	// Levenshtein counts the tokens inserted, deleted or replaced, -distance
+	// is the number of edits entries must stay below.
+	Levenshtein Similarity = iota
+	// Jaccard compares the sets of tokens regardless of their order,
+	// -distance is the fraction of distinct tokens not shared.
+	Jaccard
+)
+
+// defaultJaccardDistance is used for jaccard if -distance isn't given, its
+// default being meant for levenshtein.
+const defaultJaccardDistance = 0.5
+
+func (m Similarity) String() string {
+	if m == Jaccard {
+		return "jaccard"
+	}
+	return "levenshtein"
+}
+
+func parseSimilarity(name string) (Similarity, error) {
+	switch name {
+	case "levenshtein":
+		return Levenshtein, nil
+	case "jaccard":
+		return Jaccard, nil
+	}
+	return 0, fmt.Errorf("-similarity %q: want levenshtein or jaccard", name)
+}
+
+// checkDistance reports an error if distance isn't a whole number of edits
+// for levenshtein or a fraction between 0 and 1 for jaccard.
+func (m Similarity) checkDistance(distance float64) error {
+	if m == Jaccard {
+		if distance < 0 || distance > 1 {
+			return fmt.Errorf("-distance %g: jaccard wants a distance between 0 and 1", distance)
+		}
+		return nil
+	}
+	if distance < 0 || distance != math.Trunc(distance) {
+		return fmt.Errorf("-distance %g: levenshtein wants a whole number of edits", distance)
+	}
+	return nil
+}
+
+// step returns the change of the distance by + and - in the table.
+func (m Similarity) step() float64 {
+	if m == Jaccard {
+		return 0.1
+	}
+	return 1
+}
+
+// adjust returns distance changed by n steps, kept valid for m.
+func (m Similarity) adjust(distance float64, n int) float64 {
+	distance += float64(n) * m.step()
+	if m == Jaccard {
+		// Round away the error of adding tenths.
+		distance = math.Min(math.Round(distance*10)/10, 1)
+	}
+	return math.Max(distance, 0)
+}
+
+// JaccardDistance returns the fraction of the distinct tokens of a and b that
+// aren't in both, 0 for equal sets and 1 for disjoint ones.
+func JaccardDistance(a, b []string) float64 {
+	set := make(map[string]bool, len(a))
+	for _, t := range a {
+		set[t] = true
+	}
+	union, common := len(set), 0
+	seen := make(map[string]bool, len(b))
+	for _, t := range b {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		if set[t] {
+			common++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return 1 - float64(common)/float64(union)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJaccardDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 0},
+		{"a b c", "a b c", 0},
+		{"a b c", "c b a", 0},
+		{"a b", "c d", 1},
+		{"a b c", "a b d", 0.5},
+		{"a a b", "a b b", 0},
+		{"user 1 not found", "user 2 not found", 0.4},
+	}
+	for i, tt := range tests {
+		if got := JaccardDistance(strings.Fields(tt.a), strings.Fields(tt.b)); got != tt.want {
+			t.Errorf("Test[%d]: JaccardDistance(%q, %q) = %g, want %g", i, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarityDistance(t *testing.T) {
+	tests := []struct {
+		similarity Similarity
+		distance   float64
+		err        bool
+	}{
+		{Levenshtein, 3, false},
+		{Levenshtein, 0, false},
+		{Levenshtein, 0.5, true},
+		{Levenshtein, -1, true},
+		{Jaccard, 0.5, false},
+		{Jaccard, 1, false},
+		{Jaccard, 3, true},
+	}
+	for i, tt := range tests {
+		if err := tt.similarity.checkDistance(tt.distance); (err != nil) != tt.err {
+			t.Errorf("Test[%d]: %v distance %g error = %v, want error %v", i, tt.similarity, tt.distance, err, tt.err)
+		}
+	}
+
+	d := 0.0
+	for i := 0; i < 3; i++ {
+		d = Jaccard.adjust(d, 1)
+	}
+	if d != 0.3 {
+		t.Errorf("three jaccard steps from 0 = %g, want 0.3", d)
+	}
+	if d := Jaccard.adjust(0.95, 1); d != 1 {
+		t.Errorf("jaccard step above 1 = %g, want 1", d)
+	}
+	if d := Levenshtein.adjust(0, -1); d != 0 {
+		t.Errorf("levenshtein step below 0 = %g, want 0", d)
+	}
+}
+
+func TestStoreSimilarity(t *testing.T) {
+	messages := []string{
+		"user 17 logged in from 10.0.0.1",
+		"user 4 logged in from 10.0.0.9",
+		"connection to db-1 timed out after 30s",
+		"timed out after 30s: connection to db-1",
+		"cache miss for key session:17",
+	}
+	tests := []struct {
+		similarity Similarity
+		distance   float64
+		groups     []int
+	}{
+		// The reordered timeout differs in every position for levenshtein.
+		{Levenshtein, 3, []int{2, 1, 1, 1}},
+		// Jaccard ignores the order, "30s" and "30s:" being the only difference
+		// of the timeouts, while the logins differ in 6 of 10 tokens.
+		{Jaccard, 0.7, []int{2, 2, 1}},
+		{Jaccard, 0.3, []int{1, 1, 2, 1}},
+		{Jaccard, 0.1, []int{1, 1, 1, 1, 1}},
+	}
+	for i, tt := range tests {
+		s := NewStore(time.Second, defaultTrendBuckets, tt.distance, []string{"message"}, nil)
+		s.SetSimilarity(tt.similarity)
+		for _, message := range messages {
+			s.Push(map[string]interface{}{"message": message})
+		}
+		var groups []int
+		for j := 0; j < s.Len(); j++ {
+			groups = append(groups, s.Get(j).count)
+		}
+		if !reflect.DeepEqual(groups, tt.groups) {
+			t.Errorf("Test[%d]: %v distance %g grouped counts %v, want %v", i, tt.similarity, tt.distance, groups, tt.groups)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// snapshotVersion is bumped whenever the snapshot layout changes
+// incompatibly.
+const snapshotVersion = 1
+
+type snapshot struct {
+	Version int           `json:"version"`
+	Keys    []string      `json:"keys"`
+	Total   int           `json:"total"`
+	Rows    []snapshotRow `json:"rows"`
+}
+
+type snapshotRow struct {
+	Key     [][]string             `json:"key"`
+	Masked  bool                   `json:"masked,omitempty"`
+	Trend   []float64              `json:"trend"`
+	Count   int                    `json:"count"`
+	Data    map[string]interface{} `json:"data"`
+	Updated time.Time              `json:"updated"`
+
+	// FirstSeen and LastSeen are zero, First and Samples missing in
+	// snapshots of older versions of red.
+	FirstSeen time.Time                `json:"first_seen"`
+	LastSeen  time.Time                `json:"last_seen"`
+	First     map[string]interface{}   `json:"first,omitempty"`
+	Samples   []map[string]interface{} `json:"samples,omitempty"`
+}
+
+// Save writes the grouped rows, their counts, trends and the keys as JSON.
+func (s *Store) Save(w io.Writer) error {
+	snap := snapshot{
+		Version: snapshotVersion,
+		Keys:    s.keys,
+		Total:   s.total,
+		Rows:    make([]snapshotRow, len(s.rows)),
+	}
+	for i, row := range s.rows {
+		snap.Rows[i] = snapshotRow{
+			Key:       row.key,
+			Masked:    row.masked,
+			Trend:     row.trend,
+			Count:     row.count,
+			Data:      row.data,
+			Updated:   row.updated,
+			FirstSeen: row.firstSeen,
+			LastSeen:  row.lastSeen,
+			First:     row.first,
+			Samples:   row.samples,
+		}
+	}
+	return json.NewEncoder(w).Encode(snap)
+}
+
+// Load replaces the store content by a snapshot written by Save. Snapshots of
+// another version or trend resolution are rejected.
+func (s *Store) Load(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var snap snapshot
+	if err := dec.Decode(&snap); err != nil {
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("incompatible snapshot version %d, want %d", snap.Version, snapshotVersion)
+	}
+
+	rows := make([]RowData, len(snap.Rows))
+	exact := make(map[string]int)
+	fields := make(map[string]bool)
+	for i, row := range snap.Rows {
+		if len(row.Trend) != s.buckets {
+			return fmt.Errorf("incompatible snapshot with %d trend buckets, want %d", len(row.Trend), s.buckets)
+		}
+		rows[i] = RowData{
+			key:     row.Key,
+			trend:   row.Trend,
+			count:   row.Count,
+			data:    row.Data,
+			masked:  row.Masked,
+			updated: row.Updated.Local(),
+			first:   row.First,
+			samples: row.Samples,
+		}
+		if rows[i].first == nil {
+			rows[i].first = row.Data
+		}
+		if len(rows[i].samples) == 0 {
+			rows[i].samples = []map[string]interface{}{row.Data}
+		}
+		if row.FirstSeen.IsZero() {
+			rows[i].seen(rows[i].updated)
+		} else {
+			rows[i].seen(row.FirstSeen.Local())
+			rows[i].seen(row.LastSeen.Local())
+		}
+		s.touch(&rows[i])
+		if row.Masked && !s.separate {
+			exact[exactKey(row.Key)] = i
+		}
+		if s.drain != nil {
+			s.drain.Reserve(templateID(row.Key))
+		}
+		for k := range row.Data {
+			fields[k] = true
+		}
+	}
+
+	s.keys = snap.Keys
+	s.total = snap.Total
+	s.rows = rows
+	s.exact = exact
+	s.fields = fields
+	s.reindex()
+	return nil
+}
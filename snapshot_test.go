@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreSaveLoad(t *testing.T) {
+	s := NewStore(time.Second, defaultTrendBuckets, 3, []string{"message"}, nil)
+	masks, _ := parseMasks([]string{`\d+=>N`})
+	s.SetMasks(masks)
+	s.Push(map[string]interface{}{"message": "user 1 not found", "id": json.Number("16029078675928157035")})
+	s.Push(map[string]interface{}{"message": "user 2 not found", "id": json.Number("1")})
+	s.Push(map[string]interface{}{"message": "connection refused"})
+
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewStore(time.Second, defaultTrendBuckets, 3, nil, nil)
+	loaded.SetMasks(masks)
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// versions aren't saved, every loaded row gets a new one
+	for i := range loaded.rows {
+		if loaded.rows[i].version == 0 {
+			t.Errorf("loaded row %d has no version", i)
+		}
+		if i < len(s.rows) {
+			loaded.rows[i].version = s.rows[i].version
+		}
+	}
+	if !reflect.DeepEqual(loaded.rows, s.rows) || !reflect.DeepEqual(loaded.exact, s.exact) {
+		t.Errorf("loaded rows %v, want %v", loaded.rows, s.rows)
+	}
+	if !reflect.DeepEqual(loaded.keys, s.keys) || loaded.total != s.total {
+		t.Errorf("loaded keys %v total %d, want %v %d", loaded.keys, loaded.total, s.keys, s.total)
+	}
+
+	// masked messages still combine exactly after loading
+	loaded.Push(map[string]interface{}{"message": "user 3 not found"})
+	if loaded.Len() != 2 || loaded.Get(0).count != 3 {
+		t.Errorf("after push %d groups, first count %d, want 2 groups and count 3", loaded.Len(), loaded.Get(0).count)
+	}
+}
+
+func TestStoreLoadRejectsIncompatible(t *testing.T) {
+	for _, input := range []string{
+		`{"version": 0, "rows": []}`,
+		`{"version": 1, "rows": [{"key": [["a"]], "trend": [0, 1], "count": 1, "data": {}}]}`,
+		`not json`,
+	} {
+		s := NewStore(time.Second, defaultTrendBuckets, 3, nil, nil)
+		if err := s.Load(strings.NewReader(input)); err == nil {
+			t.Errorf("Load(%s) succeeded, want error", input)
+		}
+	}
+}
+
+func TestStoreLoadWithoutSeen(t *testing.T) {
+	input := `{"version": 1, "keys": ["message"], "total": 1, "rows": [{"key": [["a"]], "trend": [0, 0, 0, 0, 0, 0, 1], "count": 1, "data": {"message": "a"}, "updated": "2024-08-22T09:00:06Z"}]}`
+	s := NewStore(time.Second, defaultTrendBuckets, 3, nil, nil)
+	if err := s.Load(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2024, 8, 22, 9, 0, 6, 0, time.UTC)
+	if row := s.Get(0); !row.GetFirstSeen().Equal(want) || !row.GetLastSeen().Equal(want) {
+		t.Errorf("first seen %v, last seen %v, want the updated time %v", row.GetFirstSeen(), row.GetLastSeen(), want)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// sourceKey holds the label of the input a record was read from, when
+// several are, see labelSources.
+const sourceKey = "source"
+
+// stdinName names stdin among the inputs.
+const stdinName = "stdin"
+
+var (
+	// labelSources adds the source to every record, set when records come
+	// from several inputs or connections, or -source-label is given.
+	labelSources bool
+
+	// sourceLabels maps file paths, their base names or stdin to the label
+	// replacing the default one, see -source-label.
+	sourceLabels map[string]string
+
+	// connections numbers the accepted connections, labelling those
+	// without a remote address.
+	connections atomic.Int64
+)
+
+// parseSourceLabels parses -source-label rules like logs/api.log=api.
+func parseSourceLabels(specs []string) (map[string]string, error) {
+	labels := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		name, label, ok := strings.Cut(spec, "=")
+		if !ok || name == "" || label == "" {
+			return nil, fmt.Errorf("invalid -source-label %q, want file=label", spec)
+		}
+		labels[name] = label
+	}
+	return labels, nil
+}
+
+// sourceLabel returns the label of the input name, the -source-label of its
+// path or base name, or else the base name.
+func sourceLabel(name string) string {
+	if label, ok := sourceLabels[name]; ok {
+		return label
+	}
+	base := filepath.Base(name)
+	if label, ok := sourceLabels[base]; ok {
+		return label
+	}
+	return base
+}
+
+// connLabel returns the label of a connection, its remote address or, for
+// unix sockets which have none, the number of the connection.
+func connLabel(conn net.Conn) string {
+	n := connections.Add(1)
+	if addr := conn.RemoteAddr(); addr != nil && addr.String() != "" && addr.String() != "@" {
+		return addr.String()
+	}
+	return fmt.Sprintf("conn-%d", n)
+}
+
+// namedInput is an input with the name its source label derives from.
+type namedInput struct {
+	io.ReadCloser
+	name string
+}
+
+// inputName returns the name of in, "" if it has none.
+func inputName(in io.Reader) string {
+	if n, ok := in.(namedInput); ok {
+		return n.name
+	}
+	return ""
+}
+
+// newSourceDecoder creates a decoder of in, labelling its records with the
+// source of in if labelSources is set.
+func newSourceDecoder(in io.Reader) Decoder {
+	return labelDecoder(newDecoder(in), sourceLabel(inputName(in)))
+}
+
+// sourceDecoder adds the label of its input to every record. A record's own
+// source field is kept as source_orig.
+type sourceDecoder struct {
+	Decoder
+	label string
+}
+
+// labelDecoder returns a decoder adding label to the records of dec, dec
+// itself unless labelSources is set.
+func labelDecoder(dec Decoder, label string) Decoder {
+	if !labelSources || dec == nil {
+		return dec
+	}
+	return sourceDecoder{dec, label}
+}
+
+func (d sourceDecoder) Decode() (map[string]interface{}, error) {
+	m, err := d.Decoder.Decode()
+	if err != nil {
+		return m, err
+	}
+	if v, ok := m[sourceKey]; ok {
+		m[origKey(sourceKey)] = v
+	}
+	m[sourceKey] = d.label
+	return m, nil
+}
+
+func (d sourceDecoder) Raw() string {
+	return rawText(d.Decoder)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSourceLabel(t *testing.T) {
+	labels, err := parseSourceLabels([]string{"logs/api.log=api", "db.log=database"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceLabels = labels
+	defer func() { sourceLabels = nil }()
+
+	tests := []struct {
+		name, want string
+	}{
+		{"logs/api.log", "api"},
+		{"other/api.log", "api.log"},
+		{"/var/log/db.log", "database"},
+		{"/var/log/app.log", "app.log"},
+		{stdinName, "stdin"},
+	}
+	for _, tt := range tests {
+		if got := sourceLabel(tt.name); got != tt.want {
+			t.Errorf("sourceLabel(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"api.log", "=api", "api.log="} {
+		if _, err := parseSourceLabels([]string{spec}); err == nil {
+			t.Errorf("parseSourceLabels(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestInputDecoderSources(t *testing.T) {
+	format = "logfmt"
+	labelSources = true
+	defer func() { format, labelSources = autoFormat, false }()
+
+	dir := t.TempDir()
+	api, db := filepath.Join(dir, "api.log"), filepath.Join(dir, "db.log")
+	if err := os.WriteFile(api, []byte("msg=a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(db, []byte("msg=b source=replica\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inputs, err := openInputs([]string{api, db}, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeInputs(inputs)
+
+	got := decodeAll(t, inputDecoder(inputs))
+	if len(got) != 2 {
+		t.Fatalf("decoded %d records, want 2", len(got))
+	}
+	if got[0]["source"] != "api.log" || got[1]["source"] != "db.log" || got[1]["source_orig"] != "replica" {
+		t.Errorf("decoded %v, want the base names as source and the record's own as source_orig", got)
+	}
+}
@@ -5,23 +5,94 @@ import (
 	"math"
 )
 
-var steps = []rune("▁▂▃▄▅▆▇") // 8th rune "█" omitted to prevent gluing of rows.
+// SparkStyle is the ramp of runes a sparkline is drawn with, from the lowest
+// to the highest value. Every rune is one cell wide, so sparklines of any
+// style are as wide as their number of buckets.
+type SparkStyle []rune
 
-func Spark(nums []float64) string {
+var (
+	SparkBlock   = SparkStyle("▁▂▃▄▅▆▇") // 8th rune "█" omitted to prevent gluing of rows.
+	SparkBraille = SparkStyle("⣀⣤⣶⣿")
+	SparkASCII   = SparkStyle(".:-=#")
+	SparkDots    = SparkStyle("⡀⠄⠂⠁")
+)
+
+// sparkStyles are the styles selectable with -spark-style.
+var sparkStyles = map[string]SparkStyle{
+	"block":   SparkBlock,
+	"braille": SparkBraille,
+	"ascii":   SparkASCII,
+	"dots":    SparkDots,
+}
+
+func Spark(nums []float64, style SparkStyle) string {
 	if len(nums) == 0 {
 		return ""
 	}
-	indices := normalize(nums)
+	indices := normalize(nums, len(style))
 	var sparkline bytes.Buffer
 	for _, index := range indices {
-		sparkline.WriteRune(steps[index])
+		sparkline.WriteRune(style[index])
 	}
 	return sparkline.String()
 }
 
-func normalize(nums []float64) []int {
+// SplitSpark renders the WARN and ERROR entries of a row's trend on the
+// scale of the overall trend, each bucket colored by the most severe of them.
+// Without colors only ERROR entries are drawn, since they can't be told
+// apart from warnings.
+func SplitSpark(row RowData, style SparkStyle) string {
+	trend := row.GetTrend()
+	if len(trend) == 0 {
+		return ""
+	}
+	errors, warns := row.GetLevelTrend("ERROR"), row.GetLevelTrend("WARN")
+	max := maximum(trend)
+	if max == 0 {
+		max = 1
+	}
+	var sparkline bytes.Buffer
+	for i := range trend {
+		var e, w float64
+		if errors != nil {
+			e = errors[i]
+		}
+		if warns != nil && !noColor {
+			w = warns[i]
+		}
+		step := style[scale(e+w, max, len(style))]
+		color := ""
+		switch {
+		case noColor:
+		case e > 0:
+			color = levelTagColor("ERROR")
+		case w > 0:
+			color = levelTagColor("WARN")
+		}
+		if color == "" {
+			sparkline.WriteRune(step)
+			continue
+		}
+		sparkline.WriteString("[" + color + "]" + string(step) + "[-]")
+	}
+	return sparkline.String()
+}
+
+// scale maps x within 0 and max to the index of one of steps runes.
+func scale(x, max float64, steps int) int {
+	total := float64(steps)
+	x = x / max * total
+	if x >= total {
+		return steps - 1
+	}
+	return int(math.Floor(x))
+}
+
+func normalize(nums []float64, steps int) []int {
+	// Work on a copy, nums may be the trend owned by the store.
+	nums = append([]float64(nil), nums...)
 	var indices []int
-	total := float64(len(steps))
+	total := float64(steps)
 	min := minimum(nums)
 	for i := range nums {
 		nums[i] -= min
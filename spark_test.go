@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSparkKeepsInput(t *testing.T) {
+	nums := []float64{2, 3, 5, 2}
+	if got := Spark(nums, SparkBlock); got != "▁▃▇▁" {
+		t.Errorf("Spark(%v) = %q", nums, got)
+	}
+	if want := []float64{2, 3, 5, 2}; !reflect.DeepEqual(nums, want) {
+		t.Errorf("Spark modified its input to %v, want %v", nums, want)
+	}
+}
+
+func TestSplitSpark(t *testing.T) {
+	s := NewStore(time.Second, 4, 3, []string{"message"}, nil)
+	s.SetSplitTrend(true)
+	push := func(level string, n int) {
+		for i := 0; i < n; i++ {
+			s.Push(map[string]interface{}{"level": level, "message": "request failed"})
+		}
+	}
+	push("INFO", 4)
+	s.Shift()
+	push("warning", 2)
+	s.Shift()
+	push("ERROR", 1)
+	push("WARN", 3)
+	s.Shift()
+
+	row := s.Get(0)
+	if got, want := row.GetLevelTrend("WARN"), []float64{0, 2, 3, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("WARN trend %v, want %v", got, want)
+	}
+	if got := row.GetLevelTrend("DEBUG"); got != nil {
+		t.Errorf("DEBUG trend %v, want nil", got)
+	}
+
+	defer func() { noColor = false }()
+	noColor = false
+	if got, want := SplitSpark(row, SparkBlock), "▁[yellow]▄[-][red]▇[-]▁"; got != want {
+		t.Errorf("SplitSpark() = %q, want %q", got, want)
+	}
+	noColor = true
+	if got, want := SplitSpark(row, SparkBlock), "▁▁▂▁"; got != want {
+		t.Errorf("SplitSpark() without colors = %q, want %q", got, want)
+	}
+}
+
+func TestSparkStyles(t *testing.T) {
+	nums := []float64{0, 1, 2, 3, 4, 5, 6, 7, 7}
+	want := map[string]string{
+		"block":   "▁▂▃▄▅▆▇▇▇",
+		"braille": "⣀⣀⣤⣤⣶⣶⣿⣿⣿",
+		"ascii":   "..:--=###",
+		"dots":    "⡀⡀⠄⠄⠂⠂⠁⠁⠁",
+	}
+	for name, style := range sparkStyles {
+		got := Spark(nums, style)
+		if got != want[name] {
+			t.Errorf("Spark(%v, %s) = %q, want %q", nums, name, got, want[name])
+		}
+		if n := len([]rune(got)); n != len(nums) {
+			t.Errorf("%s sparkline is %d runes wide, want %d", name, n, len(nums))
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// reservoirSize is the number of values sampled per group for percentiles.
+const reservoirSize = 1024
+
+// Stat is an aggregate of a numeric field computed per group.
+type Stat struct {
+	field string
+	agg   string
+	// percentile in (0, 100] for the pN aggregates
+	percentile float64
+}
+
+func (st Stat) String() string {
+	return st.field + ":" + st.agg
+}
+
+var percentileRE = regexp.MustCompile(`^p(\d{1,2}(\.\d+)?|100)$`)
+
+// parseStat parses a spec like latency:avg. Aggregates are count, sum, avg,
+// min, max and percentiles like p95.
+func parseStat(spec string) (Stat, error) {
+	field, agg, ok := strings.Cut(spec, ":")
+	if !ok || field == "" {
+		return Stat{}, fmt.Errorf("invalid stat %q, want field:aggregate", spec)
+	}
+	switch agg {
+	case "count", "sum", "avg", "min", "max":
+		return Stat{field: field, agg: agg}, nil
+	}
+	if m := percentileRE.FindStringSubmatch(agg); m != nil {
+		p, _ := strconv.ParseFloat(m[1], 64)
+		if p > 0 {
+			return Stat{field: field, agg: agg, percentile: p}, nil
+		}
+	}
+	return Stat{}, fmt.Errorf("invalid stat %q, unknown aggregate %q", spec, agg)
+}
+
+func parseStats(specs []string) ([]Stat, error) {
+	stats := make([]Stat, 0, len(specs))
+	for _, spec := range specs {
+		st, err := parseStat(spec)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+	return stats, nil
+}
+
+// aggregate is the running state of a Stat of one group.
+type aggregate struct {
+	count    int
+	sum      float64
+	min, max float64
+	// samples is a uniform reservoir of the values for percentiles.
+	samples []float64
+}
+
+func (a *aggregate) add(x float64, percentiles bool) {
+	a.count++
+	a.sum += x
+	if a.count == 1 || x < a.min {
+		a.min = x
+	}
+	if a.count == 1 || x > a.max {
+		a.max = x
+	}
+	if !percentiles {
+		return
+	}
+	if len(a.samples) < reservoirSize {
+		a.samples = append(a.samples, x)
+	} else if i := rand.Intn(a.count); i < reservoirSize {
+		a.samples[i] = x
+	}
+}
+
+// merge adds the values of b to a. Past reservoirSize, the percentile
+// samples of both are kept in proportion to the values they stand for.
+func (a *aggregate) merge(b aggregate) {
+	if b.count == 0 {
+		return
+	}
+	if a.count == 0 || b.min < a.min {
+		a.min = b.min
+	}
+	if a.count == 0 || b.max > a.max {
+		a.max = b.max
+	}
+	if len(a.samples)+len(b.samples) > reservoirSize {
+		n := int(math.Round(reservoirSize * float64(a.count) / float64(a.count+b.count)))
+		n = min(max(n, reservoirSize-len(b.samples)), len(a.samples))
+		a.samples = append(pick(a.samples, n), pick(b.samples, reservoirSize-n)...)
+	} else {
+		a.samples = append(a.samples, b.samples...)
+	}
+	a.count += b.count
+	a.sum += b.sum
+}
+
+// pick returns n of samples chosen at random.
+func pick(samples []float64, n int) []float64 {
+	c := append([]float64(nil), samples...)
+	for i := 0; i < n; i++ {
+		j := i + rand.Intn(len(c)-i)
+		c[i], c[j] = c[j], c[i]
+	}
+	return c[:n]
+}
+
+// value returns the aggregate of st, false if no value was added.
+func (a aggregate) value(st Stat) (float64, bool) {
+	if a.count == 0 {
+		return 0, st.agg == "count"
+	}
+	switch st.agg {
+	case "count":
+		return float64(a.count), true
+	case "sum":
+		return a.sum, true
+	case "avg":
+		return a.sum / float64(a.count), true
+	case "min":
+		return a.min, true
+	case "max":
+		return a.max, true
+	}
+	samples := append([]float64(nil), a.samples...)
+	sort.Float64s(samples)
+	// nearest rank
+	rank := int(math.Ceil(st.percentile/100*float64(len(samples)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return samples[rank], true
+}
+
+// numeric returns v as a float, numbers may be decoded as json.Number or
+// kept as strings by the line based decoders.
+func numeric(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// formatStat formats whole numbers without a fraction and others with two
+// decimals.
+func formatStat(x float64) string {
+	if x == math.Trunc(x) && math.Abs(x) < 1e15 {
+		return strconv.FormatFloat(x, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(x, 'f', 2, 64)
+}
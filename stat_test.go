@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseStat(t *testing.T) {
+	for _, spec := range []string{"latency:avg", "bytes:sum", "bytes:count", "latency:p95", "latency:p99.9", "latency:p100"} {
+		if _, err := parseStat(spec); err != nil {
+			t.Errorf("parseStat(%q) returned error: %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"latency", ":avg", "latency:mean", "latency:p0", "latency:p101"} {
+		if _, err := parseStat(spec); err == nil {
+			t.Errorf("parseStat(%q) accepted an invalid stat", spec)
+		}
+	}
+}
+
+func TestStoreStats(t *testing.T) {
+	stats, err := parseStats([]string{"latency:count", "latency:sum", "latency:avg", "latency:min", "latency:max", "latency:p50", "latency:p95", "status:avg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(time.Second, defaultTrendBuckets, 3, []string{"message"}, nil)
+	s.SetStats(stats)
+	for _, latency := range []interface{}{json.Number("10"), "20", 30.0, json.Number("0.5"), "-", nil} {
+		s.Push(map[string]interface{}{"message": "request done", "latency": latency})
+	}
+
+	want := []string{"4", "60.50", "15.12", "0.50", "30", "10", "30", ""}
+	row := s.Get(0)
+	for i, st := range stats {
+		if got := row.GetStat(i, st); got != want[i] {
+			t.Errorf("%s = %q, want %q", st, got, want[i])
+		}
+	}
+}
+
+func TestAggregateMerge(t *testing.T) {
+	var a, b aggregate
+	for i := 0; i < 9000; i++ {
+		a.add(1, true)
+	}
+	for i := 0; i < 1000; i++ {
+		b.add(2, true)
+	}
+	a.merge(b)
+	if a.count != 10000 || a.sum != 11000 || a.min != 1 || a.max != 2 {
+		t.Errorf("merged count %d, sum %g, min %g, max %g", a.count, a.sum, a.min, a.max)
+	}
+	// b holds a tenth of the values, so a tenth of the samples
+	twos := 0
+	for _, x := range a.samples {
+		if x == 2 {
+			twos++
+		}
+	}
+	if len(a.samples) != reservoirSize || twos != 102 {
+		t.Errorf("%d samples with %d of b, want %d with 102", len(a.samples), twos, reservoirSize)
+	}
+}
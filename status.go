@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+var (
+	status *tview.TextView
+	footer *tview.TextView
+)
+
+// message is shown in the status line until messageExpiry.
+var (
+	message       string
+	messageExpiry time.Time
+)
+
+const messageDuration = 5 * time.Second
+
+func newStatus() *tview.TextView {
+	return tview.NewTextView().
+		SetDynamicColors(true)
+}
+
+// renderFooter shows ingest statistics.
+func renderFooter() {
+	store.RLock()
+	total, rate, groups, evicted, dropped := store.Total(), store.Rate(), store.Len(), store.Evicted(), store.Dropped()
+	store.RUnlock()
+
+	text := fmt.Sprintf("%s | events: %d | rate: %.1f/s | groups: %d | invalid: %d",
+		progressText(), total, rate, groups, invalidLines.Load())
+	if strict && currentFormat() == "json" {
+		text += fmt.Sprintf(" (syntax: %d, not object: %d)", jsonSyntaxErrors.Load(), jsonNotObjects.Load())
+	}
+	if maxGroups > 0 {
+		text += fmt.Sprintf(" | evicted: %d", evicted)
+	}
+	if n := longLines.Load(); n > 0 {
+		text += fmt.Sprintf(" | [yellow]too long: %d[-]", n)
+	}
+	if n := ignoredLines.Load(); n > 0 {
+		text += fmt.Sprintf(" | ignored: %d", n)
+	}
+	if dropped > 0 {
+		text += fmt.Sprintf(" | dismissed: %d", dropped)
+	}
+	if every > 1 {
+		text += fmt.Sprintf(" | sampled: 1/%d", every)
+	}
+	if limited.Load() {
+		text += fmt.Sprintf(" | [black:yellow]stopped after %d records[-:-]", maxLines)
+	}
+	if n := panics.Load(); n > 0 {
+		text += fmt.Sprintf(" | [red]failed: %d[-]", n)
+	}
+	footer.SetText(text)
+}
+
+// selectionText returns the position of the selected group among the rows
+// shown, or just their number while the table isn't selectable.
+func selectionText() string {
+	row, _ := table.GetSelection()
+	if selectable, _ := table.GetSelectable(); !selectable || row < 1 || row > len(rows) {
+		return fmt.Sprintf("%d groups", len(rows))
+	}
+	return fmt.Sprintf("row %d of %d groups", row, len(rows))
+}
+
+// showMessage shows a transient message in the status line.
+func showMessage(format string, args ...interface{}) {
+	message = fmt.Sprintf(format, args...)
+	messageExpiry = time.Now().Add(messageDuration)
+}
+
+// renderStatus shows the position of the selection and the active view
+// settings in the status line.
+func renderStatus() {
+	var parts []string
+	if paused.Load() {
+		parts = append(parts, "[black:yellow]PAUSED[-:-]")
+	}
+	parts = append(parts, selectionText(), "format: "+currentFormat())
+	if len(filterSpecs) > 0 {
+		parts = append(parts, "filter: "+escape(strings.Join(filterSpecs, " ")))
+	}
+	combining := fmt.Sprintf("distance: %g", distance)
+	if noCombine {
+		combining = "no combine"
+	}
+	parts = append(parts, levelFilterText(), "sort: "+sortMode.String(), combining)
+	if column := scrolledColumn(); column != "" {
+		parts = append(parts, "columns from "+escape(column))
+	}
+	if searchQuery != "" {
+		parts = append(parts, "search: "+escape(searchQuery))
+	}
+	if message != "" && time.Now().Before(messageExpiry) {
+		parts = append(parts, escape(message))
+	}
+	status.SetText(strings.Join(parts, " | "))
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+func TestSelectionText(t *testing.T) {
+	defer func(t *tview.Table, r []int) { table, rows = t, r }(table, rows)
+	table = tview.NewTable()
+	rows = []int{4, 2, 7}
+
+	if got := selectionText(); got != "3 groups" {
+		t.Errorf("selectionText() = %q without a selection, want 3 groups", got)
+	}
+	table.SetSelectable(true, false)
+	table.Select(2, 0)
+	if got := selectionText(); got != "row 2 of 3 groups" {
+		t.Errorf("selectionText() = %q, want row 2 of 3 groups", got)
+	}
+}
@@ -2,33 +2,150 @@ package main
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
-const trendSize = 7
+// defaultTrendBuckets is the default number of trend buckets per row.
+const defaultTrendBuckets = 7
 
 type RowData struct {
-	key   []string
+	key   [][]string
 	trend []float64
 	count int
 	data  map[string]interface{}
+
+	// masked is set if a mask matched the key, the row combines by exact
+	// match.
+	masked bool
+
+	// updated is when the last entry was pushed to the group.
+	updated time.Time
+
+	// first is the group's first entry, samples its latest keepSamples
+	// entries, oldest first.
+	first   map[string]interface{}
+	samples []map[string]interface{}
+
+	// firstSeen and lastSeen are the earliest and latest datetime of the
+	// group's entries, or when they were pushed if they have none.
+	firstSeen time.Time
+	lastSeen  time.Time
+
+	// window counts the entries within the store's window when it differs
+	// from the trend, see SetWindow.
+	window []float64
+
+	// levelTrend holds a trend per rank in levels, only with -split-trend.
+	levelTrend [][]float64
+
+	// stats holds an aggregate per Stat of the store.
+	stats []aggregate
+
+	// version changes whenever the row does, see Store.touch.
+	version uint64
+
+	// alerts holds the state of every Alert of the store, alerting is set
+	// while the row exceeds the threshold of any of them.
+	alerts   []alertState
+	alerting bool
 }
 
 func (d RowData) GetCount() string {
 	return strconv.Itoa(d.count)
 }
 
+// Get returns the field key of the group's latest record, key may be a path
+// into nested values, see lookup.
 func (d RowData) Get(key string) interface{} {
-	return d.data[key]
+	return lookupValue(d.data, key)
 }
 
 func (d RowData) GetTrend() []float64 {
 	return d.trend
 }
 
+// GetLevel returns the level of the latest record of the group.
+func (d RowData) GetLevel() string {
+	return fmt.Sprintf("%v", d.data["level"])
+}
+
+// GetFirst returns the first entry of the group.
+func (d RowData) GetFirst() map[string]interface{} {
+	return d.first
+}
+
+// GetSamples returns the latest entries of the group kept by the store,
+// oldest first.
+func (d RowData) GetSamples() []map[string]interface{} {
+	return d.samples
+}
+
+// GetFirstSeen returns the earliest datetime of the group's entries.
+func (d RowData) GetFirstSeen() time.Time {
+	return d.firstSeen
+}
+
+// GetLastSeen returns the latest datetime of the group's entries.
+func (d RowData) GetLastSeen() time.Time {
+	return d.lastSeen
+}
+
+// seen widens the first and last seen times of row to include t.
+func (d *RowData) seen(t time.Time) {
+	if d.firstSeen.IsZero() || t.Before(d.firstSeen) {
+		d.firstSeen = t
+	}
+	if t.After(d.lastSeen) {
+		d.lastSeen = t
+	}
+}
+
+// GetAlerting reports whether the row exceeds the threshold of an alert.
+func (d RowData) GetAlerting() bool {
+	return d.alerting
+}
+
+// GetVersion returns the row's version, which changes with its content.
+func (d RowData) GetVersion() uint64 {
+	return d.version
+}
+
+// GetTrendBuckets returns the exact number of events per trend bucket.
+func (d RowData) GetTrendBuckets() []int {
+	buckets := make([]int, len(d.trend))
+	for i, n := range d.trend {
+		buckets[i] = int(n)
+	}
+	return buckets
+}
+
+// GetLevelTrend returns the trend of the group's entries of level, nil
+// unless the store splits trends by level.
+func (d RowData) GetLevelTrend(level string) []float64 {
+	rank := levelRank(level)
+	if rank < 0 || rank >= len(d.levelTrend) {
+		return nil
+	}
+	return d.levelTrend[rank]
+}
+
+// GetStat returns the formatted aggregate of the i-th Stat of the store, or
+// "" if the group has no numeric value of the field.
+func (d RowData) GetStat(i int, st Stat) string {
+	if i >= len(d.stats) {
+		return ""
+	}
+	x, ok := d.stats[i].value(st)
+	if !ok {
+		return ""
+	}
+	return formatStat(x)
+}
+
 func (d RowData) GetData() map[string]interface{} {
 	return d.data
 }
@@ -36,43 +153,442 @@ func (d RowData) GetData() map[string]interface{} {
 type Store struct {
 	sync.RWMutex
 	duration time.Duration
-	distance int
+	buckets  int
+
+	// window is the span rates and alerts count over, 0 for the trend
+	// duration.
+	window   time.Duration
+	distance float64
 	keys     []string
+	groupBy  []string
+	masks    []Mask
 	rows     []RowData
+	total    int
+
+	// weight is added per pushed entry, see SetWeight.
+	weight int
+
+	// maxGroups caps the number of rows, 0 means unbounded. evicted counts
+	// the rows dropped to stay within it.
+	maxGroups int
+	evicted   int
+
+	// separate keeps every entry a row of its own, see SetCombine.
+	separate bool
+
+	// dismissed are the keys of the groups removed with dismissSticky set,
+	// entries combining with them are dropped and counted in dropped.
+	dismissSticky bool
+	dismissed     []dismissedKey
+	dropped       int
+
+	// levelTotals counts the pushed entries by level name, none for those
+	// without a known level.
+	levelTotals map[string]int
+
+	// fields is the union of the keys of all pushed entries.
+	fields map[string]bool
+
+	// splitTrend keeps a trend per level besides the overall one.
+	splitTrend bool
+
+	// stats are aggregated per row.
+	stats []Stat
+
+	// keepSamples is the number of latest entries kept per row.
+	keepSamples int
+
+	// similarity is the metric compared with distance.
+	similarity Similarity
+
+	// drain learns the templates entries combine by with -group-by
+	// template, nil otherwise.
+	drain *Drain
+
+	// exact indexes rows with a masked message by their key, they combine by
+	// exact match instead of levenshtein distance.
+	exact map[string]int
+
+	// byLen indexes the rows by the number of tokens of the first field of
+	// their key, in ascending order, see find. Neither index is kept without
+	// SetCombine, nothing is looked up.
+	byLen map[int][]int
+
+	// versions counts row changes, rows take the next count as version.
+	versions uint64
+
+	// alerts are checked on every change of a row, fired holds those fired
+	// until TakeAlerts.
+	alerts []Alert
+	fired  []Fired
 }
 
-func NewStore(duration time.Duration, distance int, keys []string) *Store {
-	return &Store{
+// NewStore creates a store combining similar entries, with trends of buckets
+// spanning duration. Entries are compared by the groupBy fields, each of which
+// must be within distance, or by all keys joined together if groupBy is empty.
+// A groupBy of just template combines entries by the learned template of the
+// message instead.
+func NewStore(duration time.Duration, buckets int, distance float64, keys, groupBy []string) *Store {
+	s := &Store{
 		duration: duration,
+		buckets:  buckets,
 		distance: distance,
 		keys:     keys,
+		groupBy:  groupBy,
 		rows:     make([]RowData, 0),
+		exact:    make(map[string]int),
+		byLen:    make(map[int][]int),
+		weight:   1,
+		fields:   make(map[string]bool),
+
+		levelTotals: make(map[string]int),
+
+		keepSamples: 1,
 	}
+	if len(groupBy) == 1 && groupBy[0] == templateKey {
+		s.drain = NewDrain()
+	}
+	return s
+}
+
+// SetSimilarity sets the metric compared with the distance, levenshtein by
+// default.
+func (s *Store) SetSimilarity(similarity Similarity) {
+	s.similarity = similarity
 }
 
 func (s *Store) SetKeys(keys []string) {
 	s.keys = keys
 }
 
+// SetMasks sets the masks applied to the message before grouping.
+func (s *Store) SetMasks(masks []Mask) {
+	s.masks = masks
+}
+
+// SetWeight makes each pushed entry count as n events, which scales
+// counts back up when only every nth entry is pushed.
+func (s *Store) SetWeight(n int) {
+	s.weight = n
+}
+
+// SetSplitTrend keeps a trend per level for every row, see GetLevelTrend.
+func (s *Store) SetSplitTrend(split bool) {
+	s.splitTrend = split
+}
+
+// SetKeepSamples sets the number of latest entries kept per row, at least
+// the latest one is.
+func (s *Store) SetKeepSamples(n int) {
+	s.keepSamples = max(n, 1)
+}
+
+// SetStats sets the aggregates computed for every row.
+func (s *Store) SetStats(stats []Stat) {
+	s.stats = stats
+}
+
+// defaultScrollback is the -max-groups of -no-combine, which would keep
+// every entry otherwise.
+const defaultScrollback = 1000
+
+// SetCombine sets whether similar entries combine, the default. Without,
+// every entry is a row of its own, in the order pushed, and the rows are a
+// scrollback of the latest entries within SetMaxGroups.
+func (s *Store) SetCombine(combine bool) {
+	s.separate = !combine
+}
+
+// SetMaxGroups caps the number of rows, the least recently updated row is
+// evicted to make room for a new one. 0 disables the cap.
+func (s *Store) SetMaxGroups(n int) {
+	s.maxGroups = n
+}
+
 func (s *Store) Push(value map[string]interface{}) {
-	key := s.Key(value)
-	for i := range s.rows {
-		if ComputeDistance(key, s.rows[i].key) < s.distance {
-			s.rows[i].trend[len(s.rows[i].trend)-1] += 1
-			s.rows[i].count++
-			s.rows[i].data = value
-			return
+	// Strip the monotonic reading, updated is compared and saved as wall time.
+	now := time.Now().Round(0)
+	for k := range value {
+		if k != rawKey {
+			s.fields[k] = true
 		}
 	}
+	s.total += s.weight
+	s.countLevel(value)
+	seen, ok := recordTime(value)
+	if !ok {
+		seen = now
+	}
+	var key [][]string
+	masked := true
+	if s.drain != nil {
+		key, value = s.learnTemplate(value)
+	} else {
+		key, masked = s.Key(value)
+	}
+	i := -1
+	if !s.separate {
+		i = s.find(key, masked)
+	}
+	if i >= 0 {
+		s.rows[i].trend[len(s.rows[i].trend)-1] += float64(s.weight)
+		s.rows[i].count += s.weight
+		s.rows[i].data = value
+		s.rows[i].updated = now
+		s.rows[i].seen(seen)
+		s.pushSample(&s.rows[i], value)
+		s.pushWindow(&s.rows[i])
+		s.pushLevel(&s.rows[i], value)
+		s.pushStats(&s.rows[i], value)
+		s.checkAlerts(&s.rows[i], now)
+		s.touch(&s.rows[i])
+		return
+	}
+	if s.isDismissed(key, masked) {
+		s.dropped += s.weight
+		return
+	}
 
+	if s.maxGroups > 0 && len(s.rows) >= s.maxGroups {
+		s.evict()
+	}
+	if masked && !s.separate {
+		s.exact[exactKey(key)] = len(s.rows)
+	}
 	data := RowData{
-		key:   key,
-		trend: make([]float64, trendSize),
-		count: 1,
-		data:  value,
+		key:     key,
+		trend:   make([]float64, s.buckets),
+		count:   s.weight,
+		data:    value,
+		masked:  masked,
+		updated: now,
+		first:   value,
 	}
-	data.trend[len(data.trend)-1] += 1
+	data.seen(seen)
+	s.pushSample(&data, value)
+	data.trend[len(data.trend)-1] += float64(s.weight)
+	s.pushWindow(&data)
+	s.pushLevel(&data, value)
+	s.pushStats(&data, value)
+	s.checkAlerts(&data, now)
+	s.touch(&data)
 	s.rows = append(s.rows, data)
+	if !s.separate {
+		s.index(len(s.rows) - 1)
+	}
+}
+
+// touch gives row a new version. Versions are unique across rows, so a
+// renderer comparing them notices a row replaced by another as well as a
+// changed one.
+func (s *Store) touch(row *RowData) {
+	s.versions++
+	row.version = s.versions
+}
+
+// pushSample keeps value among the latest entries of row.
+func (s *Store) pushSample(row *RowData, value map[string]interface{}) {
+	if len(row.samples) >= s.keepSamples {
+		n := copy(row.samples, row.samples[len(row.samples)-s.keepSamples+1:])
+		clear(row.samples[n:])
+		row.samples = row.samples[:n]
+	}
+	row.samples = append(row.samples, value)
+}
+
+// pushStats adds the numeric fields of value to the aggregates of row. An
+// entry counts weight times, but is sampled once for percentiles.
+func (s *Store) pushStats(row *RowData, value map[string]interface{}) {
+	if len(s.stats) == 0 {
+		return
+	}
+	if len(row.stats) != len(s.stats) {
+		row.stats = make([]aggregate, len(s.stats))
+	}
+	for i, st := range s.stats {
+		x, ok := numeric(lookupValue(value, st.field))
+		if !ok {
+			continue
+		}
+		a := &row.stats[i]
+		a.add(x, st.percentile > 0)
+		if s.weight > 1 {
+			a.count += s.weight - 1
+			a.sum += x * float64(s.weight-1)
+		}
+	}
+}
+
+// countLevel adds value to the total of its level.
+func (s *Store) countLevel(value map[string]interface{}) {
+	level := "none"
+	if rank := levelRank(fmt.Sprintf("%v", value["level"])); rank >= 0 {
+		level = levels[rank]
+	}
+	s.levelTotals[level] += s.weight
+}
+
+// LevelTotals returns the number of entries pushed by level name, none for
+// entries without a known level.
+func (s *Store) LevelTotals() map[string]int {
+	totals := make(map[string]int, len(s.levelTotals))
+	for level, n := range s.levelTotals {
+		totals[level] = n
+	}
+	return totals
+}
+
+// pushLevel counts value in the trend of its level.
+func (s *Store) pushLevel(row *RowData, value map[string]interface{}) {
+	if !s.splitTrend {
+		return
+	}
+	rank := levelRank(fmt.Sprintf("%v", value["level"]))
+	if rank < 0 {
+		return
+	}
+	if row.levelTrend == nil {
+		row.levelTrend = make([][]float64, len(levels))
+	}
+	if row.levelTrend[rank] == nil {
+		row.levelTrend[rank] = make([]float64, s.buckets)
+	}
+	row.levelTrend[rank][s.buckets-1] += float64(s.weight)
+}
+
+// evict removes the least recently updated row.
+func (s *Store) evict() {
+	if len(s.rows) == 0 {
+		return
+	}
+	if s.separate {
+		// Rows are never updated, the first one is the oldest, and no index
+		// holds the positions shifting down.
+		s.rows[0] = RowData{}
+		s.rows = s.rows[1:]
+		s.evicted++
+		return
+	}
+	oldest := 0
+	for i := range s.rows {
+		if s.rows[i].updated.Before(s.rows[oldest].updated) {
+			oldest = i
+		}
+	}
+	s.remove(oldest)
+	s.evicted++
+}
+
+// remove deletes row i, shifting the indices of the following rows.
+func (s *Store) remove(i int) {
+	s.rows = append(s.rows[:i], s.rows[i+1:]...)
+	s.reindex()
+	for k, j := range s.exact {
+		switch {
+		case j == i:
+			delete(s.exact, k)
+		case j > i:
+			s.exact[k] = j - 1
+		}
+	}
+}
+
+// Group is the serializable form of a row.
+type Group struct {
+	Count     int                    `json:"count"`
+	Trend     []int                  `json:"trend"`
+	Updated   time.Time              `json:"updated"`
+	FirstSeen time.Time              `json:"first_seen"`
+	LastSeen  time.Time              `json:"last_seen"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// Each calls fn with every row in first seen order, until fn returns false.
+func (s *Store) Each(fn func(Group) bool) {
+	for i := range s.rows {
+		if !fn(s.Group(i)) {
+			return
+		}
+	}
+}
+
+// Group returns row i as a Group.
+func (s *Store) Group(i int) Group {
+	return s.rows[i].group()
+}
+
+func (d RowData) group() Group {
+	return Group{
+		Count:     d.count,
+		Trend:     d.GetTrendBuckets(),
+		Updated:   d.updated,
+		FirstSeen: d.firstSeen,
+		LastSeen:  d.lastSeen,
+		Data:      d.data,
+	}
+}
+
+// Fields returns the sorted keys seen in any pushed entry.
+func (s *Store) Fields() []string {
+	fields := make([]string, 0, len(s.fields))
+	for k := range s.fields {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Evicted returns the number of rows evicted by the -max-groups cap.
+func (s *Store) Evicted() int {
+	return s.evicted
+}
+
+// Total returns the number of events pushed.
+func (s *Store) Total() int {
+	return s.total
+}
+
+// Rate returns the events per second within the window.
+func (s *Store) Rate() float64 {
+	var rate float64
+	for i := range s.rows {
+		rate += s.GroupRate(i)
+	}
+	return rate
+}
+
+// GroupRate returns the events per second of row i over the window.
+func (s *Store) GroupRate(i int) float64 {
+	return trendRate(s.windowCounts(&s.rows[i]), s.Window())
+}
+
+// trendRate returns the events per second of trend spanning duration.
+func trendRate(trend []float64, duration time.Duration) float64 {
+	var n float64
+	for _, c := range trend {
+		n += c
+	}
+	return n / duration.Seconds()
+}
+
+// formatRate formats events per second like the footer.
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', 1, 64) + "/s"
+}
+
+// seenLayout formats first and last seen times.
+const seenLayout = "2006-01-02 15:04:05.000"
+
+// formatSeen formats a first or last seen time in -tz, or else local, empty
+// if it is unknown.
+func formatSeen(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	if timeZone != nil {
+		return t.In(timeZone).Format(seenLayout)
+	}
+	return t.Local().Format(seenLayout)
 }
 
 func (s *Store) Len() int {
@@ -86,14 +602,122 @@ func (s *Store) Get(i int) RowData {
 	return RowData{}
 }
 
-func (s *Store) Key(value map[string]interface{}) []string {
+// find returns the index of the row value combines with, or -1.
+func (s *Store) find(key [][]string, masked bool) int {
+	if masked {
+		if i, ok := s.exact[exactKey(key)]; ok {
+			return i
+		}
+		return -1
+	}
+	if s.similarity == Jaccard {
+		for i := range s.rows {
+			if s.similar(key, s.rows[i].key) {
+				return i
+			}
+		}
+		return -1
+	}
+	// The levenshtein distance of two token lists is at least the difference
+	// of their lengths, so only rows whose first field has a length within
+	// distance can be similar. Of those the first row is returned, as if all
+	// rows were compared in order.
+	n, d := keyLen(key), int(s.distance)
+	first := -1
+	for l := n - d + 1; l < n+d; l++ {
+		for _, i := range s.byLen[l] {
+			if first >= 0 && i > first {
+				break
+			}
+			if s.similar(key, s.rows[i].key) {
+				first = i
+				break
+			}
+		}
+	}
+	return first
+}
+
+// keyLen returns the number of tokens of the first field of key.
+func keyLen(key [][]string) int {
+	if len(key) == 0 {
+		return 0
+	}
+	return len(key[0])
+}
+
+// index adds row i, the last one, to byLen.
+func (s *Store) index(i int) {
+	n := keyLen(s.rows[i].key)
+	s.byLen[n] = append(s.byLen[n], i)
+}
+
+// reindex builds byLen again after rows moved.
+func (s *Store) reindex() {
+	s.byLen = make(map[int][]int)
+	if s.separate {
+		return
+	}
+	for i := range s.rows {
+		s.index(i)
+	}
+}
+
+func exactKey(key [][]string) string {
+	parts := make([]string, len(key))
+	for i := range key {
+		parts[i] = strings.Join(key[i], " ")
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// Key returns the tokens compared by levenshtein distance, one sequence per
+// groupBy field, and whether any mask matched the message.
+func (s *Store) Key(value map[string]interface{}) ([][]string, bool) {
+	masked := false
+	if len(s.groupBy) == 0 {
+		return [][]string{s.tokens(value, s.keys, &masked)}, masked
+	}
+	key := make([][]string, len(s.groupBy))
+	for i, name := range s.groupBy {
+		key[i] = s.tokens(value, []string{name}, &masked)
+	}
+	return key, masked
+}
+
+// learnTemplate learns the template of the masked message of value and
+// returns the key of the template, which combines by exact match, and a copy
+// of value with the template field set. The caller may still read value, with
+// -batch concurrently to the push.
+func (s *Store) learnTemplate(value map[string]interface{}) ([][]string, map[string]interface{}) {
+	text := fmt.Sprintf("%v", value["message"])
+	if len(s.masks) > 0 {
+		text, _ = applyMasks(s.masks, text)
+	}
+	t := s.drain.Add(strings.Fields(text))
+	c := make(map[string]interface{}, len(value)+1)
+	for k, v := range value {
+		c[k] = v
+	}
+	c[templateKey] = t.String()
+	s.fields[templateKey] = true
+	return t.key(), c
+}
+
+func (s *Store) tokens(value map[string]interface{}, names []string, masked *bool) []string {
 	key := make([]string, 0)
-	for _, name := range s.keys {
-		sub := strings.Split(fmt.Sprintf("%v", value[name]), " ")
+	for _, name := range names {
+		text := fmt.Sprintf("%v", lookupValue(value, name))
+		if name == "message" && len(s.masks) > 0 {
+			var ok bool
+			text, ok = applyMasks(s.masks, text)
+			*masked = *masked || ok
+		}
+		sub := strings.Split(text, " ")
 
 		// For short parts of key, double sub length x2.
 		// Doubling levenshtein distance for this part of key.
-		if len(sub) < s.distance {
+		if s.similarity == Levenshtein && float64(len(sub)) < s.distance {
 			sub = append(sub, sub...)
 		}
 
@@ -102,11 +726,82 @@ func (s *Store) Key(value map[string]interface{}) []string {
 	return key
 }
 
+// similar reports whether every part of the keys is within distance.
+func (s *Store) similar(a, b [][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if s.similarity == Jaccard {
+			if JaccardDistance(a[i], b[i]) >= s.distance {
+				return false
+			}
+		} else if !WithinDistance(a[i], b[i], int(s.distance)) {
+			return false
+		}
+	}
+	return true
+}
+
 func (s *Store) Shift() {
+	now := time.Now()
 	for i := range s.rows {
-		for j := 0; j < len(s.rows[i].trend)-1; j++ {
-			s.rows[i].trend[j] = s.rows[i].trend[j+1]
+		shiftTrend(s.rows[i].trend)
+		for _, trend := range s.rows[i].levelTrend {
+			shiftTrend(trend)
+		}
+		s.checkAlerts(&s.rows[i], now)
+		s.touch(&s.rows[i])
+	}
+}
+
+func shiftTrend(trend []float64) {
+	if len(trend) == 0 {
+		return
+	}
+	copy(trend, trend[1:])
+	trend[len(trend)-1] = 0
+}
+
+type SortMode int
+
+const (
+	SortFirstSeen SortMode = iota
+	SortCount
+	SortRate
+	sortModes
+)
+
+func (m SortMode) String() string {
+	switch m {
+	case SortCount:
+		return "count"
+	case SortRate:
+		return "rate"
+	}
+	return "first seen"
+}
+
+// Sorted returns the indices of all rows in the given order. Ties keep the
+// first seen order.
+func (s *Store) Sorted(mode SortMode) []int {
+	indices := make([]int, len(s.rows))
+	for i := range indices {
+		indices[i] = i
+	}
+	switch mode {
+	case SortCount:
+		sort.SliceStable(indices, func(i, j int) bool {
+			return s.rows[indices[i]].count > s.rows[indices[j]].count
+		})
+	case SortRate:
+		rates := make([]float64, len(s.rows))
+		for i := range s.rows {
+			rates[i] = s.GroupRate(i)
 		}
-		s.rows[i].trend[len(s.rows[i].trend)-1] = 0
+		sort.SliceStable(indices, func(i, j int) bool {
+			return rates[indices[i]] > rates[indices[j]]
+		})
 	}
+	return indices
 }
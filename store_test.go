@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreSorted(t *testing.T) {
+	s := NewStore(time.Second, defaultTrendBuckets, 1, []string{"message"}, nil)
+	push := func(message string, n int) {
+		for i := 0; i < n; i++ {
+			s.Push(map[string]interface{}{"message": message})
+		}
+	}
+	push("a", 1)
+	push("b", 3)
+	s.Shift()
+	push("c", 2)
+	s.Shift()
+	s.Shift()
+	s.Shift()
+	s.Shift()
+	s.Shift()
+	s.Shift() // a and b fall out of the trend window
+
+	tests := []struct {
+		mode SortMode
+		want []int
+	}{
+		{SortFirstSeen, []int{0, 1, 2}},
+		{SortCount, []int{1, 2, 0}},
+		{SortRate, []int{2, 0, 1}},
+	}
+	for _, tt := range tests {
+		if got := s.Sorted(tt.mode); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Sorted(%v) = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestStoreGroupRate(t *testing.T) {
+	s := NewStore(4*time.Second, defaultTrendBuckets, 1, []string{"message"}, nil)
+	for i := 0; i < 6; i++ {
+		s.Push(map[string]interface{}{"message": "a"})
+	}
+	s.Shift()
+	s.Push(map[string]interface{}{"message": "a"})
+	s.Push(map[string]interface{}{"message": "b"})
+
+	if got := formatRate(s.GroupRate(0)); got != "1.8/s" {
+		t.Errorf("GroupRate(0) = %s, want 1.8/s", got)
+	}
+	if got := formatRate(s.GroupRate(1)); got != "0.2/s" {
+		t.Errorf("GroupRate(1) = %s, want 0.2/s", got)
+	}
+	if got := s.Rate(); got != 2 {
+		t.Errorf("Rate() = %v, want 2", got)
+	}
+}
+
+func TestStoreSeen(t *testing.T) {
+	s := NewStore(time.Second, defaultTrendBuckets, 1, []string{"message"}, nil)
+	at := func(sec int) time.Time { return time.Date(2024, 8, 22, 9, 0, sec, 0, time.Local) }
+	s.Push(map[string]interface{}{"message": "a", "datetime": at(5)})
+	s.Push(map[string]interface{}{"message": "a", "datetime": at(1)})
+	s.Push(map[string]interface{}{"message": "a", "datetime": "2024-08-22 09:00:09.000"})
+	s.Push(map[string]interface{}{"message": "a", "datetime": "not a time"})
+
+	row := s.Get(0)
+	if got := row.GetFirstSeen(); !got.Equal(at(1)) {
+		t.Errorf("GetFirstSeen() = %v, want %v", got, at(1))
+	}
+	// the entry without a datetime was seen now
+	if got := row.GetLastSeen(); time.Since(got) > time.Minute {
+		t.Errorf("GetLastSeen() = %v, want about now", got)
+	}
+
+	before := time.Now()
+	s.Push(map[string]interface{}{"message": "disk full"})
+	if got := s.Get(1).GetFirstSeen(); got.Before(before.Round(0).Add(-time.Millisecond)) || !got.Equal(s.Get(1).GetLastSeen()) {
+		t.Errorf("first seen %v, last seen %v, want both now", got, s.Get(1).GetLastSeen())
+	}
+	if got, want := formatSeen(at(1)), "2024-08-22 09:00:01.000"; got != want {
+		t.Errorf("formatSeen() = %q, want %q", got, want)
+	}
+	if got := formatSeen(time.Time{}); got != "" {
+		t.Errorf("formatSeen(zero) = %q, want empty", got)
+	}
+
+	// in -tz, like the datetimes
+	defer func() { timeZone = nil }()
+	timeZone = time.UTC
+	if got, want := formatSeen(time.Date(2024, 8, 22, 9, 0, 1, 0, time.FixedZone("CEST", 2*3600))), "2024-08-22 07:00:01.000"; got != want {
+		t.Errorf("formatSeen() in UTC = %q, want %q", got, want)
+	}
+}
+
+func TestStoreKeepSamples(t *testing.T) {
+	s := NewStore(time.Second, defaultTrendBuckets, 3, []string{"message"}, nil)
+	s.SetKeepSamples(3)
+	for i := 1; i <= 5; i++ {
+		s.Push(map[string]interface{}{"message": "user not found", "id": i})
+	}
+
+	row := s.Get(0)
+	if got := row.GetFirst()["id"]; got != 1 {
+		t.Errorf("first id %v, want 1", got)
+	}
+	var ids []interface{}
+	for _, sample := range row.GetSamples() {
+		ids = append(ids, sample["id"])
+	}
+	if want := []interface{}{3, 4, 5}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("sample ids %v, want %v", ids, want)
+	}
+	if got := row.GetData()["id"]; got != 5 {
+		t.Errorf("latest id %v, want 5", got)
+	}
+
+	// by default only the latest is kept
+	s = NewStore(time.Second, defaultTrendBuckets, 3, []string{"message"}, nil)
+	s.Push(map[string]interface{}{"message": "a", "id": 1})
+	s.Push(map[string]interface{}{"message": "a", "id": 2})
+	if samples := s.Get(0).GetSamples(); len(samples) != 1 || samples[0]["id"] != 2 {
+		t.Errorf("samples %v, want only the latest", samples)
+	}
+}
+
+func TestStoreGroupBy(t *testing.T) {
+	values := []map[string]interface{}{
+		{"position": "a.go:1", "message": "user 1 not found in cache"},
+		{"position": "b.go:2", "message": "user 2 not found in cache"},
+		{"position": "a.go:1", "message": "connection refused by upstream"},
+	}
+	tests := []struct {
+		groupBy []string
+		want    int
+	}{
+		{nil, 3},
+		{[]string{"message"}, 2},
+		{[]string{"position"}, 2},
+		{[]string{"position", "message"}, 3},
+	}
+	for _, tt := range tests {
+		s := NewStore(time.Second, defaultTrendBuckets, 2, []string{"position", "message"}, tt.groupBy)
+		for _, v := range values {
+			s.Push(v)
+		}
+		if s.Len() != tt.want {
+			t.Errorf("group by %v: %d groups, want %d", tt.groupBy, s.Len(), tt.want)
+		}
+	}
+}
+
+func TestStoreMasks(t *testing.T) {
+	masks, err := parseMasks([]string{`\d+=>N`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(time.Second, defaultTrendBuckets, 3, []string{"message"}, nil)
+	s.SetMasks(masks)
+	for _, message := range []string{
+		"user 123 not found",
+		"user 456 not found",
+		"order 7 not found", // masked, but differs from the above
+		"connection refused",
+		"connection reset", // not masked, levenshtein applies
+	} {
+		s.Push(map[string]interface{}{"message": message})
+	}
+
+	want := []int{2, 1, 2}
+	if s.Len() != len(want) {
+		t.Fatalf("%d groups, want %d", s.Len(), len(want))
+	}
+	for i, n := range want {
+		if s.Get(i).count != n {
+			t.Errorf("group %d has count %d, want %d", i, s.Get(i).count, n)
+		}
+	}
+}
+
+func TestStoreWeight(t *testing.T) {
+	s := NewStore(time.Second, defaultTrendBuckets, 3, []string{"message"}, nil)
+	s.SetWeight(10)
+	for _, message := range []string{"connection refused", "connection refused", "disk full"} {
+		s.Push(map[string]interface{}{"message": message})
+	}
+
+	if s.Total() != 30 {
+		t.Errorf("total %d, want 30", s.Total())
+	}
+	if got := s.Get(0).count; got != 20 {
+		t.Errorf("count %d, want 20", got)
+	}
+	if got := s.Get(1).GetTrendBuckets()[defaultTrendBuckets-1]; got != 10 {
+		t.Errorf("last trend bucket %d, want 10", got)
+	}
+}
+
+func TestStoreMaxGroups(t *testing.T) {
+	masks, err := parseMasks([]string{`\d+=>N`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(time.Second, defaultTrendBuckets, 3, []string{"message"}, nil)
+	s.SetMasks(masks)
+	s.SetMaxGroups(2)
+	for _, message := range []string{
+		"user 1 not found",
+		"connection refused",
+		"user 2 not found", // updates the first group
+		"disk full",        // evicts connection refused
+		"user 3 not found",
+	} {
+		s.Push(map[string]interface{}{"message": message})
+		time.Sleep(time.Millisecond)
+	}
+
+	if s.Len() != 2 || s.Evicted() != 1 {
+		t.Fatalf("%d groups, %d evicted, want 2 and 1", s.Len(), s.Evicted())
+	}
+	for i, want := range []string{"user 3 not found", "disk full"} {
+		if got := s.Get(i).Get("message"); got != want {
+			t.Errorf("group %d is %v, want %v", i, got, want)
+		}
+	}
+	if got := s.Get(0).count; got != 3 {
+		t.Errorf("masked group count %d, want 3", got)
+	}
+}
+
+// benchCorpus returns n entries of templates messages, with word counts
+// from 3 to 32 and a varying user id in each.
+func benchCorpus(n, templates int) []map[string]interface{} {
+	corpus := make([]map[string]interface{}, n)
+	for i := range corpus {
+		t := i % templates
+		words := make([]string, 3+t%30)
+		for j := range words {
+			words[j] = fmt.Sprintf("w%d", (t*31+j)%997)
+		}
+		words[len(words)/2] = fmt.Sprintf("user%d", i%100)
+		corpus[i] = map[string]interface{}{"message": strings.Join(words, " ")}
+	}
+	return corpus
+}
+
+func BenchmarkStorePush(b *testing.B) {
+	corpus := benchCorpus(1<<16, 2000)
+	s := NewStore(time.Second, defaultTrendBuckets, 3, []string{"message"}, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Push(corpus[i%len(corpus)])
+	}
+	b.ReportMetric(float64(s.Len()), "groups")
+}
+
+func TestStoreFindMatchesScan(t *testing.T) {
+	s := NewStore(time.Second, defaultTrendBuckets, 4, []string{"message"}, nil)
+	for _, value := range benchCorpus(5000, 300) {
+		s.Push(value)
+	}
+	for _, value := range benchCorpus(500, 700) {
+		key, _ := s.Key(value)
+		want := -1
+		for i := range s.rows {
+			if s.similar(key, s.rows[i].key) {
+				want = i
+				break
+			}
+		}
+		if got := s.find(key, false); got != want {
+			t.Fatalf("find(%v) = %d, want %d of the scan", value["message"], got, want)
+		}
+	}
+}
+
+func TestStoreNoCombine(t *testing.T) {
+	s := NewStore(time.Second, defaultTrendBuckets, 3, []string{"message"}, nil)
+	s.SetCombine(false)
+	s.SetMaxGroups(3)
+	for _, message := range []string{"a", "b", "a", "a"} {
+		s.Push(map[string]interface{}{"message": message})
+	}
+	if s.Len() != 3 || s.Evicted() != 1 {
+		t.Fatalf("%d rows with %d evicted, want the latest 3 and 1", s.Len(), s.Evicted())
+	}
+	var got []string
+	for i := 0; i < s.Len(); i++ {
+		got = append(got, s.Get(i).Get("message").(string))
+		if s.Get(i).count != 1 {
+			t.Errorf("row %d counts %d entries, want 1", i, s.Get(i).count)
+		}
+	}
+	if want := "b a a"; strings.Join(got, " ") != want {
+		t.Errorf("rows %q, want %q in the order pushed", got, want)
+	}
+	if len(s.exact) != 0 || len(s.byLen) != 0 {
+		t.Errorf("rows indexed without combining, exact %v, byLen %v", s.exact, s.byLen)
+	}
+	s.SetDistance(10)
+	if s.Len() != 3 {
+		t.Errorf("%d rows after raising the distance, want them kept apart", s.Len())
+	}
+}
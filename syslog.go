@@ -0,0 +1,176 @@
+package main
+
+import (
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var syslogSeverities = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+// syslogDecoder decodes RFC5424 syslog lines, e.g.
+// <165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3"] message
+//
+// Lines which are not RFC5424 are parsed loosely as RFC3164, e.g.
+// <34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed
+type syslogDecoder struct {
+	*lineScanner
+}
+
+func newSyslogDecoder(r io.Reader) *syslogDecoder {
+	return &syslogDecoder{
+		lineScanner: newLineScanner(r),
+	}
+}
+
+func (d *syslogDecoder) Decode() (map[string]interface{}, error) {
+	for {
+		d.Begin()
+		line, ok := d.Next()
+		if !ok {
+			if err := d.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		return parseSyslog(line), nil
+	}
+}
+
+var syslogPriRE = regexp.MustCompile(`^<(\d{1,3})>`)
+
+// parseSyslog never fails, unrecognized lines end up in the message field.
+func parseSyslog(line string) map[string]interface{} {
+	m := map[string]interface{}{}
+
+	rest := line
+	if matches := syslogPriRE.FindStringSubmatch(rest); matches != nil {
+		pri, _ := strconv.Atoi(matches[1])
+		if pri/8 < len(syslogFacilities) {
+			m["facility"] = syslogFacilities[pri/8]
+		}
+		m["severity"] = syslogSeverities[pri%8]
+		rest = rest[len(matches[0]):]
+	}
+
+	if parseRFC5424(rest, m) {
+		return m
+	}
+	parseRFC3164(rest, m)
+	return m
+}
+
+var rfc5424RE = regexp.MustCompile(`^(\d{1,2}) (\S+) (\S+) (\S+) (\S+) (\S+) ?(.*)$`)
+
+func parseRFC5424(s string, m map[string]interface{}) bool {
+	matches := rfc5424RE.FindStringSubmatch(s)
+	if matches == nil {
+		return false
+	}
+	sd, msg, ok := parseStructuredData(matches[7])
+	if !ok {
+		return false
+	}
+
+	m["version"] = toValue(matches[1])
+	setSyslogField(m, "datetime", matches[2])
+	setSyslogField(m, "hostname", matches[3])
+	setSyslogField(m, "appname", matches[4])
+	setSyslogField(m, "procid", matches[5])
+	setSyslogField(m, "msgid", matches[6])
+	for k, v := range sd {
+		m[k] = v
+	}
+	m["message"] = strings.TrimPrefix(msg, "\ufeff")
+	return true
+}
+
+// setSyslogField skips the NILVALUE "-".
+func setSyslogField(m map[string]interface{}, key, value string) {
+	if value != "-" {
+		m[key] = value
+	}
+}
+
+// parseStructuredData parses STRUCTURED-DATA at the beginning of s and returns
+// the flattened elements as "SD-ID.PARAM-NAME" keys along with the remaining
+// message.
+func parseStructuredData(s string) (map[string]interface{}, string, bool) {
+	sd := map[string]interface{}{}
+	if s == "-" || strings.HasPrefix(s, "- ") {
+		return sd, strings.TrimPrefix(strings.TrimPrefix(s, "-"), " "), true
+	}
+	if !strings.HasPrefix(s, "[") {
+		return nil, "", false
+	}
+
+	i := 0
+	for i < len(s) && s[i] == '[' {
+		i++
+		start := i
+		for i < len(s) && s[i] != ' ' && s[i] != ']' {
+			i++
+		}
+		id := s[start:i]
+		for i < len(s) && s[i] != ']' {
+			i++ // skip ' '
+			start = i
+			for i < len(s) && s[i] != '=' {
+				i++
+			}
+			name := s[start:i]
+			if i+1 >= len(s) || s[i+1] != '"' {
+				return nil, "", false
+			}
+			i += 2 // skip '="'
+			var value strings.Builder
+			for i < len(s) && s[i] != '"' {
+				if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`"\]`, s[i+1]) >= 0 {
+					i++
+				}
+				value.WriteByte(s[i])
+				i++
+			}
+			if i >= len(s) {
+				return nil, "", false
+			}
+			i++ // closing quote
+			sd[id+"."+name] = toValue(value.String())
+		}
+		if i >= len(s) {
+			return nil, "", false
+		}
+		i++ // skip ']'
+	}
+	return sd, strings.TrimPrefix(s[i:], " "), true
+}
+
+var rfc3164RE = regexp.MustCompile(`^([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}) (\S+) ([^:\[\s]+)(?:\[([^\]]+)\])?: ?(.*)$`)
+
+func parseRFC3164(s string, m map[string]interface{}) {
+	matches := rfc3164RE.FindStringSubmatch(s)
+	if matches == nil {
+		m["message"] = s
+		return
+	}
+	m["datetime"] = matches[1]
+	m["hostname"] = matches[2]
+	m["appname"] = matches[3]
+	if matches[4] != "" {
+		m["procid"] = matches[4]
+	}
+	m["message"] = matches[5]
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseSyslog(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]interface{}
+	}{
+		{
+			`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application"] An application event`,
+			map[string]interface{}{
+				"facility":                      "local4",
+				"severity":                      "notice",
+				"version":                       json.Number("1"),
+				"datetime":                      "2003-10-11T22:14:15.003Z",
+				"hostname":                      "mymachine.example.com",
+				"appname":                       "evntslog",
+				"msgid":                         "ID47",
+				"exampleSDID@32473.iut":         json.Number("3"),
+				"exampleSDID@32473.eventSource": "Application",
+				"message":                       "An application event",
+			},
+		},
+		{
+			`<34>1 2003-10-11T22:14:15.003Z host su - - - 'su root' failed`,
+			map[string]interface{}{
+				"facility": "auth",
+				"severity": "crit",
+				"version":  json.Number("1"),
+				"datetime": "2003-10-11T22:14:15.003Z",
+				"hostname": "host",
+				"appname":  "su",
+				"message":  "'su root' failed",
+			},
+		},
+		{
+			`<13>Oct 11 22:14:15 mymachine su[123]: 'su root' failed`,
+			map[string]interface{}{
+				"facility": "user",
+				"severity": "notice",
+				"datetime": "Oct 11 22:14:15",
+				"hostname": "mymachine",
+				"appname":  "su",
+				"procid":   "123",
+				"message":  "'su root' failed",
+			},
+		},
+		{
+			`something unexpected`,
+			map[string]interface{}{
+				"message": "something unexpected",
+			},
+		},
+	}
+	for i, tt := range tests {
+		got := parseSyslog(tt.line)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Test[%d]: parseSyslog(%q) = %v, want %v", i, tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// tailLines is the number of lines -tail reads of the end of every file, -1
+// reads the files in full.
+var tailLines = -1
+
+// tailChunk is the block size tailOffset reads backwards.
+const tailChunk = 64 << 10
+
+// tailOffset returns the offset of the n-th line from the end of f, like
+// tail -n. A last line without a newline counts as a line.
+func tailOffset(f *os.File, n int) (int64, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := fi.Size()
+	if n <= 0 || size == 0 {
+		return size, nil
+	}
+
+	buf := make([]byte, tailChunk)
+	end := size
+	// The newline ending the last line doesn't start another one.
+	skip := true
+	for end > 0 {
+		start := max(end-tailChunk, 0)
+		chunk := buf[:end-start]
+		if _, err := f.ReadAt(chunk, start); err != nil && err != io.EOF {
+			return 0, err
+		}
+		for i := len(chunk) - 1; i >= 0; i-- {
+			newline := chunk[i] == '\n'
+			if skip || !newline {
+				skip = false
+				continue
+			}
+			if n--; n == 0 {
+				return start + int64(i) + 1, nil
+			}
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+// seekTail positions f at the last n lines.
+func seekTail(f *os.File, n int) error {
+	offset, err := tailOffset(f, n)
+	if err != nil {
+		return err
+	}
+	_, err = f.Seek(offset, io.SeekStart)
+	return err
+}
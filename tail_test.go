@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTailOffset(t *testing.T) {
+	long := strings.Repeat("x", 3*tailChunk/2)
+	tests := []struct {
+		content string
+		n       int
+		want    string
+	}{
+		{"a\nb\nc\n", 2, "b\nc\n"},
+		{"a\nb\nc\n", 3, "a\nb\nc\n"},
+		{"a\nb\nc\n", 10, "a\nb\nc\n"},
+		{"a\nb\nc\n", 0, ""},
+		// the partial last line counts as a line
+		{"a\nb\nc", 2, "b\nc"},
+		{"a\nb\n\n", 1, "\n"},
+		{"", 5, ""},
+		{"a\n" + long + "\nb\n", 2, long + "\nb\n"},
+		{long + "\n" + long + "\n", 1, long + "\n"},
+	}
+	dir := t.TempDir()
+	for i, tt := range tests {
+		name := filepath.Join(dir, "app.log")
+		if err := os.WriteFile(name, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = seekTail(f, tt.n)
+		rest, _ := io.ReadAll(f)
+		f.Close()
+		if err != nil || string(rest) != tt.want {
+			t.Errorf("Test[%d]: last %d lines = %.40q, %v, want %.40q", i, tt.n, rest, err, tt.want)
+		}
+	}
+}
+
+func TestOpenInputsTail(t *testing.T) {
+	defer func() { tailLines = -1 }()
+	tailLines = 1
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(name, []byte("msg=a\nmsg=b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inputs, err := openInputs([]string{name}, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeInputs(inputs)
+
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("msg=c\n")
+	f.Close()
+
+	buf := make([]byte, 64)
+	var got string
+	for !strings.Contains(got, "msg=c\n") {
+		n, err := inputs[0].Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got += string(buf[:n])
+	}
+	if got != "msg=b\nmsg=c\n" {
+		t.Errorf("followed %q, want the last line then the appended one", got)
+	}
+
+	if _, err := openInputs(nil, true, false); err == nil {
+		t.Error("-tail of stdin succeeded")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// sinceTime and untilTime bound the datetime of ingested entries, zero
+// means unbounded.
+var sinceTime, untilTime time.Time
+
+// parseTimeBound parses a -since or -until value, either a timestamp in one
+// of timeLayouts or a date, or a duration before now like 10m. Timestamps
+// without zone are in -tz, as the datetimes shown, or else local.
+func parseTimeBound(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	loc := time.Local
+	if timeZone != nil {
+		loc = timeZone
+	}
+	if t, ok := parseTimeIn(s, "", loc); ok {
+		return t, nil
+	}
+	if t, ok := parseTimeLayout(s, "2006-01-02", loc); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, want a timestamp or a duration like 10m", s)
+}
+
+// inTimeBounds reports whether the entry falls within -since and -until.
+// Entries without a datetime are kept unless -require-time is set.
+func inTimeBounds(m map[string]interface{}) bool {
+	if sinceTime.IsZero() && untilTime.IsZero() {
+		return true
+	}
+	t, ok := recordTime(m)
+	if !ok {
+		return !requireTime
+	}
+	if !sinceTime.IsZero() && t.Before(sinceTime) {
+		return false
+	}
+	if !untilTime.IsZero() && t.After(untilTime) {
+		return false
+	}
+	return true
+}
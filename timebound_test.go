@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2024, 8, 22, 9, 30, 0, 0, time.Local)
+	tests := []struct {
+		s    string
+		want time.Time
+	}{
+		{"", time.Time{}},
+		{"10m", now.Add(-10 * time.Minute)},
+		{"1h30m", now.Add(-90 * time.Minute)},
+		{"2024-08-22 09:00:06", time.Date(2024, 8, 22, 9, 0, 6, 0, time.Local)},
+		{"2024-08-22T09:00:06Z", time.Date(2024, 8, 22, 9, 0, 6, 0, time.UTC)},
+		{"2024-08-22", time.Date(2024, 8, 22, 0, 0, 0, 0, time.Local)},
+	}
+	for i, tt := range tests {
+		got, err := parseTimeBound(tt.s, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("Test[%d]: parseTimeBound(%q) = %v, %v, want %v", i, tt.s, got, err, tt.want)
+		}
+	}
+
+	if _, err := parseTimeBound("yesterday", now); err == nil {
+		t.Error("parseTimeBound accepted an invalid time")
+	}
+}
+
+func TestInTimeBounds(t *testing.T) {
+	defer func() {
+		sinceTime, untilTime, requireTime = time.Time{}, time.Time{}, false
+	}()
+	sinceTime = time.Date(2024, 8, 22, 9, 0, 0, 0, time.Local)
+	untilTime = time.Date(2024, 8, 22, 10, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		value       map[string]interface{}
+		requireTime bool
+		want        bool
+	}{
+		{map[string]interface{}{"datetime": "2024-08-22 08:59:59"}, false, false},
+		{map[string]interface{}{"datetime": "2024-08-22 09:00:00"}, false, true},
+		{map[string]interface{}{"datetime": "2024-08-22 09:30:00.123"}, false, true},
+		{map[string]interface{}{"datetime": time.Date(2024, 8, 22, 10, 0, 1, 0, time.Local)}, false, false},
+		{map[string]interface{}{"message": "no datetime"}, false, true},
+		{map[string]interface{}{"message": "no datetime"}, true, false},
+		{map[string]interface{}{"datetime": "not a time"}, true, false},
+	}
+	for i, tt := range tests {
+		requireTime = tt.requireTime
+		if got := inTimeBounds(tt.value); got != tt.want {
+			t.Errorf("Test[%d]: inTimeBounds(%v) = %v, want %v", i, tt.value, got, tt.want)
+		}
+	}
+
+	// Without a bound, -require-time drops nothing.
+	sinceTime, untilTime, requireTime = time.Time{}, time.Time{}, true
+	if !inTimeBounds(map[string]interface{}{"message": "no datetime"}) {
+		t.Error("inTimeBounds dropped an entry without a datetime with neither -since nor -until set")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gdamore/tcell"
+)
+
+// Top splits indices into the n rows with the highest counts and the rest,
+// both in the order of indices. Rows with equal counts rank by first seen.
+func (s *Store) Top(indices []int, n int) (top, rest []int) {
+	if n <= 0 || len(indices) <= n {
+		return indices, nil
+	}
+	ranked := append([]int(nil), indices...)
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := s.rows[ranked[i]], s.rows[ranked[j]]
+		if a.count != b.count {
+			return a.count > b.count
+		}
+		return ranked[i] < ranked[j]
+	})
+	cutoff := make(map[int]bool, n)
+	for _, i := range ranked[:n] {
+		cutoff[i] = true
+	}
+
+	top = make([]int, 0, n)
+	rest = make([]int, 0, len(indices)-n)
+	for _, i := range indices {
+		if cutoff[i] {
+			top = append(top, i)
+		} else {
+			rest = append(rest, i)
+		}
+	}
+	return top, rest
+}
+
+// Sum returns the total count and trend of the rows at indices.
+func (s *Store) Sum(indices []int) (int, []float64) {
+	count := 0
+	trend := make([]float64, s.buckets)
+	for _, i := range indices {
+		count += s.rows[i].count
+		for j, n := range s.rows[i].trend {
+			trend[j] += n
+		}
+	}
+	return count, trend
+}
+
+// renderOthers renders the groups beyond -top as a row summing them up.
+func renderOthers(row int, rest []int) {
+	count, trend := store.Sum(rest)
+	var rate float64
+	for _, i := range rest {
+		rate += store.GroupRate(i)
+	}
+	cells := []string{Spark(trend, sparkRamp), fmt.Sprint(count), formatRate(rate)}
+	for j := range keys {
+		if j == 0 {
+			cells = append(cells, fmt.Sprintf("(%d other groups)", len(rest)))
+		} else {
+			cells = append(cells, "")
+		}
+	}
+	for range stats {
+		cells = append(cells, "")
+	}
+	if showSeen {
+		cells = append(cells, "", "")
+	}
+	for column, text := range cells {
+		setCell(row, column, text, false).
+			SetTextColor(tcell.ColorGray).SetAttributes(tcell.AttrNone)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+func TestStoreTop(t *testing.T) {
+	s := NewStore(time.Second, defaultTrendBuckets, 1, []string{"message"}, nil)
+	for i, n := range []int{1, 3, 2, 3, 1} {
+		for j := 0; j < n; j++ {
+			s.Push(map[string]interface{}{"message": fmt.Sprint(i)})
+		}
+	}
+
+	tests := []struct {
+		indices   []int
+		n         int
+		top, rest []int
+	}{
+		{[]int{0, 1, 2, 3, 4}, 2, []int{1, 3}, []int{0, 2, 4}},
+		{[]int{4, 3, 2, 1, 0}, 3, []int{3, 2, 1}, []int{4, 0}},
+		// equal counts rank by first seen
+		{[]int{4, 0}, 1, []int{0}, []int{4}},
+		{[]int{0, 1}, 2, []int{0, 1}, nil},
+		{[]int{0, 1}, 0, []int{0, 1}, nil},
+	}
+	for i, tt := range tests {
+		top, rest := s.Top(tt.indices, tt.n)
+		if !reflect.DeepEqual(top, tt.top) || !reflect.DeepEqual(rest, tt.rest) {
+			t.Errorf("Test[%d]: Top(%v, %d) = %v, %v, want %v, %v", i, tt.indices, tt.n, top, rest, tt.top, tt.rest)
+		}
+	}
+
+	count, trend := s.Sum([]int{0, 2, 4})
+	if count != 4 || trend[len(trend)-1] != 4 {
+		t.Errorf("Sum() = %d, %v, want 4 in the last bucket", count, trend)
+	}
+}
+
+func TestRenderRowsTop(t *testing.T) {
+	keys = []string{"message"}
+	table = tview.NewTable().SetFixed(1, 2)
+	store = NewStore(time.Second, defaultTrendBuckets, 3, keys, nil)
+	topN = 1
+	defer func() { keys, table, store, rows, topN = nil, nil, nil, nil, 0 }()
+
+	renderColumns()
+	store.Push(map[string]interface{}{"message": "a"})
+	store.Push(map[string]interface{}{"message": "disk full"})
+	store.Push(map[string]interface{}{"message": "disk full"})
+	store.Push(map[string]interface{}{"message": "connection refused"})
+	renderRows()
+
+	want := [][]string{
+		{"trend", "count", "rate", "message"},
+		{Spark(store.Get(1).GetTrend(), SparkBlock), "2", "2.0/s", "disk full"},
+		{Spark([]float64{0, 0, 0, 0, 0, 0, 2}, SparkBlock), "2", "2.0/s", "(2 other groups)"},
+	}
+	if table.GetRowCount() != len(want) {
+		t.Fatalf("%d table rows, want %d", table.GetRowCount(), len(want))
+	}
+	for row := range want {
+		for column, text := range want[row] {
+			if got := table.GetCell(row, column).Text; got != text {
+				t.Errorf("cell %d,%d is %q, want %q", row, column, got, text)
+			}
+		}
+	}
+
+	// the cutoff shifts, and the former others row holds a group again
+	topN = 5
+	renderRows()
+	if table.GetRowCount() != 4 {
+		t.Fatalf("%d table rows, want 4", table.GetRowCount())
+	}
+	if got := table.GetCell(2, firstDataColumn); got.Text != "disk full" || got.NotSelectable {
+		t.Errorf("row 2 is %q, selectable %v, want a selectable disk full", got.Text, !got.NotSelectable)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import "fmt"
+
+// defaultColWidth is the width t truncates cells to if -max-col-width isn't
+// given.
+const defaultColWidth = 60
+
+const ellipsis = "…"
+
+var (
+	// maxColWidth is the number of characters a cell is truncated to, 0
+	// shows cells in full. t toggles between 0 and colWidth.
+	maxColWidth int
+	colWidth    int
+	// truncateMode is right, cutting the end, or middle, keeping the start
+	// and the distinctive end of error messages.
+	truncateMode string
+)
+
+func checkTruncateMode(mode string) error {
+	if mode != "right" && mode != "middle" {
+		return fmt.Errorf("-truncate %q: want right or middle", mode)
+	}
+	return nil
+}
+
+// truncate shortens text to width characters, replacing the cut ones with an
+// ellipsis. A width of 0 keeps text.
+func truncate(text string, width int, mode string) string {
+	if width <= 0 {
+		return text
+	}
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	if width == 1 {
+		return ellipsis
+	}
+	if mode == "middle" {
+		head := (width - 1) / 2
+		tail := width - 1 - head
+		return string(runes[:head]) + ellipsis + string(runes[len(runes)-tail:])
+	}
+	return string(runes[:width-1]) + ellipsis
+}
+
+// truncateCell shortens the text of a table cell to -max-col-width.
+func truncateCell(text string) string {
+	return truncate(text, maxColWidth, truncateMode)
+}
+
+// toggleTruncate switches between full cells and cells truncated to
+// -max-col-width, or defaultColWidth if not given.
+func toggleTruncate() int {
+	if maxColWidth > 0 {
+		colWidth, maxColWidth = maxColWidth, 0
+		return 0
+	}
+	if colWidth <= 0 {
+		colWidth = defaultColWidth
+	}
+	maxColWidth = colWidth
+	return maxColWidth
+}
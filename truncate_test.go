@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		mode  string
+		want  string
+	}{
+		{"connection refused", 0, "right", "connection refused"},
+		{"connection refused", 18, "right", "connection refused"},
+		{"connection refused", 11, "right", "connection…"},
+		{"connection refused", 11, "middle", "conne…fused"},
+		{"connection refused", 10, "middle", "conn…fused"},
+		{"日本語のメッセージ", 4, "right", "日本語…"},
+		{"abc", 1, "middle", "…"},
+	}
+	for i, tt := range tests {
+		if got := truncate(tt.text, tt.width, tt.mode); got != tt.want {
+			t.Errorf("Test[%d]: truncate(%q, %d, %s) = %q, want %q", i, tt.text, tt.width, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestToggleTruncate(t *testing.T) {
+	defer func() { maxColWidth, colWidth = 0, 0 }()
+	maxColWidth, colWidth = 0, 0
+	if got := toggleTruncate(); got != defaultColWidth {
+		t.Errorf("toggling without -max-col-width truncates to %d, want %d", got, defaultColWidth)
+	}
+	maxColWidth, colWidth = 20, 0
+	if got := toggleTruncate(); got != 0 || maxColWidth != 0 {
+		t.Errorf("toggling -max-col-width 20 truncates to %d, want full cells", got)
+	}
+	if got := toggleTruncate(); got != 20 {
+		t.Errorf("toggling back truncates to %d, want 20", got)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeZone is the -tz location datetimes are normalized to, nil keeps them
+// as logged.
+var timeZone *time.Location
+
+// parseTimeZone parses a -tz value: local, utc or an IANA name like
+// Europe/Berlin. An empty name keeps the datetimes as logged.
+func parseTimeZone(name string) (*time.Location, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return nil, nil
+	case "local":
+		return time.Local, nil
+	case "utc":
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -tz %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// normalizeTime renders the datetime of value in timeZone, as RFC 3339 so
+// it parses back to the same instant. Datetimes no layout parses are kept.
+func normalizeTime(value map[string]interface{}) {
+	if timeZone == nil {
+		return
+	}
+	if t, ok := recordTime(value); ok {
+		value["datetime"] = t.In(timeZone).Format(time.RFC3339Nano)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeZone(t *testing.T) {
+	tests := []struct {
+		name string
+		want *time.Location
+		err  bool
+	}{
+		{"", nil, false},
+		{"local", time.Local, false},
+		{"UTC", time.UTC, false},
+		{"Mars/Olympus", nil, true},
+	}
+	for i, tt := range tests {
+		loc, err := parseTimeZone(tt.name)
+		if (err != nil) != tt.err || loc != tt.want {
+			t.Errorf("Test[%d]: parseTimeZone(%q) = %v, %v, want %v", i, tt.name, loc, err, tt.want)
+		}
+	}
+	if loc, err := parseTimeZone("Asia/Tokyo"); err != nil || loc.String() != "Asia/Tokyo" {
+		t.Errorf("parseTimeZone(Asia/Tokyo) = %v, %v", loc, err)
+	}
+}
+
+func TestNormalizeTime(t *testing.T) {
+	defer func() { timeZone = nil }()
+	timeZone = time.FixedZone("UTC+8", 8*60*60)
+
+	tests := []struct {
+		datetime interface{}
+		want     interface{}
+	}{
+		{"2024-08-22T01:00:06.956Z", "2024-08-22T09:00:06.956+08:00"},
+		{"2024-08-22T09:00:06+08:00", "2024-08-22T09:00:06+08:00"},
+		{time.Date(2024, 8, 22, 1, 0, 0, 0, time.UTC), "2024-08-22T09:00:00+08:00"},
+		{"yesterday", "yesterday"},
+	}
+	for i, tt := range tests {
+		value := map[string]interface{}{"datetime": tt.datetime}
+		normalizeTime(value)
+		if value["datetime"] != tt.want {
+			t.Errorf("Test[%d]: normalized %v to %v, want %v", i, tt.datetime, value["datetime"], tt.want)
+		}
+	}
+
+	// Bounds without zone are in -tz too.
+	got, err := parseTimeBound("2024-08-22 09:00:00", time.Now())
+	if want := time.Date(2024, 8, 22, 1, 0, 0, 0, time.UTC); err != nil || !got.Equal(want) {
+		t.Errorf("parseTimeBound in -tz = %v, %v, want %v", got, err, want)
+	}
+}
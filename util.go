@@ -1,6 +1,11 @@
 package main
 
-import "sort"
+import (
+	"flag"
+	"regexp"
+	"sort"
+	"strings"
+)
 
 func min(a, b int) int {
 	if a < b {
@@ -28,13 +33,43 @@ func equals(a, b []string) bool {
 	return true
 }
 
+// mapKeys returns the sorted keys of m, except rawKey.
 func mapKeys(m map[string]interface{}) []string {
-	keys := make([]string, len(m))
-	i := 0
+	keys := make([]string, 0, len(m))
 	for key := range m {
-		keys[i] = key
-		i++
+		if key != rawKey {
+			keys = append(keys, key)
+		}
 	}
 	sort.Strings(keys)
 	return keys
 }
+
+var escapeRE = regexp.MustCompile(`\[([a-zA-Z0-9_,;: \-\."#]+)\]`)
+
+// escape prevents tview from interpreting square brackets in text, like the
+// zaplog [func] field, as color tags.
+func escape(text string) string {
+	return escapeRE.ReplaceAllString(text, "[$1[]")
+}
+
+// splitFields splits a comma separated list of field names.
+func splitFields(s string) []string {
+	var fields []string
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// isFlagSet reports whether the flag name was given on the command line or in
+// the -config file.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
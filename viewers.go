@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/rivo/tview"
+)
+
+// pinnedViewer shows a group pinned with p next to the viewer, which follows
+// the selection, so several groups can be compared side by side. It keeps a
+// copy of the group as it was when pinned.
+type pinnedViewer struct {
+	*tview.TextView
+	row    RowData
+	index  int
+	sample int
+}
+
+func newPinnedViewer(row RowData, index int) *pinnedViewer {
+	view := tview.NewTextView().
+		SetDynamicColors(true).
+		SetScrollable(true)
+	view.SetBorder(true).SetTitle(" pinned, Esc to close ")
+	return &pinnedViewer{TextView: view, row: row, index: index}
+}
+
+// copyRow copies the parts of row the store changes in place, so the copy
+// can be read without holding the store's lock.
+func copyRow(row RowData) RowData {
+	row.trend = append([]float64(nil), row.trend...)
+	row.samples = append([]map[string]interface{}(nil), row.samples...)
+	return row
+}
+
+// focusedPinned returns the index of the pinned viewer with focus, or -1.
+func focusedPinned(pinned []*pinnedViewer) int {
+	for i, v := range pinned {
+		if v.HasFocus() {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextFocus returns the primitive after the focused one of panes, wrapping
+// around, or the first if none has focus.
+func nextFocus(panes []tview.Primitive) tview.Primitive {
+	for i, p := range panes {
+		if p.GetFocusable().HasFocus() {
+			return panes[(i+1)%len(panes)]
+		}
+	}
+	return panes[0]
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+func TestNextFocus(t *testing.T) {
+	a, b, c := tview.NewTable(), tview.NewTextView(), tview.NewTextView()
+	panes := []tview.Primitive{a, b, c}
+	if got := nextFocus(panes); got != a {
+		t.Errorf("nextFocus() = %v without focus, want the first pane", got)
+	}
+	b.Focus(nil)
+	if got := nextFocus(panes); got != c {
+		t.Errorf("nextFocus() = %v, want the pane after the focused one", got)
+	}
+	b.Blur()
+	c.Focus(nil)
+	if got := nextFocus(panes); got != a {
+		t.Errorf("nextFocus() = %v from the last pane, want the first", got)
+	}
+}
+
+func TestCopyRow(t *testing.T) {
+	s := NewStore(time.Second, defaultTrendBuckets, 1, []string{"message"}, nil)
+	s.SetKeepSamples(2)
+	s.Push(map[string]interface{}{"message": "a", "n": 1})
+	s.Push(map[string]interface{}{"message": "a", "n": 2})
+	row := copyRow(s.Get(0))
+	s.Push(map[string]interface{}{"message": "a", "n": 3})
+	s.Shift()
+
+	if samples := row.GetSamples(); len(samples) != 2 || samples[0]["n"] != 1 || samples[1]["n"] != 2 {
+		t.Errorf("copied samples %v, want those at the time of the copy", samples)
+	}
+	if buckets := row.GetTrendBuckets(); buckets[len(buckets)-1] != 2 {
+		t.Errorf("copied trend %v, want 2 entries in the last bucket", buckets)
+	}
+}
+
+func TestFocusedPinned(t *testing.T) {
+	pinned := []*pinnedViewer{newPinnedViewer(RowData{}, 0), newPinnedViewer(RowData{}, 1)}
+	if i := focusedPinned(pinned); i != -1 {
+		t.Errorf("focusedPinned() = %d without focus, want -1", i)
+	}
+	pinned[1].Focus(nil)
+	if i := focusedPinned(pinned); i != 1 {
+		t.Errorf("focusedPinned() = %d, want 1", i)
+	}
+}
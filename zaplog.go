@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// zaplogDecoder decodes zap console encoder output, e.g.
+// 2024-08-22 09:00:06.956 ERROR dbsvr/counter.go:202 [GetCounterBatch] empty counter list {"process": 8982}
+//
+// Only the datetime and level are mandatory, the caller, the [func] and the
+// trailing {...} fields block are optional. Lines without a leading timestamp
+// continue the previous record and are kept in its stacktrace field.
+//
+// The segments are separated by sep, the ConsoleSeparator of the encoder.
+// If sep is empty it is detected per line: tabs, as in zap's default
+// encoder config, pipes or spaces.
+//
+// The fields of the {...} block are merged into the record, those named like
+// a segment as <key>_orig, see origKey. If nest is set every field is kept
+// under the fields. prefix instead.
+type zaplogDecoder struct {
+	*lineScanner
+	layout string
+	sep    string
+	nest   bool
+}
+
+// fieldsPrefix prefixes the zap fields kept apart from the segments.
+const fieldsPrefix = "fields."
+
+// zapSeparators names the -zap-sep separators which are awkward to quote.
+var zapSeparators = map[string]string{
+	"auto":  "",
+	"space": " ",
+	"tab":   "\t",
+	"pipe":  "|",
+}
+
+// zaplogTimeLayout is the layout of zap's default time encoder in this repo's
+// services.
+const zaplogTimeLayout = "2006-01-02 15:04:05.000"
+
+// newZaplogDecoder creates a decoder which parses the leading timestamp with
+// layout, or with any of the common layouts if layout is empty.
+func newZaplogDecoder(r io.Reader, layout string) *zaplogDecoder {
+	return &zaplogDecoder{
+		lineScanner: newLineScanner(r),
+		layout:      layout,
+	}
+}
+
+func (d *zaplogDecoder) Decode() (map[string]interface{}, error) {
+	for {
+		d.Begin()
+		line, ok := d.Next()
+		if !ok {
+			if err := d.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		line = strings.TrimSpace(line)
+
+		m, ok := d.parse(line)
+		if !ok {
+			invalidEntry(line)
+			continue
+		}
+		if stack := d.continuation(); stack != "" {
+			m["stacktrace"] = stack
+		}
+		return m, nil
+	}
+}
+
+// continuation consumes the lines following a record which don't start with
+// a timestamp, like the goroutine dump of a panic, and returns them joined.
+// In follow mode this delays a record until the next line arrives.
+func (d *zaplogDecoder) continuation() string {
+	var lines []string
+	for {
+		line, ok := d.Peek()
+		if !ok {
+			break
+		}
+		if line := strings.TrimSpace(line); d.isRecord(line) {
+			break
+		}
+		d.Next()
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, strings.TrimRight(line, " \t"))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	zaplogLevelRE  = regexp.MustCompile(`^(TRACE|DEBUG|INFO|WARN|ERROR)$`)
+	zaplogCallerRE = regexp.MustCompile(`^\S+\.go:\d+$`)
+)
+
+var zaplogPipeRE = regexp.MustCompile(`^\S+( \S+)? *\| `)
+
+// separator returns the separator of the segments of line.
+func (d *zaplogDecoder) separator(line string) string {
+	switch {
+	case d.sep != "":
+		return d.sep
+	case strings.Contains(line, "\t"):
+		return "\t"
+	case zaplogPipeRE.MatchString(line):
+		return "|"
+	}
+	return " "
+}
+
+// isRecord reports whether line starts with a timestamp, beginning a record.
+func (d *zaplogDecoder) isRecord(line string) bool {
+	_, _, ok := d.cutTime(line, d.separator(line))
+	return ok
+}
+
+func (d *zaplogDecoder) parse(line string) (map[string]interface{}, bool) {
+	sep := d.separator(line)
+	datetime, rest, ok := d.cutTime(line, sep)
+	if !ok {
+		return nil, false
+	}
+	level, rest := cutToken(rest, sep)
+	if !zaplogLevelRE.MatchString(level) {
+		return nil, false
+	}
+
+	var m = map[string]interface{}{}
+	m["datetime"] = datetime
+	m["level"] = level
+
+	// caller, e.g. dbsvr/counter.go:202
+	if tok, r := cutToken(rest, sep); zaplogCallerRE.MatchString(tok) {
+		m["position"] = tok
+		rest = r
+	}
+
+	// func, e.g. [GetCounterBatch]
+	if strings.HasPrefix(rest, "[") {
+		if tok, r := cutToken(rest, sep); strings.HasSuffix(tok, "]") {
+			m["func"] = tok
+			rest = r
+		}
+	}
+
+	// zapfields, e.g. {"process": 8982}. Nested objects are merged as they
+	// are, -flatten turns them into dotted keys like the rest of the record.
+	// Braces which aren't a JSON object, like a struct printed with %v, stay
+	// in the message.
+	if strings.HasSuffix(rest, "}") {
+		i := fieldsIndex(rest, sep)
+		if zapfields, ok := parseFields(rest, i); ok {
+			for k, v := range zapfields {
+				switch {
+				case d.nest:
+					k = fieldsPrefix + k
+				case reservedKeys[k]:
+					k = origKey(k)
+				}
+				m[k] = v
+			}
+			rest = strings.TrimRight(strings.TrimSuffix(strings.TrimRight(rest[:i], " "), sep), " ")
+		}
+	}
+
+	m["message"] = rest
+	return m, true
+}
+
+// fieldsIndex returns where the {...} fields block at the end of rest
+// starts, after a separator, or -1 if there is none. The block may contain
+// nested objects, like {"err": {"code": 5}}.
+func fieldsIndex(rest, sep string) int {
+	i := openingBrace(rest)
+	switch {
+	case i <= 0:
+		return i
+	case strings.HasSuffix(rest[:i], sep):
+		return i
+	case sep != " " && strings.HasSuffix(rest[:i], sep+" "):
+		// separators may be padded, like "message | {...}"
+		return i
+	}
+	return -1
+}
+
+// parseFields decodes the fields block of rest starting at i, which must be
+// a single JSON object.
+func parseFields(rest string, i int) (map[string]interface{}, bool) {
+	if i < 0 {
+		return nil, false
+	}
+	zapfields := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewBufferString(rest[i:]))
+	dec.UseNumber()
+	if err := dec.Decode(&zapfields); err != nil {
+		return nil, false
+	}
+	return zapfields, dec.InputOffset() == int64(len(rest)-i)
+}
+
+// openingBrace returns the index of the { matching the } at the end of s,
+// skipping braces in JSON strings, or -1 if there is none.
+func openingBrace(s string) int {
+	depth := 0
+	inString := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if inString {
+			if c == '"' && !escapedQuote(s, i) {
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '}':
+			depth++
+		case '{':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// escapedQuote reports whether the quote at s[i] is escaped by an odd number
+// of backslashes.
+func escapedQuote(s string, i int) bool {
+	n := 0
+	for i--; i >= 0 && s[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// cutTime parses the leading timestamp of s, which may span two tokens like
+// "2024-08-22 09:00:06.956", and returns the remainder.
+func (d *zaplogDecoder) cutTime(s, sep string) (time.Time, string, bool) {
+	first, rest := cutToken(s, sep)
+	if second, rest2 := cutToken(rest, sep); sep == " " && second != "" {
+		if t, ok := parseTime(first+" "+second, d.layout); ok {
+			return t, rest2, true
+		}
+	}
+	if t, ok := parseTime(first, d.layout); ok {
+		return t, rest, true
+	}
+	return time.Time{}, s, false
+}
+
+// cutToken splits s around the first sep. Other separators than a space may
+// be padded with spaces, which are trimmed.
+func cutToken(s, sep string) (string, string) {
+	tok, rest, _ := strings.Cut(s, sep)
+	if sep != " " {
+		return strings.TrimSpace(tok), strings.TrimLeft(rest, " ")
+	}
+	return tok, rest
+}
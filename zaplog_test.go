@@ -0,0 +1,358 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestZaplogDecoderKeepsAllLines(t *testing.T) {
+	input := strings.Join([]string{
+		`2024-08-22 09:00:06.956 ERROR dbsvr/counter.go:202 [GetCounterBatch] first {"process": 1}`,
+		`2024-08-22 09:00:07.001 WARN dbsvr/counter.go:210 [GetCounterBatch] second {"process": 2}`,
+		`2024-08-22 09:00:08.123 INFO dbsvr/counter.go:220 [GetCounterBatch] third {"process": 3}`,
+	}, "\n")
+
+	var got []string
+	for _, m := range decodeAll(t, newZaplogDecoder(strings.NewReader(input), zaplogTimeLayout)) {
+		got = append(got, m["message"].(string))
+	}
+
+	want := []string{"first", "second", "third"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("decoded messages %q, want %q", got, want)
+	}
+}
+
+var zaplogTime = time.Date(2024, 8, 22, 9, 0, 6, 956000000, time.Local)
+
+func TestParseZaplogOptionalSegments(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[string]interface{}
+	}{
+		{
+			`2024-08-22 09:00:06.956 ERROR dbsvr/counter.go:202 [GetCounterBatch] empty counter list {"process": 8982}`,
+			map[string]interface{}{
+				"datetime": zaplogTime,
+				"level":    "ERROR",
+				"position": "dbsvr/counter.go:202",
+				"func":     "[GetCounterBatch]",
+				"message":  "empty counter list",
+				"process":  json.Number("8982"),
+			},
+		},
+		{
+			`2024-08-22 09:00:06.956 ERROR [GetCounterBatch] no caller {"process": 8982}`,
+			map[string]interface{}{
+				"datetime": zaplogTime,
+				"level":    "ERROR",
+				"func":     "[GetCounterBatch]",
+				"message":  "no caller",
+				"process":  json.Number("8982"),
+			},
+		},
+		{
+			`2024-08-22 09:00:06.956 WARN dbsvr/counter.go:202 no func {"process": 8982}`,
+			map[string]interface{}{
+				"datetime": zaplogTime,
+				"level":    "WARN",
+				"position": "dbsvr/counter.go:202",
+				"message":  "no func",
+				"process":  json.Number("8982"),
+			},
+		},
+		{
+			`2024-08-22 09:00:06.956 INFO dbsvr/counter.go:202 [GetCounterBatch] no fields`,
+			map[string]interface{}{
+				"datetime": zaplogTime,
+				"level":    "INFO",
+				"position": "dbsvr/counter.go:202",
+				"func":     "[GetCounterBatch]",
+				"message":  "no fields",
+			},
+		},
+		{
+			`2024-08-22 09:00:06.956 DEBUG only a message`,
+			map[string]interface{}{
+				"datetime": zaplogTime,
+				"level":    "DEBUG",
+				"message":  "only a message",
+			},
+		},
+	}
+	for i, tt := range tests {
+		got, ok := newZaplogDecoder(nil, zaplogTimeLayout).parse(tt.line)
+		if !ok {
+			t.Errorf("Test[%d]: parseZaplog(%q) rejected the line", i, tt.line)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Test[%d]: parseZaplog(%q) = %v, want %v", i, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseZaplogBracesInMessage(t *testing.T) {
+	tests := []struct {
+		line    string
+		message string
+		fields  map[string]interface{}
+	}{
+		{`2024-08-22 09:00:06.956 INFO saving {ID:1 Name:foo}`, "saving {ID:1 Name:foo}", nil},
+		{`2024-08-22 09:00:06.956 INFO saving {ID:1 Name:{First:a}}`, "saving {ID:1 Name:{First:a}}", nil},
+		{`2024-08-22 09:00:06.956 INFO saving {ID:1} {"process": 8982}`, "saving {ID:1}", map[string]interface{}{"process": json.Number("8982")}},
+		{`2024-08-22 09:00:06.956 INFO got {"a": 1} from cache {"process": 8982}`, `got {"a": 1} from cache`, map[string]interface{}{"process": json.Number("8982")}},
+		{`2024-08-22 09:00:06.956 INFO not an object {"a": 1} }`, `not an object {"a": 1} }`, nil},
+		{"2024-08-22 09:00:06.956\tINFO\tsaving {ID:1}", "saving {ID:1}", nil},
+	}
+	for i, tt := range tests {
+		got, ok := newZaplogDecoder(nil, zaplogTimeLayout).parse(tt.line)
+		if !ok {
+			t.Errorf("Test[%d]: parse(%q) rejected the line", i, tt.line)
+			continue
+		}
+		want := map[string]interface{}{"datetime": zaplogTime, "level": "INFO", "message": tt.message}
+		for k, v := range tt.fields {
+			want[k] = v
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Test[%d]: parse(%q) = %v, want %v", i, tt.line, got, want)
+		}
+	}
+}
+
+func TestParseZaplogRequiresDatetimeAndLevel(t *testing.T) {
+	for _, line := range []string{
+		`ERROR dbsvr/counter.go:202 missing datetime`,
+		`2024-08-22 09:00:06.956 missing level`,
+	} {
+		if _, ok := newZaplogDecoder(nil, zaplogTimeLayout).parse(line); ok {
+			t.Errorf("parseZaplog(%q) accepted the line", line)
+		}
+	}
+}
+
+func TestParseZaplogTimeLayouts(t *testing.T) {
+	tests := []struct {
+		layout string
+		line   string
+	}{
+		{zaplogTimeLayout, `2024-08-22 09:00:06.956 INFO hello`},
+		{"", `2024-08-22 09:00:06.956 INFO hello`},
+		{"", `2024-08-22T09:00:06.956` + zaplogTime.Format("Z07:00") + ` INFO hello`},
+		{"", `2024-08-22T09:00:06.956` + zaplogTime.Format("Z0700") + ` INFO hello`},
+		{time.RFC3339Nano, `2024-08-22T09:00:06.956` + zaplogTime.Format("Z07:00") + ` INFO hello`},
+		{"", fmt.Sprintf("%d.956 INFO hello", zaplogTime.Unix())},
+		{epochLayout, fmt.Sprintf("%d INFO hello", zaplogTime.UnixMilli())},
+		{epochLayout, fmt.Sprintf("%d INFO hello", zaplogTime.UnixNano())},
+	}
+	for i, tt := range tests {
+		got, ok := newZaplogDecoder(nil, tt.layout).parse(tt.line)
+		if !ok {
+			t.Errorf("Test[%d]: layout %q rejected %q", i, tt.layout, tt.line)
+			continue
+		}
+		if dt := got["datetime"].(time.Time); !dt.Equal(zaplogTime) {
+			t.Errorf("Test[%d]: datetime = %v, want %v", i, dt, zaplogTime)
+		}
+		if got["message"] != "hello" {
+			t.Errorf("Test[%d]: message = %q, want %q", i, got["message"], "hello")
+		}
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	got, _ := newZaplogDecoder(nil, "").parse("2024-08-22T09:00:06.956Z INFO hello")
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{got["datetime"], "2024-08-22T09:00:06.956Z"},
+		{got["message"], "hello"},
+		{json.Number("42"), "42"},
+		{nil, "<nil>"},
+	}
+	for i, tt := range tests {
+		if s := formatValue(tt.value); s != tt.want {
+			t.Errorf("Test[%d]: formatValue(%v) = %q, want %q", i, tt.value, s, tt.want)
+		}
+	}
+}
+
+func TestZaplogDecoderMultilineStacktrace(t *testing.T) {
+	input := strings.Join([]string{
+		`2024-08-22 09:00:06.956 ERROR dbsvr/counter.go:202 [GetCounterBatch] panic recovered {"process": 1}`,
+		`panic: runtime error: index out of range [3] with length 3`,
+		``,
+		`goroutine 1 [running]:`,
+		`main.main()`,
+		`	/src/dbsvr/main.go:12 +0x1d`,
+		`2024-08-22 09:00:07.001 INFO dbsvr/counter.go:210 [GetCounterBatch] next {"process": 2}`,
+	}, "\n")
+
+	before := invalidLines.Load()
+	got := decodeAll(t, newZaplogDecoder(strings.NewReader(input), zaplogTimeLayout))
+	if len(got) != 2 {
+		t.Fatalf("decoded %d records, want 2", len(got))
+	}
+	want := strings.Join([]string{
+		`panic: runtime error: index out of range [3] with length 3`,
+		`goroutine 1 [running]:`,
+		`main.main()`,
+		`	/src/dbsvr/main.go:12 +0x1d`,
+	}, "\n")
+	if got[0]["stacktrace"] != want {
+		t.Errorf("stacktrace = %q, want %q", got[0]["stacktrace"], want)
+	}
+	if _, ok := got[1]["stacktrace"]; ok || got[1]["message"] != "next" {
+		t.Errorf("second record = %v, want message next without stacktrace", got[1])
+	}
+	if invalid := invalidLines.Load() - before; invalid != 0 {
+		t.Errorf("%d invalid lines, want 0", invalid)
+	}
+}
+
+func TestParseZaplogSeparators(t *testing.T) {
+	want := map[string]interface{}{
+		"datetime": zaplogTime,
+		"level":    "ERROR",
+		"position": "dbsvr/counter.go:202",
+		"func":     "[GetCounterBatch]",
+		"message":  "empty counter list",
+		"process":  json.Number("8982"),
+	}
+	tests := []struct {
+		sep  string
+		line string
+	}{
+		// zap's default ConsoleSeparator
+		{"", "2024-08-22 09:00:06.956\tERROR\tdbsvr/counter.go:202\t[GetCounterBatch]\tempty counter list\t{\"process\": 8982}"},
+		{"\t", "2024-08-22 09:00:06.956\tERROR\tdbsvr/counter.go:202\t[GetCounterBatch]\tempty counter list\t{\"process\": 8982}"},
+		{"", `2024-08-22 09:00:06.956 | ERROR | dbsvr/counter.go:202 | [GetCounterBatch] | empty counter list | {"process": 8982}`},
+		{"|", `2024-08-22 09:00:06.956|ERROR|dbsvr/counter.go:202|[GetCounterBatch]|empty counter list|{"process": 8982}`},
+		{" ", `2024-08-22 09:00:06.956 ERROR dbsvr/counter.go:202 [GetCounterBatch] empty counter list {"process": 8982}`},
+		{"", `2024-08-22 09:00:06.956 ERROR dbsvr/counter.go:202 [GetCounterBatch] empty counter list {"process": 8982}`},
+	}
+	for i, tt := range tests {
+		d := newZaplogDecoder(nil, zaplogTimeLayout)
+		d.sep = tt.sep
+		got, ok := d.parse(tt.line)
+		if !ok {
+			t.Errorf("Test[%d]: parse(%q) rejected the line", i, tt.line)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Test[%d]: parse(%q) = %v, want %v", i, tt.line, got, want)
+		}
+	}
+}
+
+func TestZaplogDecoderTabs(t *testing.T) {
+	input := strings.Join([]string{
+		"2024-08-22 09:00:06.956\tERROR\tdbsvr/counter.go:202\tpanic recovered",
+		"goroutine 1 [running]:",
+		"2024-08-22 09:00:07.001\tINFO\tnext message with spaces\t{\"process\": 2}",
+	}, "\n")
+
+	got := decodeAll(t, newZaplogDecoder(strings.NewReader(input), zaplogTimeLayout))
+	if len(got) != 2 {
+		t.Fatalf("decoded %d records, want 2", len(got))
+	}
+	if got[0]["stacktrace"] != "goroutine 1 [running]:" {
+		t.Errorf("stacktrace = %q", got[0]["stacktrace"])
+	}
+	if got[1]["message"] != "next message with spaces" || got[1]["process"] != json.Number("2") {
+		t.Errorf("second record = %v", got[1])
+	}
+}
+
+func TestZaplogDecoderNestedFields(t *testing.T) {
+	input := strings.Join([]string{
+		`2024-08-22 09:00:06.956 ERROR dbsvr/counter.go:202 save failed {"err": {"code": 5, "msg": "x"}, "user": {"id": 1, "meta": {"region": "us"}}}`,
+		"2024-08-22 09:00:06.957\tERROR\tsave failed\t{\"err\": {\"code\": 6, \"msg\": \"a } in {a string\\\"}\"}}",
+	}, "\n")
+
+	got := decodeAll(t, flattenDecoder{newZaplogDecoder(strings.NewReader(input), zaplogTimeLayout)})
+	want := []map[string]interface{}{
+		{
+			"datetime":         zaplogTime,
+			"level":            "ERROR",
+			"position":         "dbsvr/counter.go:202",
+			"message":          "save failed",
+			"err.code":         json.Number("5"),
+			"err.msg":          "x",
+			"user.id":          json.Number("1"),
+			"user.meta.region": "us",
+		},
+		{
+			"datetime": zaplogTime.Add(time.Millisecond),
+			"level":    "ERROR",
+			"message":  "save failed",
+			"err.code": json.Number("6"),
+			"err.msg":  `a } in {a string"}`,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %v, want %v", got, want)
+	}
+}
+
+func TestZaplogDecoderCRLFAndBOM(t *testing.T) {
+	input := "\ufeff2024-08-22 09:00:06.956 ERROR dbsvr/counter.go:202 empty counter list {\"process\": 8982}\r\n" +
+		"2024-08-22 09:00:06.957 WARN retrying\r\n" +
+		"2024-08-22 09:00:06.958 INFO done\r"
+
+	got := decodeAll(t, newZaplogDecoder(strings.NewReader(input), zaplogTimeLayout))
+	want := []map[string]interface{}{
+		{
+			"datetime": zaplogTime,
+			"level":    "ERROR",
+			"position": "dbsvr/counter.go:202",
+			"message":  "empty counter list",
+			"process":  json.Number("8982"),
+		},
+		{"datetime": zaplogTime.Add(time.Millisecond), "level": "WARN", "message": "retrying"},
+		{"datetime": zaplogTime.Add(2 * time.Millisecond), "level": "INFO", "message": "done"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %v, want %v", got, want)
+	}
+}
+
+func TestZaplogDecoderFieldsPrefix(t *testing.T) {
+	line := `2024-08-22 09:00:06.956 ERROR dbsvr/counter.go:202 save failed {"process": 1, "level": "fatal", "message": "from fields"}`
+	tests := []struct {
+		nest bool
+		want map[string]interface{}
+	}{
+		{false, map[string]interface{}{
+			"process":      json.Number("1"),
+			"level_orig":   "fatal",
+			"message_orig": "from fields",
+		}},
+		{true, map[string]interface{}{
+			"fields.process": json.Number("1"),
+			"fields.level":   "fatal",
+			"fields.message": "from fields",
+		}},
+	}
+	for _, tt := range tests {
+		d := newZaplogDecoder(strings.NewReader(line), zaplogTimeLayout)
+		d.nest = tt.nest
+		got := decodeAll(t, d)
+		if len(got) != 1 {
+			t.Fatalf("nest %v: decoded %d records, want 1", tt.nest, len(got))
+		}
+		tt.want["datetime"] = zaplogTime
+		tt.want["level"] = "ERROR"
+		tt.want["position"] = "dbsvr/counter.go:202"
+		tt.want["message"] = "save failed"
+		if !reflect.DeepEqual(got[0], tt.want) {
+			t.Errorf("nest %v: decoded %v, want %v", tt.nest, got[0], tt.want)
+		}
+	}
+}